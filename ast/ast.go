@@ -52,6 +52,7 @@ type Identifier struct {
 }
 
 func (i *Identifier) expressionNode()      {}
+func (i *Identifier) patternNode()         {}
 func (i *Identifier) TokenLiteral() string { return i.Token.Literal }
 func (i *Identifier) String() string       { return i.Value }
 
@@ -75,6 +76,31 @@ func (sl *StringLiteral) expressionNode()      {}
 func (sl *StringLiteral) TokenLiteral() string { return sl.Token.Literal }
 func (sl *StringLiteral) String() string       { return "\"" + sl.Value + "\"" }
 
+// TemplateLiteral はテンプレート文字列（`Hello, ${name}!`）
+// Strings と Expressions を交互に連結した文字列になる（len(Strings) は len(Expressions)+1）
+type TemplateLiteral struct {
+	Token       token.Token // 最初の token.TEMPLATE または token.TEMPLATE_HEAD トークン
+	Strings     []string
+	Expressions []Expression
+}
+
+func (tl *TemplateLiteral) expressionNode()      {}
+func (tl *TemplateLiteral) TokenLiteral() string { return tl.Token.Literal }
+func (tl *TemplateLiteral) String() string {
+	var out bytes.Buffer
+	out.WriteString("`")
+	for i, s := range tl.Strings {
+		out.WriteString(s)
+		if i < len(tl.Expressions) {
+			out.WriteString("${")
+			out.WriteString(tl.Expressions[i].String())
+			out.WriteString("}")
+		}
+	}
+	out.WriteString("`")
+	return out.String()
+}
+
 // BooleanLiteral は真偽値リテラル
 type BooleanLiteral struct {
 	Token token.Token // token.TRUE または token.FALSE
@@ -173,9 +199,10 @@ func (ce *CallExpression) String() string {
 
 // VariableStatement は変数宣言文（mut x = 10; または const PI = 3.14;）
 type VariableStatement struct {
-	Token token.Token // token.MUT または token.CONST
-	Name  *Identifier
-	Value Expression
+	Token   token.Token // token.MUT または token.CONST
+	Name    *Identifier // 宣言する変数（分割代入では nil）
+	Pattern Pattern     // 分割代入のパターン（const [a, b] = ...。通常の宣言では nil）
+	Value   Expression
 }
 
 func (vs *VariableStatement) statementNode()       {}
@@ -183,7 +210,11 @@ func (vs *VariableStatement) TokenLiteral() string { return vs.Token.Literal }
 func (vs *VariableStatement) String() string {
 	var out bytes.Buffer
 	out.WriteString(vs.TokenLiteral() + " ")
-	out.WriteString(vs.Name.String())
+	if vs.Pattern != nil {
+		out.WriteString(vs.Pattern.String())
+	} else {
+		out.WriteString(vs.Name.String())
+	}
 	out.WriteString(" = ")
 	if vs.Value != nil {
 		out.WriteString(vs.Value.String())
@@ -267,10 +298,14 @@ func (is *IfStatement) String() string {
 }
 
 // FunctionLiteral は関数リテラル
+// 分割代入のパラメータ（func([a, b]) => ...）の Parameters の要素は、パターンを表示する仮の識別子になる
 type FunctionLiteral struct {
 	Token      token.Token // 'func' トークン
 	Name       *Identifier // 関数名（オプション）
 	Parameters []*Identifier
+	Defaults   []Expression // Parameters と同じ順のデフォルト値（ないパラメータは nil。どれにもなければ nil）
+	Patterns   []Pattern    // Parameters と同じ順の分割代入のパターン（ないパラメータは nil。どれにもなければ nil）
+	Rest       *Identifier  // 残りの引数を配列で受け取るパラメータ（...rest、オプション）
 	Body       *BlockStatement
 }
 
@@ -278,22 +313,34 @@ func (fl *FunctionLiteral) expressionNode()      {}
 func (fl *FunctionLiteral) TokenLiteral() string { return fl.Token.Literal }
 func (fl *FunctionLiteral) String() string {
 	var out bytes.Buffer
-	params := []string{}
-	for _, p := range fl.Parameters {
-		params = append(params, p.String())
-	}
 	out.WriteString(fl.TokenLiteral())
 	if fl.Name != nil {
 		out.WriteString(" ")
 		out.WriteString(fl.Name.String())
 	}
 	out.WriteString("(")
-	out.WriteString(strings.Join(params, ", "))
+	out.WriteString(strings.Join(ParameterStrings(fl.Parameters, fl.Defaults, fl.Rest), ", "))
 	out.WriteString(") => ")
 	out.WriteString(fl.Body.String())
 	return out.String()
 }
 
+// ParameterStrings はパラメータを "a"、"b = 10"、"...rest" の形式の文字列にする
+func ParameterStrings(params []*Identifier, defaults []Expression, rest *Identifier) []string {
+	strs := []string{}
+	for i, p := range params {
+		if defaults != nil && defaults[i] != nil {
+			strs = append(strs, p.String()+" = "+defaults[i].String())
+		} else {
+			strs = append(strs, p.String())
+		}
+	}
+	if rest != nil {
+		strs = append(strs, "..."+rest.String())
+	}
+	return strs
+}
+
 // WhileStatement はwhile文
 type WhileStatement struct {
 	Token     token.Token // 'while' トークン
@@ -344,12 +391,16 @@ func (fs *ForStatement) String() string {
 
 // ForInStatement はfor-inループ文
 // for (item in arr) { ... } または for (key, value in map) { ... }
+// Key と Value は分割代入のパターンにもできる。その場合 Key（Value）はパターンを表示する仮の識別子になり、
+// KeyPattern（ValuePattern）がパターンを持つ
 type ForInStatement struct {
-	Token    token.Token     // 'for' トークン
-	Key      *Identifier     // 単一変数 or 2変数のキー/インデックス
-	Value    *Identifier     // 2変数形式の値（nil なら単一変数形式）
-	Iterable Expression      // イテレート対象（配列 or マップ）
-	Body     *BlockStatement // ループ本体
+	Token        token.Token     // 'for' トークン
+	Key          *Identifier     // 単一変数 or 2変数のキー/インデックス
+	Value        *Identifier     // 2変数形式の値（nil なら単一変数形式）
+	KeyPattern   Pattern         // Key のパターン（パターンでなければ nil）
+	ValuePattern Pattern         // Value のパターン（パターンでなければ nil）
+	Iterable     Expression      // イテレート対象（配列 or マップ）
+	Body         *BlockStatement // ループ本体
 }
 
 func (fis *ForInStatement) statementNode()       {}
@@ -491,7 +542,13 @@ func (iae *IndexAssignExpression) String() string {
 // MapLiteral はマップリテラル（{"key": "value"}）
 type MapLiteral struct {
 	Token token.Token // '{' トークン
-	Pairs map[Expression]Expression
+	Pairs []MapPair   // 記述した順のキーと値
+}
+
+// MapPair はマップリテラルのキーと値の組
+type MapPair struct {
+	Key   Expression
+	Value Expression
 }
 
 func (ml *MapLiteral) expressionNode()      {}
@@ -499,8 +556,8 @@ func (ml *MapLiteral) TokenLiteral() string { return ml.Token.Literal }
 func (ml *MapLiteral) String() string {
 	var out bytes.Buffer
 	pairs := []string{}
-	for key, value := range ml.Pairs {
-		pairs = append(pairs, key.String()+": "+value.String())
+	for _, pair := range ml.Pairs {
+		pairs = append(pairs, pair.Key.String()+": "+pair.Value.String())
 	}
 	out.WriteString("{")
 	out.WriteString(strings.Join(pairs, ", "))
@@ -508,6 +565,79 @@ func (ml *MapLiteral) String() string {
 	return out.String()
 }
 
+// Pattern は分割代入で値を受け取る左辺（*Identifier、*ArrayPattern、*MapPattern）
+type Pattern interface {
+	Node
+	patternNode()
+}
+
+// ArrayPattern は配列の分割代入のパターン（[a, b, ...rest]）
+type ArrayPattern struct {
+	Token    token.Token // '[' トークン
+	Elements []Pattern   // 先頭から順に要素を受け取るパターン
+	Rest     *Identifier // 残りの要素を配列で受け取る変数（なければ nil）
+}
+
+func (ap *ArrayPattern) patternNode()         {}
+func (ap *ArrayPattern) TokenLiteral() string { return ap.Token.Literal }
+func (ap *ArrayPattern) String() string {
+	elements := []string{}
+	for _, elem := range ap.Elements {
+		elements = append(elements, elem.String())
+	}
+	if ap.Rest != nil {
+		elements = append(elements, "..."+ap.Rest.String())
+	}
+	return "[" + strings.Join(elements, ", ") + "]"
+}
+
+// MapPattern はマップの分割代入のパターン（{"name": n}）
+type MapPattern struct {
+	Token token.Token   // '{' トークン
+	Pairs []PatternPair // 記述した順のキーとパターン
+}
+
+// PatternPair はマップのパターンのキーと、その値を受け取るパターンの組
+// キーは文字列・数値・真偽値のリテラル
+type PatternPair struct {
+	Key   Expression
+	Value Pattern
+}
+
+func (mp *MapPattern) patternNode()         {}
+func (mp *MapPattern) TokenLiteral() string { return mp.Token.Literal }
+func (mp *MapPattern) String() string {
+	pairs := []string{}
+	for _, pair := range mp.Pairs {
+		pairs = append(pairs, pair.Key.String()+": "+pair.Value.String())
+	}
+	return "{" + strings.Join(pairs, ", ") + "}"
+}
+
+// PatternNames は pattern が宣言する変数を記述した順に返す
+func PatternNames(pattern Pattern) []*Identifier {
+	switch pattern := pattern.(type) {
+	case *Identifier:
+		return []*Identifier{pattern}
+	case *ArrayPattern:
+		names := []*Identifier{}
+		for _, elem := range pattern.Elements {
+			names = append(names, PatternNames(elem)...)
+		}
+		if pattern.Rest != nil {
+			names = append(names, pattern.Rest)
+		}
+		return names
+	case *MapPattern:
+		names := []*Identifier{}
+		for _, pair := range pattern.Pairs {
+			names = append(names, PatternNames(pair.Value)...)
+		}
+		return names
+	}
+	return nil
+}
+
 // PostfixExpression は後置演算子式（x++, x-- など）
 type PostfixExpression struct {
 	Token    token.Token // ++ or -- token
@@ -591,12 +721,14 @@ func (se *SliceExpression) String() string {
 	return out.String()
 }
 
-// TryStatement はtry/catch文
+// TryStatement はtry/catch/finally文
+// catch と finally はどちらか一方を省略できる
 type TryStatement struct {
-	Token       token.Token     // 'try' トークン
-	TryBlock    *BlockStatement // try ブロック
-	CatchParam  *Identifier     // catch パラメータ（エラー変数）
-	CatchBlock  *BlockStatement // catch ブロック
+	Token        token.Token     // 'try' トークン
+	TryBlock     *BlockStatement // try ブロック
+	CatchParam   *Identifier     // catch パラメータ（エラー変数）
+	CatchBlock   *BlockStatement // catch ブロック（省略時は nil）
+	FinallyBlock *BlockStatement // finally ブロック（省略時は nil）
 }
 
 func (ts *TryStatement) statementNode()       {}
@@ -605,10 +737,16 @@ func (ts *TryStatement) String() string {
 	var out bytes.Buffer
 	out.WriteString("try ")
 	out.WriteString(ts.TryBlock.String())
-	out.WriteString(" catch (")
-	out.WriteString(ts.CatchParam.String())
-	out.WriteString(") ")
-	out.WriteString(ts.CatchBlock.String())
+	if ts.CatchBlock != nil {
+		out.WriteString(" catch (")
+		out.WriteString(ts.CatchParam.String())
+		out.WriteString(") ")
+		out.WriteString(ts.CatchBlock.String())
+	}
+	if ts.FinallyBlock != nil {
+		out.WriteString(" finally ")
+		out.WriteString(ts.FinallyBlock.String())
+	}
 	return out.String()
 }
 
@@ -627,3 +765,35 @@ func (ts *ThrowStatement) String() string {
 	out.WriteString(";")
 	return out.String()
 }
+
+// ImportStatement はimport文（import "lib.sugu";）
+type ImportStatement struct {
+	Token token.Token    // 'import' トークン
+	Path  *StringLiteral // 読み込むファイルのパス
+}
+
+func (is *ImportStatement) statementNode()       {}
+func (is *ImportStatement) TokenLiteral() string { return is.Token.Literal }
+func (is *ImportStatement) String() string {
+	var out bytes.Buffer
+	out.WriteString("import ")
+	out.WriteString(is.Path.String())
+	out.WriteString(";")
+	return out.String()
+}
+
+// ExportStatement はexport文（export const x = 1; や export func f() => { ... }）
+type ExportStatement struct {
+	Token     token.Token // 'export' トークン
+	Name      *Identifier // エクスポートされる識別子
+	Statement Statement   // 変数宣言文または名前付き関数の式文
+}
+
+func (es *ExportStatement) statementNode()       {}
+func (es *ExportStatement) TokenLiteral() string { return es.Token.Literal }
+func (es *ExportStatement) String() string {
+	var out bytes.Buffer
+	out.WriteString("export ")
+	out.WriteString(es.Statement.String())
+	return out.String()
+}
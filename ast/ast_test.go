@@ -251,3 +251,40 @@ func TestContinueStatement(t *testing.T) {
 		t.Errorf("cont.String() wrong. got=%q", cont.String())
 	}
 }
+
+func TestImportStatement(t *testing.T) {
+	imp := &ImportStatement{
+		Token: token.Token{Type: token.IMPORT, Literal: "import"},
+		Path: &StringLiteral{
+			Token: token.Token{Type: token.STRING, Literal: "lib.sugu"},
+			Value: "lib.sugu",
+		},
+	}
+
+	if imp.String() != `import "lib.sugu";` {
+		t.Errorf("imp.String() wrong. got=%q", imp.String())
+	}
+}
+
+func TestExportStatement(t *testing.T) {
+	name := &Identifier{
+		Token: token.Token{Type: token.IDENT, Literal: "x"},
+		Value: "x",
+	}
+	exp := &ExportStatement{
+		Token: token.Token{Type: token.EXPORT, Literal: "export"},
+		Name:  name,
+		Statement: &VariableStatement{
+			Token: token.Token{Type: token.CONST, Literal: "const"},
+			Name:  name,
+			Value: &NumberLiteral{
+				Token: token.Token{Type: token.NUMBER, Literal: "1"},
+				Value: "1",
+			},
+		},
+	}
+
+	if exp.String() != "export const x = 1;" {
+		t.Errorf("exp.String() wrong. got=%q", exp.String())
+	}
+}
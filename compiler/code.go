@@ -0,0 +1,305 @@
+package compiler
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+)
+
+// Instructions はバイトコードの命令列
+type Instructions []byte
+
+// Opcode は命令の種類
+type Opcode byte
+
+const (
+	// 値
+	OpConstant Opcode = iota // 定数プールの値を積む
+	OpNull
+	OpTrue
+	OpFalse
+	OpArray    // スタック上の n 個の値から配列を作る
+	OpMap      // スタック上の n 組のキーと値からマップを作る
+	OpTemplate // スタック上の n 個の値の Inspect を連結した文字列を作る（テンプレート文字列）
+	OpHashKey  // スタックの先頭がマップのキーに使えるか検査する
+	OpClosure  // 関数リテラルから関数オブジェクトを作る
+
+	// スタック操作
+	OpPop     // スタックの先頭を捨てる
+	OpPopLast // スタックの先頭を文の評価結果として記録する
+	OpDup     // スタックの先頭を複製する
+
+	// 演算子
+	OpAdd
+	OpSub
+	OpMul
+	OpDiv
+	OpMod
+	OpEqual
+	OpNotEqual
+	OpLess
+	OpGreater
+	OpLessEqual
+	OpGreaterEqual
+	OpMinus
+	OpBang
+	OpSwitchEqual // switch の case の比較
+
+	// ジャンプ
+	OpJump
+	OpJumpNotTruthy  // 先頭を取り出し、偽ならジャンプ
+	OpJumpFalsyKeep  // 先頭が偽なら残したままジャンプ、真なら取り出す（&&）
+	OpJumpTruthyKeep // 先頭が真なら残したままジャンプ、偽なら取り出す（||）
+	OpJumpIfBound    // 変数が現在のスコープで束縛されていればジャンプ（引数が渡されたパラメータのデフォルト値を飛ばす）
+
+	// 変数
+	OpGetName      // 変数または組み込み関数を積む
+	OpGetVar       // 変数を積む（組み込み関数は探さない）
+	OpDefine       // mut 宣言
+	OpDefineConst  // const 宣言
+	OpDestructure  // 分割代入（オペランドはパターンのインデックスと、const なら 1）
+	OpCheckDefined // 代入先の変数が定義されているか検査する
+	OpCheckMutable // const 変数への代入を検査する（オペランド 2 は MutateReassign / MutateModify）
+	OpAssign       // 代入（const の検査をして更新する）
+	OpUpdate       // 定義されているスコープで値を更新する
+	OpPostfix      // x++ / x--（オペランド 2 は 0 が ++、1 が --）
+
+	// インデックス
+	OpIndex
+	OpSetIndex
+	OpIndexCompound // arr[i] op= v（オペランドは演算子の Opcode）
+	OpSliceIndex    // スライスのインデックスが数値か検査する
+	OpSlice         // オペランドは SliceLow / SliceHigh のビットの組み合わせ
+
+	// スコープ
+	OpPushScope
+	OpPopScope
+
+	// 関数
+	OpCall
+	OpReturnValue
+	OpReturnLast // 最後に評価した文の結果を返す
+
+	// for-in
+	OpIterInit
+	OpIterNext // 次の要素を新しいスコープに束縛する。終わりならジャンプ
+
+	// 例外
+	OpSetupTry
+	OpPopTry
+	OpThrow
+	OpError        // 定数プールの文字列をエラーメッセージ（オペランド 1）と種類（オペランド 2）としてエラーを発生させる
+	OpSetupFinally // finally ブロックを登録する（例外が発生したら finally ブロックに飛ぶ）
+	OpEnterFinally // 正常に finally ブロックに入るときに、それまでの文の評価結果を積む
+	OpEndFinally   // finally ブロックの終わり。保留中の例外があれば投げ直す
+
+	// モジュール
+	OpImport
+	OpExport
+
+	// 実行の上限
+	OpStep // 実行ステップを数え、上限に達していればエラーを発生させる
+)
+
+// OpCheckMutable の第 2 オペランド（エラーメッセージの違い）
+const (
+	MutateReassign = 0 // cannot reassign to const variable
+	MutateModify   = 1 // cannot modify const variable
+)
+
+// OpSlice のオペランドのビット
+const (
+	SliceLow  = 1 << 0
+	SliceHigh = 1 << 1
+)
+
+// NoName は OpIterNext で値の変数を持たないことを表す名前のインデックス
+const NoName = 0xFFFF
+
+// Definition は命令の名前とオペランドのバイト幅
+type Definition struct {
+	Name          string
+	OperandWidths []int
+}
+
+var definitions = map[Opcode]*Definition{
+	OpConstant: {"OpConstant", []int{2}},
+	OpNull:     {"OpNull", []int{}},
+	OpTrue:     {"OpTrue", []int{}},
+	OpFalse:    {"OpFalse", []int{}},
+	OpArray:    {"OpArray", []int{2}},
+	OpMap:      {"OpMap", []int{2}},
+	OpTemplate: {"OpTemplate", []int{2}},
+	OpHashKey:  {"OpHashKey", []int{}},
+	OpClosure:  {"OpClosure", []int{2}},
+
+	OpPop:     {"OpPop", []int{}},
+	OpPopLast: {"OpPopLast", []int{}},
+	OpDup:     {"OpDup", []int{}},
+
+	OpAdd:          {"OpAdd", []int{}},
+	OpSub:          {"OpSub", []int{}},
+	OpMul:          {"OpMul", []int{}},
+	OpDiv:          {"OpDiv", []int{}},
+	OpMod:          {"OpMod", []int{}},
+	OpEqual:        {"OpEqual", []int{}},
+	OpNotEqual:     {"OpNotEqual", []int{}},
+	OpLess:         {"OpLess", []int{}},
+	OpGreater:      {"OpGreater", []int{}},
+	OpLessEqual:    {"OpLessEqual", []int{}},
+	OpGreaterEqual: {"OpGreaterEqual", []int{}},
+	OpMinus:        {"OpMinus", []int{}},
+	OpBang:         {"OpBang", []int{}},
+	OpSwitchEqual:  {"OpSwitchEqual", []int{}},
+
+	OpJump:           {"OpJump", []int{4}},
+	OpJumpNotTruthy:  {"OpJumpNotTruthy", []int{4}},
+	OpJumpFalsyKeep:  {"OpJumpFalsyKeep", []int{4}},
+	OpJumpTruthyKeep: {"OpJumpTruthyKeep", []int{4}},
+	OpJumpIfBound:    {"OpJumpIfBound", []int{4, 2}},
+
+	OpGetName:      {"OpGetName", []int{2}},
+	OpGetVar:       {"OpGetVar", []int{2}},
+	OpDefine:       {"OpDefine", []int{2}},
+	OpDefineConst:  {"OpDefineConst", []int{2}},
+	OpDestructure:  {"OpDestructure", []int{2, 1}},
+	OpCheckDefined: {"OpCheckDefined", []int{2}},
+	OpCheckMutable: {"OpCheckMutable", []int{2, 1}},
+	OpAssign:       {"OpAssign", []int{2}},
+	OpUpdate:       {"OpUpdate", []int{2}},
+	OpPostfix:      {"OpPostfix", []int{2, 1}},
+
+	OpIndex:         {"OpIndex", []int{}},
+	OpSetIndex:      {"OpSetIndex", []int{}},
+	OpIndexCompound: {"OpIndexCompound", []int{1}},
+	OpSliceIndex:    {"OpSliceIndex", []int{}},
+	OpSlice:         {"OpSlice", []int{1}},
+
+	OpPushScope: {"OpPushScope", []int{}},
+	OpPopScope:  {"OpPopScope", []int{}},
+
+	OpCall:        {"OpCall", []int{1}},
+	OpReturnValue: {"OpReturnValue", []int{}},
+	OpReturnLast:  {"OpReturnLast", []int{}},
+
+	OpIterInit: {"OpIterInit", []int{}},
+	OpIterNext: {"OpIterNext", []int{4, 2, 2}},
+
+	OpSetupTry:     {"OpSetupTry", []int{4}},
+	OpPopTry:       {"OpPopTry", []int{}},
+	OpThrow:        {"OpThrow", []int{}},
+	OpError:        {"OpError", []int{2, 2}},
+	OpSetupFinally: {"OpSetupFinally", []int{4}},
+	OpEnterFinally: {"OpEnterFinally", []int{}},
+	OpEndFinally:   {"OpEndFinally", []int{}},
+
+	OpImport: {"OpImport", []int{2}},
+	OpExport: {"OpExport", []int{2}},
+
+	OpStep: {"OpStep", []int{}},
+}
+
+// Lookup は命令の定義を返す
+func Lookup(op byte) (*Definition, error) {
+	def, ok := definitions[Opcode(op)]
+	if !ok {
+		return nil, fmt.Errorf("opcode %d undefined", op)
+	}
+	return def, nil
+}
+
+// Make は命令とオペランドをバイト列にエンコードする
+func Make(op Opcode, operands ...int) []byte {
+	def, ok := definitions[op]
+	if !ok {
+		return []byte{}
+	}
+
+	length := 1
+	for _, w := range def.OperandWidths {
+		length += w
+	}
+
+	instruction := make([]byte, length)
+	instruction[0] = byte(op)
+
+	offset := 1
+	for i, o := range operands {
+		width := def.OperandWidths[i]
+		switch width {
+		case 4:
+			binary.BigEndian.PutUint32(instruction[offset:], uint32(o))
+		case 2:
+			binary.BigEndian.PutUint16(instruction[offset:], uint16(o))
+		case 1:
+			instruction[offset] = byte(o)
+		}
+		offset += width
+	}
+
+	return instruction
+}
+
+// ReadOperands は命令のオペランドをデコードし、読み込んだバイト数とともに返す
+func ReadOperands(def *Definition, ins Instructions) ([]int, int) {
+	operands := make([]int, len(def.OperandWidths))
+	offset := 0
+
+	for i, width := range def.OperandWidths {
+		switch width {
+		case 4:
+			operands[i] = int(ReadUint32(ins[offset:]))
+		case 2:
+			operands[i] = int(ReadUint16(ins[offset:]))
+		case 1:
+			operands[i] = int(ins[offset])
+		}
+		offset += width
+	}
+
+	return operands, offset
+}
+
+// ReadUint32 は 4 バイトのオペランドを読む
+func ReadUint32(ins Instructions) uint32 {
+	return binary.BigEndian.Uint32(ins)
+}
+
+// ReadUint16 は 2 バイトのオペランドを読む
+func ReadUint16(ins Instructions) uint16 {
+	return binary.BigEndian.Uint16(ins)
+}
+
+// String は命令列を逆アセンブルした文字列を返す（デバッグ・テスト用）
+func (ins Instructions) String() string {
+	var out bytes.Buffer
+
+	i := 0
+	for i < len(ins) {
+		def, err := Lookup(ins[i])
+		if err != nil {
+			fmt.Fprintf(&out, "ERROR: %s\n", err)
+			i++
+			continue
+		}
+
+		operands, read := ReadOperands(def, ins[i+1:])
+		fmt.Fprintf(&out, "%04d %s\n", i, ins.fmtInstruction(def, operands))
+
+		i += 1 + read
+	}
+
+	return out.String()
+}
+
+func (ins Instructions) fmtInstruction(def *Definition, operands []int) string {
+	if len(operands) != len(def.OperandWidths) {
+		return fmt.Sprintf("ERROR: operand len %d does not match defined %d\n", len(operands), len(def.OperandWidths))
+	}
+
+	out := def.Name
+	for _, o := range operands {
+		out += fmt.Sprintf(" %d", o)
+	}
+	return out
+}
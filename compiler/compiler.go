@@ -0,0 +1,1040 @@
+package compiler
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"sugu/ast"
+	"sugu/object"
+	"sugu/token"
+)
+
+// Position は命令に対応するソースコード上の位置（エラー位置の表示に使用）
+type Position struct {
+	Offset int // 命令の先頭のオフセット
+	Line   int
+	Column int
+}
+
+// CompiledFunction はコンパイル済みの関数本体、またはトップレベルのコード
+type CompiledFunction struct {
+	Literal      *ast.FunctionLiteral // 元の関数リテラル（トップレベルのコードでは nil）
+	Instructions Instructions
+	Positions    []Position // Offset の昇順
+	Bytecode     *Bytecode  // 定数や名前を参照する表
+}
+
+// PositionAt は offset から始まる命令の位置を返す
+// 位置を記録していない命令（位置なしのエラーを返す命令）では false を返す
+func (cf *CompiledFunction) PositionAt(offset int) (Position, bool) {
+	i := sort.Search(len(cf.Positions), func(i int) bool {
+		return cf.Positions[i].Offset >= offset
+	})
+	if i < len(cf.Positions) && cf.Positions[i].Offset == offset {
+		return cf.Positions[i], true
+	}
+	return Position{}, false
+}
+
+// Error は位置情報付きのコンパイルエラー
+type Error struct {
+	Line    int
+	Column  int
+	Message string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("line %d, column %d: %s", e.Line, e.Column, e.Message)
+}
+
+// Bytecode はコンパイル結果
+// 関数本体もトップレベルのコードと同じ定数・名前の表を共有する
+type Bytecode struct {
+	Main      *CompiledFunction
+	Functions []*CompiledFunction    // OpClosure のオペランドで参照される関数
+	Constants []object.Object        // OpConstant / OpError のオペランドで参照される値
+	Names     []string               // 変数名（名前を扱う命令のオペランドで参照される）
+	Imports   []*ast.ImportStatement // OpImport のオペランドで参照される import 文
+	Patterns  []ast.Pattern          // OpDestructure のオペランドで参照される分割代入のパターン
+}
+
+// blockKind は break/continue の飛び先を決めるために追跡する構文の種類
+type blockKind int
+
+const (
+	loopBlock    blockKind = iota // while / for / for-in の本体
+	switchBlock                   // switch の case 本体（break だけを受け取る）
+	scopeBlock                    // OpPushScope で作ったスコープ
+	tryBlock                      // OpSetupTry で登録した try ブロック
+	finallyBlock                  // OpSetupFinally で登録した finally ブロックの対象（try と catch）
+	pendingBlock                  // finally ブロックの実行中（保留中の値をスタックに置いている）
+)
+
+type block struct {
+	kind      blockKind
+	breaks    []int // 飛び先を後で埋める OpJump の位置
+	continues []int
+	finally   *ast.BlockStatement // finallyBlock で抜けるときに実行するブロック
+}
+
+// compilationScope はコンパイル中の関数の状態
+type compilationScope struct {
+	fn     *CompiledFunction
+	blocks []*block
+}
+
+// Compiler は AST をバイトコードに変換する
+type Compiler struct {
+	bytecode *Bytecode
+	numbers  map[float64]int // 数値定数の重複排除
+	strings  map[string]int  // 文字列定数の重複排除
+	names    map[string]int
+	scope    *compilationScope
+}
+
+// New は新しい Compiler を作成する
+func New() *Compiler {
+	bc := &Bytecode{}
+	bc.Main = &CompiledFunction{Bytecode: bc}
+	return &Compiler{
+		bytecode: bc,
+		numbers:  make(map[float64]int),
+		strings:  make(map[string]int),
+		names:    make(map[string]int),
+		scope:    &compilationScope{fn: bc.Main},
+	}
+}
+
+// Compile はプログラムをトップレベルのコードとしてコンパイルする
+func (c *Compiler) Compile(program *ast.Program) error {
+	for _, stmt := range program.Statements {
+		c.emit(OpStep)
+		if err := c.compileStatement(stmt); err != nil {
+			return err
+		}
+	}
+	c.emit(OpReturnLast)
+	return c.checkLimits()
+}
+
+// checkLimits はオペランドで参照できる数を超えていないか確認する
+func (c *Compiler) checkLimits() error {
+	if len(c.bytecode.Constants) > 0xFFFF || len(c.bytecode.Names) >= NoName ||
+		len(c.bytecode.Functions) > 0xFFFF || len(c.bytecode.Imports) > 0xFFFF {
+		return fmt.Errorf("program too large to compile")
+	}
+	return nil
+}
+
+// Bytecode はコンパイル結果を返す
+func (c *Compiler) Bytecode() *Bytecode {
+	return c.bytecode
+}
+
+// CompileFunction は関数リテラルを単独でコンパイルする
+// VM が自分でコンパイルしていない関数（木構造評価器で作られた関数など）を呼び出すときに使う
+func CompileFunction(node *ast.FunctionLiteral) (*CompiledFunction, error) {
+	c := New()
+	idx, err := c.compileFunction(node)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.checkLimits(); err != nil {
+		return nil, err
+	}
+	return c.bytecode.Functions[idx], nil
+}
+
+// compileStatement は文をコンパイルする
+// 文の評価結果は OpPopLast で記録され、関数やプログラムの値として返される
+func (c *Compiler) compileStatement(stmt ast.Statement) error {
+	switch node := stmt.(type) {
+	case *ast.ExpressionStatement:
+		if err := c.compileExpression(node.Expression); err != nil {
+			return err
+		}
+		c.emit(OpPopLast)
+
+	case *ast.BlockStatement:
+		for _, s := range node.Statements {
+			c.emit(OpStep)
+			if err := c.compileStatement(s); err != nil {
+				return err
+			}
+		}
+
+	case *ast.VariableStatement:
+		if err := c.compileVariableStatement(node); err != nil {
+			return err
+		}
+		c.emit(OpPopLast)
+
+	case *ast.ReturnStatement:
+		if err := c.compileExpression(node.ReturnValue); err != nil {
+			return err
+		}
+		if err := c.compileReturnFinally(); err != nil {
+			return err
+		}
+		c.emit(OpReturnValue)
+
+	case *ast.IfStatement:
+		return c.compileIfStatement(node)
+
+	case *ast.WhileStatement:
+		return c.compileWhileStatement(node)
+
+	case *ast.ForStatement:
+		return c.compileForStatement(node)
+
+	case *ast.ForInStatement:
+		return c.compileForInStatement(node)
+
+	case *ast.SwitchStatement:
+		return c.compileSwitchStatement(node)
+
+	case *ast.BreakStatement:
+		return c.compileBreak(node.Token)
+
+	case *ast.ContinueStatement:
+		return c.compileContinue(node.Token)
+
+	case *ast.TryStatement:
+		return c.compileTryStatement(node)
+
+	case *ast.ThrowStatement:
+		if err := c.compileExpression(node.Value); err != nil {
+			return err
+		}
+		c.emitAt(node.Token, OpThrow)
+
+	case *ast.ImportStatement:
+		c.bytecode.Imports = append(c.bytecode.Imports, node)
+		c.emit(OpImport, len(c.bytecode.Imports)-1)
+		c.emit(OpPopLast)
+
+	case *ast.ExportStatement:
+		if err := c.compileStatement(node.Statement); err != nil {
+			return err
+		}
+		c.emit(OpExport, c.name(node.Name.Value))
+
+	default:
+		return fmt.Errorf("unsupported statement: %T", stmt)
+	}
+
+	return nil
+}
+
+// compileDiscarded は評価結果を記録しない文をコンパイルする（for の初期化式）
+func (c *Compiler) compileDiscarded(stmt ast.Statement) error {
+	switch node := stmt.(type) {
+	case *ast.ExpressionStatement:
+		if err := c.compileExpression(node.Expression); err != nil {
+			return err
+		}
+		c.emit(OpPop)
+		return nil
+	case *ast.VariableStatement:
+		if err := c.compileVariableStatement(node); err != nil {
+			return err
+		}
+		c.emit(OpPop)
+		return nil
+	default:
+		return c.compileStatement(stmt)
+	}
+}
+
+// compileVariableStatement は変数宣言をコンパイルする（宣言した値はスタックに残る）
+func (c *Compiler) compileVariableStatement(node *ast.VariableStatement) error {
+	if err := c.compileExpression(node.Value); err != nil {
+		return err
+	}
+	constant := node.Token.Literal == "const"
+	switch {
+	case node.Pattern != nil:
+		c.emitDestructure(node.Token, node.Pattern, constant)
+	case constant:
+		c.emit(OpDefineConst, c.name(node.Name.Value))
+	default:
+		c.emit(OpDefine, c.name(node.Name.Value))
+	}
+	return nil
+}
+
+// emitDestructure はスタックの先頭の値を pattern で分割代入する命令を追加する（値はスタックに残る）
+// 値がパターンに合わない場合のエラーは tok の位置になる
+func (c *Compiler) emitDestructure(tok token.Token, pattern ast.Pattern, constant bool) {
+	c.bytecode.Patterns = append(c.bytecode.Patterns, pattern)
+	flag := 0
+	if constant {
+		flag = 1
+	}
+	c.emitAt(tok, OpDestructure, len(c.bytecode.Patterns)-1, flag)
+}
+
+// compileIfStatement はif文をコンパイルする
+// どの節も実行されなかった場合、文の値は null になる
+func (c *Compiler) compileIfStatement(node *ast.IfStatement) error {
+	if err := c.compileExpression(node.Condition); err != nil {
+		return err
+	}
+	jumpNotTruthy := c.emit(OpJumpNotTruthy, 0)
+
+	if err := c.compileStatement(node.Consequence); err != nil {
+		return err
+	}
+	jump := c.emit(OpJump, 0)
+
+	c.patchJump(jumpNotTruthy)
+	if node.Alternative != nil {
+		if err := c.compileStatement(node.Alternative); err != nil {
+			return err
+		}
+	} else {
+		c.emit(OpNull)
+		c.emit(OpPopLast)
+	}
+	c.patchJump(jump)
+
+	return nil
+}
+
+// compileWhileStatement はwhile文をコンパイルする
+func (c *Compiler) compileWhileStatement(node *ast.WhileStatement) error {
+	c.emit(OpNull)
+	c.emit(OpPopLast)
+
+	loopStart := c.emit(OpStep)
+	if err := c.compileExpression(node.Condition); err != nil {
+		return err
+	}
+	exit := c.emit(OpJumpNotTruthy, 0)
+
+	loop := c.enterBlock(loopBlock)
+	if err := c.compileStatement(node.Body); err != nil {
+		return err
+	}
+	c.leaveBlock()
+	c.emit(OpJump, loopStart)
+
+	c.patchLoopExits(loop, loopStart)
+	c.patchJump(exit)
+	return nil
+}
+
+// compileForStatement はfor文をコンパイルする
+// 初期化式で宣言した変数はfor文専用のスコープに置かれる
+func (c *Compiler) compileForStatement(node *ast.ForStatement) error {
+	c.emit(OpPushScope)
+	c.enterBlock(scopeBlock)
+
+	if node.Init != nil {
+		if err := c.compileDiscarded(node.Init); err != nil {
+			return err
+		}
+	}
+
+	c.emit(OpNull)
+	c.emit(OpPopLast)
+
+	loopStart := c.emit(OpStep)
+	exit := -1
+	if node.Condition != nil {
+		if err := c.compileExpression(node.Condition); err != nil {
+			return err
+		}
+		exit = c.emit(OpJumpNotTruthy, 0)
+	}
+
+	loop := c.enterBlock(loopBlock)
+	if err := c.compileStatement(node.Body); err != nil {
+		return err
+	}
+	c.leaveBlock()
+
+	// continue は更新式に飛ぶ
+	update := c.offset()
+	if node.Update != nil {
+		if err := c.compileExpression(node.Update); err != nil {
+			return err
+		}
+		c.emit(OpPop)
+	}
+	c.emit(OpJump, loopStart)
+
+	c.patchLoopExits(loop, update)
+	if exit >= 0 {
+		c.patchJump(exit)
+	}
+	c.leaveBlock()
+	c.emit(OpPopScope)
+	return nil
+}
+
+// compileForInStatement はfor-in文をコンパイルする
+// 反復ごとのスコープは OpIterNext が作り、本体の最後の OpPopScope で破棄する
+func (c *Compiler) compileForInStatement(node *ast.ForInStatement) error {
+	if err := c.compileExpression(node.Iterable); err != nil {
+		return err
+	}
+	c.emitAt(node.Token, OpIterInit)
+
+	c.emit(OpNull)
+	c.emit(OpPopLast)
+
+	value := NoName
+	if node.Value != nil {
+		value = c.name(node.Value.Value)
+	}
+	loopStart := c.emit(OpIterNext, 0, c.name(node.Key.Value), value)
+	c.emit(OpStep)
+
+	// パターンの変数は、仮の識別子に束縛された値を分割代入する
+	if node.KeyPattern != nil {
+		c.emit(OpGetVar, c.name(node.Key.Value))
+		c.emitDestructure(node.Token, node.KeyPattern, true)
+		c.emit(OpPop)
+	}
+	if node.ValuePattern != nil {
+		c.emit(OpGetVar, c.name(node.Value.Value))
+		c.emitDestructure(node.Token, node.ValuePattern, true)
+		c.emit(OpPop)
+	}
+
+	loop := c.enterBlock(loopBlock)
+	c.enterBlock(scopeBlock)
+	if err := c.compileStatement(node.Body); err != nil {
+		return err
+	}
+	c.leaveBlock()
+	c.leaveBlock()
+	c.emit(OpPopScope)
+	c.emit(OpJump, loopStart)
+
+	c.patchLoopExits(loop, loopStart)
+	c.patchJump(loopStart)
+	c.emit(OpPop) // イテレータ
+	return nil
+}
+
+// compileSwitchStatement はswitch文をコンパイルする
+// case の値は一致するものが見つかるまで順に評価される
+func (c *Compiler) compileSwitchStatement(node *ast.SwitchStatement) error {
+	if err := c.compileExpression(node.Value); err != nil {
+		return err
+	}
+
+	sw := &block{kind: switchBlock}
+	ends := []int{}
+
+	for _, clause := range node.Cases {
+		c.emit(OpDup)
+		if err := c.compileExpression(clause.Value); err != nil {
+			return err
+		}
+		c.emit(OpSwitchEqual)
+		next := c.emit(OpJumpNotTruthy, 0)
+
+		c.emit(OpPop)
+		if err := c.compileSwitchBody(sw, clause.Body); err != nil {
+			return err
+		}
+		ends = append(ends, c.emit(OpJump, 0))
+
+		c.patchJump(next)
+	}
+
+	c.emit(OpPop)
+	if node.Default != nil {
+		if err := c.compileSwitchBody(sw, node.Default); err != nil {
+			return err
+		}
+	} else {
+		c.emit(OpNull)
+		c.emit(OpPopLast)
+	}
+
+	// switch 内の break は switch を抜け、値は null になる
+	if len(sw.breaks) > 0 {
+		ends = append(ends, c.emit(OpJump, 0))
+		for _, pos := range sw.breaks {
+			c.patchJump(pos)
+		}
+		c.emit(OpNull)
+		c.emit(OpPopLast)
+	}
+
+	for _, pos := range ends {
+		c.patchJump(pos)
+	}
+	return nil
+}
+
+// compileSwitchBody は case / default の本体をコンパイルする
+func (c *Compiler) compileSwitchBody(sw *block, body *ast.BlockStatement) error {
+	c.scope.blocks = append(c.scope.blocks, sw)
+	defer c.leaveBlock()
+	return c.compileStatement(body)
+}
+
+// compileTryStatement はtry/catch/finally文をコンパイルする
+// finally ブロックは正常に抜けた場合と例外が発生した場合で同じコードを実行し、
+// return/break/continue で抜ける場合はその場に finally ブロックを展開する
+func (c *Compiler) compileTryStatement(node *ast.TryStatement) error {
+	if node.FinallyBlock == nil {
+		return c.compileTryCatch(node)
+	}
+
+	setup := c.emit(OpSetupFinally, 0)
+	fb := c.enterBlock(finallyBlock)
+	fb.finally = node.FinallyBlock
+	if node.CatchBlock != nil {
+		if err := c.compileTryCatch(node); err != nil {
+			return err
+		}
+	} else if err := c.compileStatement(node.TryBlock); err != nil {
+		return err
+	}
+	c.leaveBlock()
+	c.emit(OpPopTry)
+	c.emit(OpEnterFinally)
+
+	// finally ブロック: 例外が発生した場合は VM が保留中の例外を積んでからここに飛ぶ
+	c.patchJump(setup)
+	if err := c.compilePending(node.FinallyBlock); err != nil {
+		return err
+	}
+	c.emit(OpEndFinally)
+	return nil
+}
+
+// compileTryCatch はtry/catch文の try ブロックと catch ブロックをコンパイルする
+func (c *Compiler) compileTryCatch(node *ast.TryStatement) error {
+	setup := c.emit(OpSetupTry, 0)
+
+	c.enterBlock(tryBlock)
+	if err := c.compileStatement(node.TryBlock); err != nil {
+		return err
+	}
+	c.leaveBlock()
+	c.emit(OpPopTry)
+	end := c.emit(OpJump, 0)
+
+	// catch ブロック: VM が catch した値を積んでからここに飛ぶ
+	c.patchJump(setup)
+	c.emit(OpPushScope)
+	c.emit(OpDefine, c.name(node.CatchParam.Value))
+	c.emit(OpPop)
+	c.enterBlock(scopeBlock)
+	if err := c.compileStatement(node.CatchBlock); err != nil {
+		return err
+	}
+	c.leaveBlock()
+	c.emit(OpPopScope)
+
+	c.patchJump(end)
+	return nil
+}
+
+// compilePending はスタックに値を置いたまま finally ブロックをコンパイルする
+// finally ブロックから break/continue で抜ける場合は置いた値を捨てる
+func (c *Compiler) compilePending(body *ast.BlockStatement) error {
+	c.enterBlock(pendingBlock)
+	defer c.leaveBlock()
+	return c.compileStatement(body)
+}
+
+// inlineFinally は i 番目のブロックの finally ブロックを登録から外し、その場で実行するコードを出力する
+// 展開した finally ブロックの中の break/continue は、finally ブロックの外側のブロックを対象にする
+func (c *Compiler) inlineFinally(i int, pending bool) error {
+	c.emit(OpPopTry)
+
+	blocks := c.scope.blocks
+	c.scope.blocks = append([]*block(nil), blocks[:i]...)
+	defer func() { c.scope.blocks = blocks }()
+
+	if pending {
+		return c.compilePending(blocks[i].finally)
+	}
+	return c.compileStatement(blocks[i].finally)
+}
+
+// compileReturnFinally は return で抜ける finally ブロックを内側から順に展開する
+// 返り値はスタックに置いたまま、finally ブロックが置かれたスコープに戻ってから実行する
+func (c *Compiler) compileReturnFinally() error {
+	outermost := -1
+	for i, b := range c.scope.blocks {
+		if b.kind == finallyBlock {
+			outermost = i
+			break
+		}
+	}
+	if outermost < 0 {
+		return nil
+	}
+
+	for i := len(c.scope.blocks) - 1; i >= outermost; i-- {
+		switch c.scope.blocks[i].kind {
+		case scopeBlock:
+			c.emit(OpPopScope)
+		case tryBlock:
+			c.emit(OpPopTry)
+		case finallyBlock:
+			if err := c.inlineFinally(i, true); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// compileBreak は break をコンパイルする
+// 抜けるスコープと try を片付け、finally ブロックを実行してから、ループまたは switch の出口に飛ぶ
+func (c *Compiler) compileBreak(tok token.Token) error {
+	for i := len(c.scope.blocks) - 1; i >= 0; i-- {
+		b := c.scope.blocks[i]
+		switch b.kind {
+		case scopeBlock:
+			c.emit(OpPopScope)
+		case tryBlock:
+			c.emit(OpPopTry)
+		case finallyBlock:
+			if err := c.inlineFinally(i, false); err != nil {
+				return err
+			}
+		case pendingBlock:
+			c.emit(OpPop)
+		case loopBlock, switchBlock:
+			b.breaks = append(b.breaks, c.emit(OpJump, 0))
+			return nil
+		}
+	}
+	return errorAt(tok, "break outside loop")
+}
+
+// compileContinue は continue をコンパイルする
+// switch は continue を受け取らないので、外側のループまで抜ける
+func (c *Compiler) compileContinue(tok token.Token) error {
+	for i := len(c.scope.blocks) - 1; i >= 0; i-- {
+		b := c.scope.blocks[i]
+		switch b.kind {
+		case scopeBlock:
+			c.emit(OpPopScope)
+		case tryBlock:
+			c.emit(OpPopTry)
+		case finallyBlock:
+			if err := c.inlineFinally(i, false); err != nil {
+				return err
+			}
+		case pendingBlock:
+			c.emit(OpPop)
+		case loopBlock:
+			b.continues = append(b.continues, c.emit(OpJump, 0))
+			return nil
+		}
+	}
+	return errorAt(tok, "continue outside loop")
+}
+
+// patchLoopExits は break と continue の飛び先を埋める
+// break で抜けた場合、ループの値は null になる
+func (c *Compiler) patchLoopExits(loop *block, continueTarget int) {
+	for _, pos := range loop.continues {
+		c.patchJumpTo(pos, continueTarget)
+	}
+	if len(loop.breaks) == 0 {
+		return
+	}
+	for _, pos := range loop.breaks {
+		c.patchJump(pos)
+	}
+	c.emit(OpNull)
+	c.emit(OpPopLast)
+}
+
+// compileExpression は式をコンパイルする（結果の値はスタックに積まれる）
+func (c *Compiler) compileExpression(exp ast.Expression) error {
+	switch node := exp.(type) {
+	case nil:
+		c.emit(OpNull)
+
+	case *ast.Identifier:
+		c.emitAt(node.Token, OpGetName, c.name(node.Value))
+
+	case *ast.NumberLiteral:
+		val, err := strconv.ParseFloat(node.Value, 64)
+		if err != nil {
+			msg := fmt.Sprintf("could not parse %q as number", node.Value)
+			c.emitAt(node.Token, OpError, c.stringConstant(msg), c.stringConstant(object.VALUE_ERROR))
+			return nil
+		}
+		c.emit(OpConstant, c.numberConstant(val))
+
+	case *ast.StringLiteral:
+		c.emit(OpConstant, c.stringConstant(node.Value))
+
+	case *ast.TemplateLiteral:
+		n := 0
+		for i, str := range node.Strings {
+			if str != "" {
+				c.emit(OpConstant, c.stringConstant(str))
+				n++
+			}
+			if i < len(node.Expressions) {
+				if err := c.compileExpression(node.Expressions[i]); err != nil {
+					return err
+				}
+				n++
+			}
+		}
+		c.emit(OpTemplate, n)
+
+	case *ast.BooleanLiteral:
+		if node.Value {
+			c.emit(OpTrue)
+		} else {
+			c.emit(OpFalse)
+		}
+
+	case *ast.NullLiteral:
+		c.emit(OpNull)
+
+	case *ast.PrefixExpression:
+		if err := c.compileExpression(node.Right); err != nil {
+			return err
+		}
+		switch node.Operator {
+		case "!":
+			c.emitAt(node.Token, OpBang)
+		case "-":
+			c.emitAt(node.Token, OpMinus)
+		default:
+			return errorAt(node.Token, "unknown operator: %s", node.Operator)
+		}
+
+	case *ast.InfixExpression:
+		if node.Operator == "&&" || node.Operator == "||" {
+			return c.compileLogicalExpression(node)
+		}
+		op, ok := infixOpcodes[node.Operator]
+		if !ok {
+			return errorAt(node.Token, "unknown operator: %s", node.Operator)
+		}
+		if err := c.compileExpression(node.Left); err != nil {
+			return err
+		}
+		if err := c.compileExpression(node.Right); err != nil {
+			return err
+		}
+		c.emitAt(node.Token, op)
+
+	case *ast.FunctionLiteral:
+		idx, err := c.compileFunction(node)
+		if err != nil {
+			return err
+		}
+		c.emit(OpClosure, idx)
+
+	case *ast.CallExpression:
+		if len(node.Arguments) > 255 {
+			return errorAt(node.Token, "too many arguments")
+		}
+		if err := c.compileExpression(node.Function); err != nil {
+			return err
+		}
+		for _, arg := range node.Arguments {
+			if err := c.compileExpression(arg); err != nil {
+				return err
+			}
+		}
+		c.emitAt(node.Token, OpCall, len(node.Arguments))
+
+	case *ast.AssignExpression:
+		if err := c.compileExpression(node.Value); err != nil {
+			return err
+		}
+		name := c.name(node.Name.Value)
+		c.emitAt(node.Name.Token, OpCheckDefined, name)
+		c.emitAt(node.Token, OpAssign, name)
+
+	case *ast.ArrayLiteral:
+		for _, el := range node.Elements {
+			if err := c.compileExpression(el); err != nil {
+				return err
+			}
+		}
+		c.emit(OpArray, len(node.Elements))
+
+	case *ast.IndexExpression:
+		if err := c.compileExpression(node.Left); err != nil {
+			return err
+		}
+		if err := c.compileExpression(node.Index); err != nil {
+			return err
+		}
+		c.emitAt(node.Token, OpIndex)
+
+	case *ast.MapLiteral:
+		for _, pair := range node.Pairs {
+			if err := c.compileExpression(pair.Key); err != nil {
+				return err
+			}
+			c.emitAt(node.Token, OpHashKey)
+			if err := c.compileExpression(pair.Value); err != nil {
+				return err
+			}
+		}
+		c.emit(OpMap, len(node.Pairs))
+
+	case *ast.IndexAssignExpression:
+		if ident, ok := node.Left.(*ast.Identifier); ok {
+			c.emitAt(node.Token, OpCheckMutable, c.name(ident.Value), MutateModify)
+		}
+		for _, e := range []ast.Expression{node.Left, node.Index, node.Value} {
+			if err := c.compileExpression(e); err != nil {
+				return err
+			}
+		}
+		c.emitAt(node.Token, OpSetIndex)
+
+	case *ast.PostfixExpression:
+		ident, ok := node.Operand.(*ast.Identifier)
+		if !ok {
+			msg := fmt.Sprintf("postfix operator %s requires a variable", node.Operator)
+			c.emitAt(node.Token, OpError, c.stringConstant(msg), c.stringConstant(object.TYPE_ERROR))
+			return nil
+		}
+		name := c.name(ident.Value)
+		c.emitAt(ident.Token, OpGetVar, name)
+		decrement := 0
+		if node.Operator == "--" {
+			decrement = 1
+		}
+		c.emitAt(node.Token, OpPostfix, name, decrement)
+
+	case *ast.CompoundAssignExpression:
+		op, ok := infixOpcodes[node.Operator[:1]]
+		if !ok {
+			return errorAt(node.Token, "unknown operator: %s", node.Operator)
+		}
+		name := c.name(node.Name.Value)
+		c.emitAt(node.Name.Token, OpGetVar, name)
+		c.emitAt(node.Token, OpCheckMutable, name, MutateReassign)
+		if err := c.compileExpression(node.Value); err != nil {
+			return err
+		}
+		c.emitAt(node.Token, op)
+		c.emitAt(node.Token, OpUpdate, name)
+
+	case *ast.IndexCompoundAssignExpression:
+		op, ok := infixOpcodes[node.Operator[:1]]
+		if !ok {
+			return errorAt(node.Token, "unknown operator: %s", node.Operator)
+		}
+		if ident, ok := node.Left.(*ast.Identifier); ok {
+			c.emitAt(node.Token, OpCheckMutable, c.name(ident.Value), MutateModify)
+		}
+		for _, e := range []ast.Expression{node.Left, node.Index, node.Value} {
+			if err := c.compileExpression(e); err != nil {
+				return err
+			}
+		}
+		c.emitAt(node.Token, OpIndexCompound, int(op))
+
+	case *ast.SliceExpression:
+		if err := c.compileExpression(node.Left); err != nil {
+			return err
+		}
+		flags := 0
+		if node.Low != nil {
+			if err := c.compileExpression(node.Low); err != nil {
+				return err
+			}
+			c.emitAt(node.Token, OpSliceIndex)
+			flags |= SliceLow
+		}
+		if node.High != nil {
+			if err := c.compileExpression(node.High); err != nil {
+				return err
+			}
+			c.emitAt(node.Token, OpSliceIndex)
+			flags |= SliceHigh
+		}
+		c.emitAt(node.Token, OpSlice, flags)
+
+	default:
+		return fmt.Errorf("unsupported expression: %T", exp)
+	}
+
+	return nil
+}
+
+// compileLogicalExpression は && と || を短絡評価するようにコンパイルする
+// 結果は真偽値に変換せず、最後に評価したオペランドの値になる
+func (c *Compiler) compileLogicalExpression(node *ast.InfixExpression) error {
+	if err := c.compileExpression(node.Left); err != nil {
+		return err
+	}
+
+	op := OpJumpFalsyKeep
+	if node.Operator == "||" {
+		op = OpJumpTruthyKeep
+	}
+	jump := c.emit(op, 0)
+
+	if err := c.compileExpression(node.Right); err != nil {
+		return err
+	}
+	c.patchJump(jump)
+	return nil
+}
+
+// compileFunction は関数本体をコンパイルし、Functions のインデックスを返す
+// 関数の値は明示的な return がなければ最後に評価した文の結果になる
+func (c *Compiler) compileFunction(node *ast.FunctionLiteral) (int, error) {
+	outer := c.scope
+	fn := &CompiledFunction{Literal: node, Bytecode: c.bytecode}
+	c.scope = &compilationScope{fn: fn}
+
+	err := c.compileDefaults(node)
+	if err == nil {
+		err = c.compileStatement(node.Body)
+	}
+	c.emit(OpReturnLast)
+	c.scope = outer
+	if err != nil {
+		return 0, err
+	}
+
+	c.bytecode.Functions = append(c.bytecode.Functions, fn)
+	return len(c.bytecode.Functions) - 1, nil
+}
+
+// compileDefaults は引数が渡されなかったパラメータにデフォルト値を束縛する命令を、パラメータの順にコンパイルする
+func (c *Compiler) compileDefaults(node *ast.FunctionLiteral) error {
+	for i, param := range node.Parameters {
+		if node.Defaults == nil || node.Defaults[i] == nil {
+			continue
+		}
+		name := c.name(param.Value)
+		jump := c.emit(OpJumpIfBound, 0, name)
+		if err := c.compileExpression(node.Defaults[i]); err != nil {
+			return err
+		}
+		c.emit(OpDefine, name)
+		if node.Patterns != nil && node.Patterns[i] != nil {
+			c.emitDestructure(param.Token, node.Patterns[i], false)
+		}
+		c.emit(OpPop)
+		c.patchJump(jump)
+	}
+	return nil
+}
+
+var infixOpcodes = map[string]Opcode{
+	"+":  OpAdd,
+	"-":  OpSub,
+	"*":  OpMul,
+	"/":  OpDiv,
+	"%":  OpMod,
+	"==": OpEqual,
+	"!=": OpNotEqual,
+	"<":  OpLess,
+	">":  OpGreater,
+	"<=": OpLessEqual,
+	">=": OpGreaterEqual,
+}
+
+var infixOperators = map[Opcode]string{}
+
+func init() {
+	for operator, op := range infixOpcodes {
+		infixOperators[op] = operator
+	}
+}
+
+// InfixOperator は中置演算子の命令に対応する演算子を返す
+func InfixOperator(op Opcode) string {
+	return infixOperators[op]
+}
+
+// ヘルパー関数
+
+func errorAt(tok token.Token, format string, a ...interface{}) *Error {
+	return &Error{Line: tok.Line, Column: tok.Column, Message: fmt.Sprintf(format, a...)}
+}
+
+// emit は命令を追加し、その位置を返す
+func (c *Compiler) emit(op Opcode, operands ...int) int {
+	pos := len(c.scope.fn.Instructions)
+	c.scope.fn.Instructions = append(c.scope.fn.Instructions, Make(op, operands...)...)
+	return pos
+}
+
+// emitAt は位置情報付きで命令を追加する（命令がエラーを返す場合に位置を表示するため）
+func (c *Compiler) emitAt(tok token.Token, op Opcode, operands ...int) int {
+	pos := c.emit(op, operands...)
+	c.scope.fn.Positions = append(c.scope.fn.Positions, Position{Offset: pos, Line: tok.Line, Column: tok.Column})
+	return pos
+}
+
+func (c *Compiler) offset() int {
+	return len(c.scope.fn.Instructions)
+}
+
+// patchJump は pos のジャンプ命令の飛び先を現在の位置にする
+func (c *Compiler) patchJump(pos int) {
+	c.patchJumpTo(pos, c.offset())
+}
+
+// patchJumpTo は pos の命令の最初のオペランド（飛び先）を target にする
+func (c *Compiler) patchJumpTo(pos, target int) {
+	ins := c.scope.fn.Instructions
+	op := Opcode(ins[pos])
+	def := definitions[op]
+	operands, _ := ReadOperands(def, ins[pos+1:])
+	operands[0] = target
+	copy(ins[pos:], Make(op, operands...))
+}
+
+func (c *Compiler) enterBlock(kind blockKind) *block {
+	b := &block{kind: kind}
+	c.scope.blocks = append(c.scope.blocks, b)
+	return b
+}
+
+func (c *Compiler) leaveBlock() {
+	c.scope.blocks = c.scope.blocks[:len(c.scope.blocks)-1]
+}
+
+func (c *Compiler) numberConstant(val float64) int {
+	if idx, ok := c.numbers[val]; ok {
+		return idx
+	}
+	c.bytecode.Constants = append(c.bytecode.Constants, &object.Number{Value: val})
+	idx := len(c.bytecode.Constants) - 1
+	c.numbers[val] = idx
+	return idx
+}
+
+func (c *Compiler) stringConstant(val string) int {
+	if idx, ok := c.strings[val]; ok {
+		return idx
+	}
+	c.bytecode.Constants = append(c.bytecode.Constants, &object.String{Value: val})
+	idx := len(c.bytecode.Constants) - 1
+	c.strings[val] = idx
+	return idx
+}
+
+func (c *Compiler) name(name string) int {
+	if idx, ok := c.names[name]; ok {
+		return idx
+	}
+	c.bytecode.Names = append(c.bytecode.Names, name)
+	idx := len(c.bytecode.Names) - 1
+	c.names[name] = idx
+	return idx
+}
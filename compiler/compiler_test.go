@@ -0,0 +1,350 @@
+package compiler
+
+import (
+	"sugu/ast"
+	"sugu/lexer"
+	"sugu/object"
+	"sugu/parser"
+	"testing"
+)
+
+type compilerTestCase struct {
+	input                string
+	expectedConstants    []interface{}
+	expectedNames        []string
+	expectedInstructions []Instructions
+}
+
+func TestNumberArithmetic(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input:             "1 + 2; 1.5",
+			expectedConstants: []interface{}{1.0, 2.0, 1.5},
+			expectedInstructions: []Instructions{
+				Make(OpStep),
+				Make(OpConstant, 0),
+				Make(OpConstant, 1),
+				Make(OpAdd),
+				Make(OpPopLast),
+				Make(OpStep),
+				Make(OpConstant, 2),
+				Make(OpPopLast),
+				Make(OpReturnLast),
+			},
+		},
+		{
+			input:             "-1 < 1",
+			expectedConstants: []interface{}{1.0},
+			expectedInstructions: []Instructions{
+				Make(OpStep),
+				Make(OpConstant, 0),
+				Make(OpMinus),
+				Make(OpConstant, 0),
+				Make(OpLess),
+				Make(OpPopLast),
+				Make(OpReturnLast),
+			},
+		},
+	}
+
+	runCompilerTests(t, tests)
+}
+
+func TestVariables(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input:             `mut x = "a"; const y = x; x = y;`,
+			expectedConstants: []interface{}{"a"},
+			expectedNames:     []string{"x", "y"},
+			expectedInstructions: []Instructions{
+				Make(OpStep),
+				Make(OpConstant, 0),
+				Make(OpDefine, 0),
+				Make(OpPopLast),
+				Make(OpStep),
+				Make(OpGetName, 0),
+				Make(OpDefineConst, 1),
+				Make(OpPopLast),
+				Make(OpStep),
+				Make(OpGetName, 1),
+				Make(OpCheckDefined, 0),
+				Make(OpAssign, 0),
+				Make(OpPopLast),
+				Make(OpReturnLast),
+			},
+		},
+		{
+			input:         "x += 1; x++;",
+			expectedNames: []string{"x"},
+			expectedInstructions: []Instructions{
+				Make(OpStep),
+				Make(OpGetVar, 0),
+				Make(OpCheckMutable, 0, MutateReassign),
+				Make(OpConstant, 0),
+				Make(OpAdd),
+				Make(OpUpdate, 0),
+				Make(OpPopLast),
+				Make(OpStep),
+				Make(OpGetVar, 0),
+				Make(OpPostfix, 0, 0),
+				Make(OpPopLast),
+				Make(OpReturnLast),
+			},
+		},
+	}
+
+	runCompilerTests(t, tests)
+}
+
+func TestTemplateLiteral(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			// 空の文字列の部分は積まない
+			input:             "`a${1}${2}`",
+			expectedConstants: []interface{}{"a", 1.0, 2.0},
+			expectedInstructions: []Instructions{
+				Make(OpStep),
+				Make(OpConstant, 0),
+				Make(OpConstant, 1),
+				Make(OpConstant, 2),
+				Make(OpTemplate, 3),
+				Make(OpPopLast),
+				Make(OpReturnLast),
+			},
+		},
+	}
+
+	runCompilerTests(t, tests)
+}
+
+func TestConditionals(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input: "if (true) { 10 }",
+			expectedInstructions: []Instructions{
+				// 0000
+				Make(OpStep),
+				// 0001
+				Make(OpTrue),
+				// 0002
+				Make(OpJumpNotTruthy, 17),
+				// 0007
+				Make(OpStep),
+				// 0008
+				Make(OpConstant, 0),
+				// 0011
+				Make(OpPopLast),
+				// 0012
+				Make(OpJump, 19),
+				// 0017
+				Make(OpNull),
+				// 0018
+				Make(OpPopLast),
+				// 0019
+				Make(OpReturnLast),
+			},
+		},
+		{
+			input: "true && false",
+			expectedInstructions: []Instructions{
+				// 0000
+				Make(OpStep),
+				// 0001
+				Make(OpTrue),
+				// 0002
+				Make(OpJumpFalsyKeep, 8),
+				// 0007
+				Make(OpFalse),
+				// 0008
+				Make(OpPopLast),
+				// 0009
+				Make(OpReturnLast),
+			},
+		},
+	}
+
+	runCompilerTests(t, tests)
+}
+
+func TestLoops(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input: "while (true) { break; }",
+			expectedInstructions: []Instructions{
+				// 0000
+				Make(OpStep),
+				// 0001
+				Make(OpNull),
+				// 0002
+				Make(OpPopLast),
+				// 0003 反復ごとに 1 ステップ
+				Make(OpStep),
+				// 0004
+				Make(OpTrue),
+				// 0005
+				Make(OpJumpNotTruthy, 23),
+				// 0010
+				Make(OpStep),
+				// 0011 break
+				Make(OpJump, 21),
+				// 0016
+				Make(OpJump, 3),
+				// 0021 break で抜けた場合は null
+				Make(OpNull),
+				// 0022
+				Make(OpPopLast),
+				// 0023
+				Make(OpReturnLast),
+			},
+		},
+		{
+			input:         "for (x in []) { continue; }",
+			expectedNames: []string{"x"},
+			expectedInstructions: []Instructions{
+				// 0000
+				Make(OpStep),
+				// 0001
+				Make(OpArray, 0),
+				// 0004
+				Make(OpIterInit),
+				// 0005
+				Make(OpNull),
+				// 0006
+				Make(OpPopLast),
+				// 0007
+				Make(OpIterNext, 30, 0, NoName),
+				// 0016 反復ごとに 1 ステップ
+				Make(OpStep),
+				// 0017
+				Make(OpStep),
+				// 0018 continue は反復のスコープを破棄してから戻る
+				Make(OpPopScope),
+				// 0019
+				Make(OpJump, 7),
+				// 0024
+				Make(OpPopScope),
+				// 0025
+				Make(OpJump, 7),
+				// 0030 イテレータを捨てる
+				Make(OpPop),
+				// 0031
+				Make(OpReturnLast),
+			},
+		},
+	}
+
+	runCompilerTests(t, tests)
+}
+
+func TestPositions(t *testing.T) {
+	program := parse("mut a = 1;\n  b;")
+	comp := New()
+	if err := comp.Compile(program); err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+	main := comp.Bytecode().Main
+
+	// OpStep(1) OpConstant(3) OpDefine(3) OpPopLast(1) OpStep(1) の次が OpGetName
+	pos, ok := main.PositionAt(9)
+	if !ok {
+		t.Fatalf("position not recorded for OpGetName")
+	}
+	if pos.Line != 2 || pos.Column != 3 {
+		t.Errorf("wrong position. want=2:3, got=%d:%d", pos.Line, pos.Column)
+	}
+
+	if _, ok := main.PositionAt(1); ok {
+		t.Errorf("position should not be recorded for OpConstant")
+	}
+}
+
+func TestCompilerErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"break;", "line 1, column 1: break outside loop"},
+		{"if (true) {\n  continue;\n}", "line 2, column 3: continue outside loop"},
+		{"while (true) { func() => { break; } }", "line 1, column 28: break outside loop"},
+		{"switch (1) { case 1: { continue; } }", "line 1, column 24: continue outside loop"},
+	}
+
+	for _, tt := range tests {
+		comp := New()
+		err := comp.Compile(parse(tt.input))
+		if err == nil {
+			t.Errorf("expected error for %q", tt.input)
+			continue
+		}
+		if err.Error() != tt.expected {
+			t.Errorf("wrong error. want=%q, got=%q", tt.expected, err.Error())
+		}
+	}
+}
+
+// ヘルパー関数
+
+func parse(input string) *ast.Program {
+	l := lexer.New(input)
+	p := parser.New(l)
+	return p.ParseProgram()
+}
+
+func runCompilerTests(t *testing.T, tests []compilerTestCase) {
+	t.Helper()
+
+	for _, tt := range tests {
+		comp := New()
+		if err := comp.Compile(parse(tt.input)); err != nil {
+			t.Fatalf("compiler error: %s", err)
+		}
+
+		bytecode := comp.Bytecode()
+
+		expected := Instructions{}
+		for _, ins := range tt.expectedInstructions {
+			expected = append(expected, ins...)
+		}
+		if bytecode.Main.Instructions.String() != expected.String() {
+			t.Errorf("wrong instructions for %q.\nwant=\n%s\ngot=\n%s", tt.input, expected, bytecode.Main.Instructions)
+		}
+
+		if tt.expectedConstants != nil {
+			testConstants(t, tt.expectedConstants, bytecode.Constants)
+		}
+
+		if tt.expectedNames != nil {
+			if len(bytecode.Names) != len(tt.expectedNames) {
+				t.Fatalf("wrong number of names. want=%d, got=%d", len(tt.expectedNames), len(bytecode.Names))
+			}
+			for i, name := range tt.expectedNames {
+				if bytecode.Names[i] != name {
+					t.Errorf("name %d wrong. want=%q, got=%q", i, name, bytecode.Names[i])
+				}
+			}
+		}
+	}
+}
+
+func testConstants(t *testing.T, expected []interface{}, actual []object.Object) {
+	t.Helper()
+
+	if len(expected) != len(actual) {
+		t.Fatalf("wrong number of constants. want=%d, got=%d", len(expected), len(actual))
+	}
+
+	for i, constant := range expected {
+		switch constant := constant.(type) {
+		case float64:
+			num, ok := actual[i].(*object.Number)
+			if !ok || num.Value != constant {
+				t.Errorf("constant %d wrong. want=%v, got=%s", i, constant, actual[i].Inspect())
+			}
+		case string:
+			str, ok := actual[i].(*object.String)
+			if !ok || str.Value != constant {
+				t.Errorf("constant %d wrong. want=%q, got=%s", i, constant, actual[i].Inspect())
+			}
+		}
+	}
+}
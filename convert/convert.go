@@ -0,0 +1,364 @@
+// Package convert は Go の値と Sugu の値（object.Object）を相互に変換する
+//
+// 構造体はエクスポートされたフィールドをキーにしたマップになる。キーの名前は
+// `sugu:"name"` タグで変更でき、`sugu:"-"` のフィールドは変換しない。
+// `sugu:"name,omitempty"` のフィールドはゼロ値の場合に省略する。
+package convert
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+	"sugu/object"
+	"time"
+)
+
+// maxExactInt は float64 で正確に表せる整数の最大値（Sugu の数値は float64）
+const maxExactInt = 1 << 53
+
+var (
+	objectType = reflect.TypeOf((*object.Object)(nil)).Elem()
+	errorType  = reflect.TypeOf((*error)(nil)).Elem()
+	timeType   = reflect.TypeOf(time.Time{})
+)
+
+// ToObject は Go の値を Sugu の値に変換する
+//
+//   - nil、nil のポインタ・スライス・マップは null
+//   - bool は真偽値、整数と浮動小数点数は数値、string と []byte は文字列
+//   - スライスと配列は配列、キーが文字列・数値・真偽値のマップはマップ
+//   - 構造体はフィールド名（または sugu タグの名前）をキーにしたマップ
+//   - time.Time は日時（object.Time）
+//   - 関数は組み込み関数（引数は FromObject で変換し、戻り値の error は実行時エラーになる）
+//   - object.Object はそのまま
+//
+// チャネルなど変換できない値や、float64 で正確に表せない整数はエラーになる
+func ToObject(v any) (object.Object, error) {
+	enc := &encoder{visiting: make(map[visitKey]bool)}
+	return enc.encode(reflect.ValueOf(v), "")
+}
+
+// visitKey は変換中のポインタ・マップ・スライス（循環参照の検出に使用）
+type visitKey struct {
+	ptr uintptr
+	typ reflect.Type
+}
+
+// encoder は ToObject の変換状態
+type encoder struct {
+	visiting map[visitKey]bool // 変換中の値（自分自身を含む値は変換できない）
+}
+
+func (enc *encoder) encode(v reflect.Value, path string) (object.Object, error) {
+	if !v.IsValid() {
+		return object.NULL, nil
+	}
+	if v.Type().Implements(objectType) {
+		if (v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface) && v.IsNil() {
+			return object.NULL, nil
+		}
+		return v.Interface().(object.Object), nil
+	}
+	if v.Type() == timeType {
+		return &object.Time{Value: v.Interface().(time.Time)}, nil
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		return nativeBool(v.Bool()), nil
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n := v.Int()
+		if n > maxExactInt || n < -maxExactInt {
+			return nil, pathError(path, "cannot convert %d to NUMBER exactly", n)
+		}
+		return &object.Number{Value: float64(n)}, nil
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		n := v.Uint()
+		if n > maxExactInt {
+			return nil, pathError(path, "cannot convert %d to NUMBER exactly", n)
+		}
+		return &object.Number{Value: float64(n)}, nil
+
+	case reflect.Float32, reflect.Float64:
+		return &object.Number{Value: v.Float()}, nil
+
+	case reflect.String:
+		return &object.String{Value: v.String()}, nil
+
+	case reflect.Interface:
+		if v.IsNil() {
+			return object.NULL, nil
+		}
+		return enc.encode(v.Elem(), path)
+
+	case reflect.Pointer:
+		if v.IsNil() {
+			return object.NULL, nil
+		}
+		return enc.visit(v, path, func() (object.Object, error) {
+			return enc.encode(v.Elem(), path)
+		})
+
+	case reflect.Slice:
+		if v.IsNil() {
+			return object.NULL, nil
+		}
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return &object.String{Value: string(v.Bytes())}, nil
+		}
+		return enc.visit(v, path, func() (object.Object, error) {
+			return enc.encodeArray(v, path)
+		})
+
+	case reflect.Array:
+		return enc.encodeArray(v, path)
+
+	case reflect.Map:
+		if v.IsNil() {
+			return object.NULL, nil
+		}
+		return enc.visit(v, path, func() (object.Object, error) {
+			return enc.encodeMap(v, path)
+		})
+
+	case reflect.Struct:
+		return enc.encodeStruct(v, path)
+
+	case reflect.Func:
+		if v.IsNil() {
+			return object.NULL, nil
+		}
+		return newBuiltin(v, path)
+	}
+
+	return nil, pathError(path, "cannot convert %s to a Sugu value", v.Type())
+}
+
+// visit は v を変換中として記録して f を実行する
+// 変換中の値に再び出会った場合は循環参照としてエラーにする
+func (enc *encoder) visit(v reflect.Value, path string, f func() (object.Object, error)) (object.Object, error) {
+	key := visitKey{ptr: v.Pointer(), typ: v.Type()}
+	if enc.visiting[key] {
+		return nil, pathError(path, "cannot convert cyclic %s", v.Type())
+	}
+	enc.visiting[key] = true
+	defer delete(enc.visiting, key)
+	return f()
+}
+
+func (enc *encoder) encodeArray(v reflect.Value, path string) (object.Object, error) {
+	elements := make([]object.Object, v.Len())
+	for i := range elements {
+		elem, err := enc.encode(v.Index(i), fmt.Sprintf("%s[%d]", path, i))
+		if err != nil {
+			return nil, err
+		}
+		elements[i] = elem
+	}
+	return &object.Array{Elements: elements}, nil
+}
+
+// encodeMap はマップを変換する（Go のマップは順序を持たないので、キーを並べ替えた順に追加する）
+func (enc *encoder) encodeMap(v reflect.Value, path string) (object.Object, error) {
+	if !isKeyKind(v.Type().Key().Kind()) {
+		return nil, pathError(path, "cannot convert %s to a Sugu value: map key must be string, number or bool", v.Type())
+	}
+
+	keys := v.MapKeys()
+	sort.Slice(keys, func(i, j int) bool { return lessKey(keys[i], keys[j]) })
+
+	m := &object.Map{}
+	for _, k := range keys {
+		key, err := enc.encode(k, path)
+		if err != nil {
+			return nil, err
+		}
+		value, err := enc.encode(v.MapIndex(k), fmt.Sprintf("%s[%s]", path, key.Inspect()))
+		if err != nil {
+			return nil, err
+		}
+		m.Set(key.(object.Hashable), value)
+	}
+	return m, nil
+}
+
+func (enc *encoder) encodeStruct(v reflect.Value, path string) (object.Object, error) {
+	fields := structFields(v.Type())
+	m := &object.Map{}
+	for _, f := range fields {
+		fv := v.FieldByIndex(f.index)
+		if f.omitEmpty && fv.IsZero() {
+			continue
+		}
+		value, err := enc.encode(fv, joinPath(path, f.name))
+		if err != nil {
+			return nil, err
+		}
+		m.Set(&object.String{Value: f.name}, value)
+	}
+	return m, nil
+}
+
+// newBuiltin は Go の関数を組み込み関数に変換する
+// 戻り値は「なし」「値」「error」「値と error」のいずれかでなければならない
+func newBuiltin(fn reflect.Value, path string) (object.Object, error) {
+	if f, ok := fn.Interface().(func(args ...object.Object) object.Object); ok {
+		return &object.Builtin{Fn: f}, nil
+	}
+	if f, ok := fn.Interface().(object.BuiltinFunction); ok {
+		return &object.Builtin{Fn: f}, nil
+	}
+
+	t := fn.Type()
+	switch {
+	case t.NumOut() <= 1:
+	case t.NumOut() == 2 && t.Out(1) == errorType:
+	default:
+		return nil, pathError(path, "cannot convert %s to a Sugu value: a function must return at most one value and an error", t)
+	}
+
+	return &object.Builtin{Fn: func(args ...object.Object) object.Object {
+		in, errObj := callArgs(t, args)
+		if errObj != nil {
+			return errObj
+		}
+		return callResult(t, fn.Call(in))
+	}}, nil
+}
+
+// callArgs は組み込み関数の引数を Go の関数の引数に変換する
+func callArgs(t reflect.Type, args []object.Object) ([]reflect.Value, *object.Error) {
+	n := t.NumIn()
+	if t.IsVariadic() {
+		if len(args) < n-1 {
+			return nil, newError(object.ARGUMENT_ERROR, "wrong number of arguments. got=%d, want at least %d", len(args), n-1)
+		}
+	} else if len(args) != n {
+		return nil, newError(object.ARGUMENT_ERROR, "wrong number of arguments. got=%d, want=%d", len(args), n)
+	}
+
+	dec := &decoder{visiting: make(map[object.Object]bool)}
+	in := make([]reflect.Value, len(args))
+	for i, arg := range args {
+		var param reflect.Type
+		if t.IsVariadic() && i >= n-1 {
+			param = t.In(n - 1).Elem()
+		} else {
+			param = t.In(i)
+		}
+		v := reflect.New(param).Elem()
+		if err := dec.decode(arg, v, ""); err != nil {
+			return nil, newError(object.TYPE_ERROR, "argument %d: %s", i+1, err)
+		}
+		in[i] = v
+	}
+	return in, nil
+}
+
+// callResult は Go の関数の戻り値を組み込み関数の戻り値に変換する
+// error が nil でなければ実行時エラーにする
+func callResult(t reflect.Type, out []reflect.Value) object.Object {
+	if len(out) > 0 && t.Out(len(out)-1) == errorType {
+		if err := out[len(out)-1]; !err.IsNil() {
+			return newError(object.GENERIC_ERROR, "%s", err.Interface().(error))
+		}
+		out = out[:len(out)-1]
+	}
+	if len(out) == 0 {
+		return object.NULL
+	}
+
+	result, err := ToObject(out[0].Interface())
+	if err != nil {
+		return newError(object.TYPE_ERROR, "invalid return value: %s", err)
+	}
+	return result
+}
+
+// field は Sugu のマップのキーになる構造体のフィールド
+type field struct {
+	name      string
+	index     []int
+	omitEmpty bool
+}
+
+// structFields は t のエクスポートされたフィールドを sugu タグに従って返す
+func structFields(t reflect.Type) []field {
+	var fields []field
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		tag := f.Tag.Get("sugu")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if name == "" {
+			name = f.Name
+		}
+		fields = append(fields, field{name: name, index: f.Index, omitEmpty: opts == "omitempty"})
+	}
+	return fields
+}
+
+// isKeyKind はマップのキーとして変換できる種類かを返す
+func isKeyKind(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// lessKey はマップのキーの順序（文字列は辞書順、数値は昇順、false が先）
+func lessKey(a, b reflect.Value) bool {
+	switch a.Kind() {
+	case reflect.String:
+		return a.String() < b.String()
+	case reflect.Bool:
+		return !a.Bool() && b.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return a.Int() < b.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return a.Uint() < b.Uint()
+	default:
+		return a.Float() < b.Float() || (math.IsNaN(a.Float()) && !math.IsNaN(b.Float()))
+	}
+}
+
+func nativeBool(b bool) *object.Boolean {
+	if b {
+		return object.TRUE
+	}
+	return object.FALSE
+}
+
+func newError(kind, format string, a ...any) *object.Error {
+	return &object.Error{Message: fmt.Sprintf(format, a...), Kind: kind}
+}
+
+// pathError は変換に失敗した値の位置（"items[2].name" など）を付けたエラーを作成する
+func pathError(path, format string, a ...any) error {
+	msg := fmt.Sprintf(format, a...)
+	if path == "" {
+		return fmt.Errorf("%s", msg)
+	}
+	return fmt.Errorf("%s: %s", path, msg)
+}
+
+// joinPath は構造体のフィールドやマップのキーの位置をつなげる
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
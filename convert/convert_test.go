@@ -0,0 +1,444 @@
+package convert
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sugu/object"
+	"testing"
+	"time"
+)
+
+type address struct {
+	City string `sugu:"city"`
+	Zip  string `sugu:"zip,omitempty"`
+}
+
+type person struct {
+	Name     string           `sugu:"name"`
+	Age      int              `sugu:"age"`
+	Tags     []string         `sugu:"tags"`
+	Address  *address         `sugu:"address"`
+	Scores   map[string]uint8 `sugu:"scores"`
+	Born     time.Time        `sugu:"born"`
+	Password string           `sugu:"-"`
+	Extra    map[int]bool     `sugu:"extra,omitempty"`
+	Note     string           // タグがなければフィールド名
+	private  string
+	Raw      object.Object     `sugu:"raw"`
+	Labels   map[bool][]string `sugu:"labels,omitempty"`
+}
+
+func TestToObject(t *testing.T) {
+	born := time.Date(2000, 1, 2, 3, 4, 5, 0, time.UTC)
+	var nilPtr *address
+	var nilSlice []int
+
+	tests := []struct {
+		input    any
+		expected string
+	}{
+		{nil, "null"},
+		{true, "true"},
+		{42, "42"},
+		{int64(-7), "-7"},
+		{uint16(7), "7"},
+		{1.5, "1.5"},
+		{float32(0.25), "0.25"},
+		{"hello", "hello"},
+		{[]byte("bytes"), "bytes"},
+		{[]int{1, 2, 3}, "[1, 2, 3]"},
+		{[2]string{"a", "b"}, "[a, b]"},
+		{nilSlice, "null"},
+		{nilPtr, "null"},
+		{&address{City: "Tokyo"}, "{city: Tokyo}"},
+		{map[string]int{"b": 2}, "{b: 2}"},
+		{map[int]string{1: "one"}, "{1: one}"},
+		{map[bool]int{true: 1}, "{true: 1}"},
+		{born, "2000-01-02T03:04:05Z"},
+		{&object.String{Value: "as is"}, "as is"},
+		{[]any{1, "a", nil, []any{true}}, "[1, a, null, [true]]"},
+	}
+
+	for _, tt := range tests {
+		obj, err := ToObject(tt.input)
+		if err != nil {
+			t.Errorf("ToObject(%#v) failed: %s", tt.input, err)
+			continue
+		}
+		if obj.Inspect() != tt.expected {
+			t.Errorf("wrong result for %#v. expected=%q, got=%q", tt.input, tt.expected, obj.Inspect())
+		}
+	}
+
+	// time.Time は文字列ではなく日時になる
+	if obj, _ := ToObject(born); obj.Type() != object.TIME_OBJ {
+		t.Errorf("time.Time should be converted to TIME. got=%s", obj.Type())
+	}
+}
+
+func TestToObjectSingletons(t *testing.T) {
+	// null と真偽値は評価器が同一性で比較するため、シングルトンを返す
+	for _, tt := range []struct {
+		input    any
+		expected object.Object
+	}{
+		{nil, object.NULL},
+		{true, object.TRUE},
+		{false, object.FALSE},
+		{(*int)(nil), object.NULL},
+	} {
+		obj, err := ToObject(tt.input)
+		if err != nil || obj != tt.expected {
+			t.Errorf("ToObject(%#v) should return the singleton %s. got=%v (%v)", tt.input, tt.expected.Inspect(), obj, err)
+		}
+	}
+}
+
+func TestToObjectStructFields(t *testing.T) {
+	born := time.Date(2000, 1, 2, 3, 4, 5, 0, time.UTC)
+	obj, err := ToObject(person{
+		Name:     "Bob",
+		Address:  &address{City: "Osaka"},
+		Born:     born,
+		Password: "secret",
+		Extra:    map[int]bool{2: true},
+		Note:     "note",
+		private:  "private",
+	})
+	if err != nil {
+		t.Fatalf("ToObject failed: %s", err)
+	}
+	m := obj.(*object.Map)
+
+	tests := []struct {
+		key      string
+		expected string // 空文字列はキーがないことを表す
+	}{
+		{"name", "Bob"},
+		{"age", "0"},
+		{"tags", "null"},
+		{"address", "{city: Osaka}"}, // zip は omitempty
+		{"scores", "null"},
+		{"born", "2000-01-02T03:04:05Z"},
+		{"extra", "{2: true}"},
+		{"Note", "note"},
+		{"raw", "null"},
+		{"Password", ""},
+		{"private", ""},
+		{"labels", ""},
+	}
+	for _, tt := range tests {
+		pair, ok := m.Get(&object.String{Value: tt.key})
+		if tt.expected == "" {
+			if ok {
+				t.Errorf("key %q should be omitted. got=%s", tt.key, pair.Value.Inspect())
+			}
+			continue
+		}
+		if !ok {
+			t.Errorf("key %q not found", tt.key)
+			continue
+		}
+		if pair.Value.Inspect() != tt.expected {
+			t.Errorf("wrong value for %q. expected=%q, got=%q", tt.key, tt.expected, pair.Value.Inspect())
+		}
+	}
+	if m.Len() != 9 {
+		t.Errorf("wrong number of keys. expected=9, got=%d", m.Len())
+	}
+}
+
+func TestToObjectErrors(t *testing.T) {
+	type cyclic struct {
+		Next *cyclic `sugu:"next"`
+	}
+	loop := &cyclic{}
+	loop.Next = loop
+	self := []any{nil}
+	self[0] = self
+
+	tests := []struct {
+		input    any
+		expected string
+	}{
+		{make(chan int), "cannot convert chan int to a Sugu value"},
+		{complex(1, 2), "cannot convert complex128 to a Sugu value"},
+		{map[string]any{"handler": make(chan int)}, "[handler]: cannot convert chan int to a Sugu value"},
+		{struct {
+			Items []any `sugu:"items"`
+		}{Items: []any{1, func() {}, make(chan int)}}, "items[2]: cannot convert chan int to a Sugu value"},
+		{map[[2]int]string{}, "cannot convert map[[2]int]string to a Sugu value: map key must be string, number or bool"},
+		{int64(1) << 60, "cannot convert 1152921504606846976 to NUMBER exactly"},
+		{loop, "next: cannot convert cyclic *convert.cyclic"},
+		{self, "[0]: cannot convert cyclic []interface {}"},
+		{func() (int, int) { return 0, 0 }, "cannot convert func() (int, int) to a Sugu value: a function must return at most one value and an error"},
+	}
+
+	for _, tt := range tests {
+		_, err := ToObject(tt.input)
+		if err == nil {
+			t.Errorf("expected error for %T", tt.input)
+			continue
+		}
+		if err.Error() != tt.expected {
+			t.Errorf("wrong error for %T. expected=%q, got=%q", tt.input, tt.expected, err.Error())
+		}
+	}
+}
+
+func TestFromObject(t *testing.T) {
+	born := time.Date(2000, 1, 2, 3, 4, 5, 0, time.UTC)
+	input := person{
+		Name:    "Alice",
+		Age:     30,
+		Tags:    []string{"a", "b"},
+		Address: &address{City: "Tokyo", Zip: "100"},
+		Scores:  map[string]uint8{"math": 90},
+		Born:    born,
+		Extra:   map[int]bool{1: true, 2: false},
+		Note:    "note",
+		Raw:     &object.Array{Elements: []object.Object{object.TRUE}},
+		Labels:  map[bool][]string{true: {"yes"}},
+	}
+
+	obj, err := ToObject(input)
+	if err != nil {
+		t.Fatalf("ToObject failed: %s", err)
+	}
+
+	var got person
+	if err := FromObject(obj, &got); err != nil {
+		t.Fatalf("FromObject failed: %s", err)
+	}
+	if !reflect.DeepEqual(got, input) {
+		t.Errorf("round trip mismatch.\nexpected=%+v\ngot=     %+v", input, got)
+	}
+}
+
+func TestFromObjectValues(t *testing.T) {
+	arr := &object.Array{Elements: []object.Object{&object.Number{Value: 1}, &object.String{Value: "a"}, object.NULL}}
+	m := newMap(
+		&object.String{Value: "a"}, &object.Number{Value: 1},
+		&object.Number{Value: 2}, object.TRUE,
+		object.FALSE, arr,
+	)
+
+	var n int8
+	var u uint
+	var f float32
+	var b []byte
+	var p *int
+	var a any
+	var obj object.Object
+	var str *object.String
+	var fixed [3]any
+	var at time.Time
+
+	tests := []struct {
+		input    object.Object
+		target   any
+		expected any
+	}{
+		{&object.Number{Value: -128}, &n, int8(-128)},
+		{&object.Number{Value: 7}, &u, uint(7)},
+		{&object.Number{Value: 0.5}, &f, float32(0.5)},
+		{&object.String{Value: "bytes"}, &b, []byte("bytes")},
+		{&object.Number{Value: 3}, &p, func() *int { v := 3; return &v }()},
+		{object.NULL, &p, (*int)(nil)},
+		{arr, &a, []any{1.0, "a", nil}},
+		{m, &a, map[string]any{"a": 1.0, "2": true, "false": []any{1.0, "a", nil}}},
+		{arr, &obj, object.Object(arr)},
+		{&object.String{Value: "s"}, &str, &object.String{Value: "s"}},
+		{arr, &fixed, [3]any{1.0, "a", nil}},
+		{&object.String{Value: "2000-01-02T03:04:05.5+09:00"}, &at, time.Date(2000, 1, 2, 3, 4, 5, 5e8, time.FixedZone("", 9*60*60))},
+		{&object.Time{Value: time.Date(2000, 1, 2, 0, 0, 0, 0, time.UTC)}, &at, time.Date(2000, 1, 2, 0, 0, 0, 0, time.UTC)},
+		{&object.Time{Value: time.Date(2000, 1, 2, 0, 0, 0, 0, time.UTC)}, &a, time.Date(2000, 1, 2, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		if err := FromObject(tt.input, tt.target); err != nil {
+			t.Errorf("FromObject(%s, %T) failed: %s", tt.input.Inspect(), tt.target, err)
+			continue
+		}
+		got := reflect.ValueOf(tt.target).Elem().Interface()
+		if tm, ok := got.(time.Time); ok {
+			if !tm.Equal(tt.expected.(time.Time)) {
+				t.Errorf("wrong time. expected=%s, got=%s", tt.expected, tm)
+			}
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("wrong result for %s into %T. expected=%#v, got=%#v", tt.input.Inspect(), tt.target, tt.expected, got)
+		}
+	}
+}
+
+func TestFromObjectErrors(t *testing.T) {
+	var n int
+	var u8 uint8
+	var s string
+	var pair [2]int
+	var a any
+	var ch chan int
+	var keyed map[[2]int]int
+	var p person
+
+	tests := []struct {
+		input    object.Object
+		target   any
+		expected string
+	}{
+		{&object.Number{Value: 1}, n, "target must be a non-nil pointer, got int"},
+		{&object.Number{Value: 1}, (*int)(nil), "target must be a non-nil pointer, got *int"},
+		{&object.String{Value: "1"}, &n, "cannot convert STRING to int"},
+		{&object.Number{Value: 1.5}, &n, "cannot convert 1.5 to int: not an integer"},
+		{&object.Number{Value: 256}, &u8, "256 overflows uint8"},
+		{&object.Number{Value: -1}, &u8, "-1 overflows uint8"},
+		{&object.Number{Value: 1}, &s, "cannot convert NUMBER to string"},
+		{&object.Array{Elements: []object.Object{&object.Number{Value: 1}}}, &pair, "cannot convert ARRAY of length 1 to [2]int"},
+		{&object.Builtin{}, &a, "cannot convert BUILTIN to a Go value"},
+		{newMap(&object.String{Value: "1"}, object.NULL, &object.Number{Value: 1}, object.NULL), &a, `duplicate key "1" after converting keys to strings`},
+		{&object.Number{Value: 1}, &ch, "cannot convert NUMBER to chan int"},
+		{newMap(), &keyed, "cannot convert MAP to map[[2]int]int: map key must be string, number or bool"},
+		{newMap(&object.String{Value: "tags"}, &object.Array{Elements: []object.Object{&object.String{Value: "a"}, &object.Number{Value: 2}}}), &p, "tags[1]: cannot convert NUMBER to string"},
+		{newMap(&object.String{Value: "address"}, newMap(&object.String{Value: "city"}, object.TRUE)), &p, "address.city: cannot convert BOOLEAN to string"},
+		{newMap(&object.String{Value: "born"}, &object.String{Value: "yesterday"}), &p, `born: cannot convert "yesterday" to time.Time: expected RFC 3339 format`},
+	}
+
+	for _, tt := range tests {
+		err := FromObject(tt.input, tt.target)
+		if err == nil {
+			t.Errorf("expected error for %s into %T", tt.input.Inspect(), tt.target)
+			continue
+		}
+		if err.Error() != tt.expected {
+			t.Errorf("wrong error for %s into %T. expected=%q, got=%q", tt.input.Inspect(), tt.target, tt.expected, err.Error())
+		}
+	}
+}
+
+func TestFromObjectCyclic(t *testing.T) {
+	self := newMap(&object.String{Value: "a"}, &object.Number{Value: 1})
+	self.Set(&object.String{Value: "self"}, self)
+	arr := &object.Array{}
+	arr.Elements = []object.Object{object.NULL, newMap(&object.String{Value: "back"}, arr)}
+	// 同じ値を 2 回含むだけなら循環ではない
+	shared := &object.Array{Elements: []object.Object{&object.Number{Value: 1}}}
+	twice := &object.Array{Elements: []object.Object{shared, shared}}
+
+	var a any
+	var m map[string]any
+	var nested [][]int
+	type node struct {
+		Self *node `sugu:"self"`
+	}
+	var n node
+
+	tests := []struct {
+		name     string
+		input    object.Object
+		target   any
+		expected string
+	}{
+		{"map into any", self, &a, "[self]: cannot convert cyclic MAP"},
+		{"array into any", arr, &a, "[1][back]: cannot convert cyclic ARRAY"},
+		{"map into map", self, &m, "[self]: cannot convert cyclic MAP"},
+		{"map into struct", self, &n, "self: cannot convert cyclic MAP"},
+		{"shared array", twice, &nested, ""},
+	}
+
+	for _, tt := range tests {
+		err := FromObject(tt.input, tt.target)
+		got := ""
+		if err != nil {
+			got = err.Error()
+		}
+		if got != tt.expected {
+			t.Errorf("%s: wrong error. expected=%q, got=%q", tt.name, tt.expected, got)
+		}
+	}
+}
+
+func TestFunctionToBuiltin(t *testing.T) {
+	tests := []struct {
+		fn       any
+		args     []object.Object
+		expected string
+	}{
+		{func(a, b int) int { return a + b }, numbers(1, 2), "3"},
+		{func(names ...string) int { return len(names) }, []object.Object{&object.String{Value: "a"}, &object.String{Value: "b"}}, "2"},
+		{func(prefix string, n ...float64) string { return fmt.Sprint(prefix, n) }, []object.Object{&object.String{Value: "p"}}, "p[]"},
+		{func() {}, nil, "null"},
+		{func(a address) string { return a.City }, []object.Object{newMap(&object.String{Value: "city"}, &object.String{Value: "Kyoto"})}, "Kyoto"},
+		{func(n int) (int, error) { return n * 2, nil }, numbers(21), "42"},
+		{func(args ...object.Object) object.Object { return &object.Number{Value: float64(len(args))} }, numbers(1, 2, 3), "3"},
+		{object.BuiltinFunction(func(args ...object.Object) object.Object { return object.TRUE }), nil, "true"},
+		{func(v any) any { return v }, []object.Object{&object.Array{Elements: numbers(1)}}, "[1]"},
+	}
+
+	for _, tt := range tests {
+		obj, err := ToObject(tt.fn)
+		if err != nil {
+			t.Errorf("ToObject(%T) failed: %s", tt.fn, err)
+			continue
+		}
+		builtin, ok := obj.(*object.Builtin)
+		if !ok {
+			t.Errorf("ToObject(%T) should return a builtin. got=%T", tt.fn, obj)
+			continue
+		}
+		if result := builtin.Fn(tt.args...); result.Inspect() != tt.expected {
+			t.Errorf("wrong result of %T. expected=%q, got=%q", tt.fn, tt.expected, result.Inspect())
+		}
+	}
+}
+
+func TestFunctionToBuiltinErrors(t *testing.T) {
+	tests := []struct {
+		fn       any
+		args     []object.Object
+		kind     string
+		expected string
+	}{
+		{func(a, b int) int { return a + b }, numbers(1), object.ARGUMENT_ERROR, "wrong number of arguments. got=1, want=2"},
+		{func(a string, b ...int) {}, nil, object.ARGUMENT_ERROR, "wrong number of arguments. got=0, want at least 1"},
+		{func(a int) int { return a }, []object.Object{&object.String{Value: "x"}}, object.TYPE_ERROR, "argument 1: cannot convert STRING to int"},
+		{func(n int) (int, error) { return 0, errors.New("negative") }, numbers(-1), object.GENERIC_ERROR, "negative"},
+		{func() error { return errors.New("failed") }, nil, object.GENERIC_ERROR, "failed"},
+		{func() chan int { return nil }, nil, object.TYPE_ERROR, "invalid return value: cannot convert chan int to a Sugu value"},
+	}
+
+	for _, tt := range tests {
+		obj, err := ToObject(tt.fn)
+		if err != nil {
+			t.Errorf("ToObject(%T) failed: %s", tt.fn, err)
+			continue
+		}
+		result := obj.(*object.Builtin).Fn(tt.args...)
+		errObj, ok := result.(*object.Error)
+		if !ok {
+			t.Errorf("expected error from %T. got=%s", tt.fn, result.Inspect())
+			continue
+		}
+		if errObj.Kind != tt.kind || errObj.Message != tt.expected {
+			t.Errorf("wrong error from %T. expected=%s %q, got=%s %q", tt.fn, tt.kind, tt.expected, errObj.Kind, errObj.Message)
+		}
+	}
+}
+
+func numbers(values ...float64) []object.Object {
+	result := make([]object.Object, len(values))
+	for i, v := range values {
+		result[i] = &object.Number{Value: v}
+	}
+	return result
+}
+
+func newMap(kv ...object.Object) *object.Map {
+	m := &object.Map{}
+	for i := 0; i < len(kv); i += 2 {
+		m.Set(kv[i].(object.Hashable), kv[i+1])
+	}
+	return m
+}
@@ -0,0 +1,311 @@
+package convert
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sugu/object"
+	"time"
+)
+
+// FromObject は Sugu の値を target の指す Go の値に変換する
+// target は nil でないポインタでなければならない。自分自身を含む配列・マップはエラーになる
+//
+//   - null は Go のゼロ値
+//   - 数値は整数型（小数部がなく範囲内の場合）と浮動小数点数型
+//   - マップは構造体（sugu タグの名前、またはフィールド名のキー）と Go のマップ
+//   - 日時と RFC 3339 形式の文字列は time.Time
+//   - object.Object に代入できる型にはそのまま代入する
+//
+// any には null・真偽値・float64・string・time.Time・[]any・map[string]any を入れる
+// このとき数値と真偽値のキーは文字列にする（"1"、"true"）
+func FromObject(obj object.Object, target any) error {
+	v := reflect.ValueOf(target)
+	if v.Kind() != reflect.Pointer || v.IsNil() {
+		return fmt.Errorf("target must be a non-nil pointer, got %T", target)
+	}
+	dec := &decoder{visiting: make(map[object.Object]bool)}
+	return dec.decode(obj, v.Elem(), "")
+}
+
+// decoder は FromObject の変換状態
+type decoder struct {
+	visiting map[object.Object]bool // 変換中の配列とマップ（循環参照の検出に使用）
+}
+
+// visit は obj を変換中として記録して f を実行する
+func (dec *decoder) visit(obj object.Object, path string, f func() error) error {
+	if dec.visiting[obj] {
+		return pathError(path, "cannot convert cyclic %s", obj.Type())
+	}
+	dec.visiting[obj] = true
+	defer delete(dec.visiting, obj)
+	return f()
+}
+
+// decode は obj を v に代入する
+func (dec *decoder) decode(obj object.Object, v reflect.Value, path string) error {
+	if obj == nil {
+		obj = object.NULL
+	}
+	if v.Kind() == reflect.Interface && v.NumMethod() == 0 {
+		value, err := dec.toAny(obj, path)
+		if err != nil {
+			return err
+		}
+		if value == nil {
+			v.SetZero()
+		} else {
+			v.Set(reflect.ValueOf(value))
+		}
+		return nil
+	}
+	if reflect.TypeOf(obj).AssignableTo(v.Type()) {
+		v.Set(reflect.ValueOf(obj))
+		return nil
+	}
+	if obj.Type() == object.NULL_OBJ {
+		v.SetZero()
+		return nil
+	}
+	if v.Type() == timeType {
+		return decodeTime(obj, v, path)
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		b, ok := obj.(*object.Boolean)
+		if !ok {
+			return mismatch(obj, v, path)
+		}
+		v.SetBool(b.Value)
+		return nil
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := integer(obj, v, path)
+		if err != nil {
+			return err
+		}
+		if n < math.MinInt64 || n >= math.MaxInt64 || v.OverflowInt(int64(n)) {
+			return pathError(path, "%s overflows %s", obj.Inspect(), v.Type())
+		}
+		v.SetInt(int64(n))
+		return nil
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		n, err := integer(obj, v, path)
+		if err != nil {
+			return err
+		}
+		if n < 0 || n >= math.MaxUint64 || v.OverflowUint(uint64(n)) {
+			return pathError(path, "%s overflows %s", obj.Inspect(), v.Type())
+		}
+		v.SetUint(uint64(n))
+		return nil
+
+	case reflect.Float32, reflect.Float64:
+		n, ok := obj.(*object.Number)
+		if !ok {
+			return mismatch(obj, v, path)
+		}
+		v.SetFloat(n.Value)
+		return nil
+
+	case reflect.String:
+		s, ok := obj.(*object.String)
+		if !ok {
+			return mismatch(obj, v, path)
+		}
+		v.SetString(s.Value)
+		return nil
+
+	case reflect.Pointer:
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return dec.decode(obj, v.Elem(), path)
+
+	case reflect.Slice:
+		if s, ok := obj.(*object.String); ok && v.Type().Elem().Kind() == reflect.Uint8 {
+			v.SetBytes([]byte(s.Value))
+			return nil
+		}
+		arr, ok := obj.(*object.Array)
+		if !ok {
+			return mismatch(obj, v, path)
+		}
+		slice := reflect.MakeSlice(v.Type(), len(arr.Elements), len(arr.Elements))
+		err := dec.visit(arr, path, func() error {
+			for i, elem := range arr.Elements {
+				if err := dec.decode(elem, slice.Index(i), fmt.Sprintf("%s[%d]", path, i)); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		v.Set(slice)
+		return nil
+
+	case reflect.Array:
+		arr, ok := obj.(*object.Array)
+		if !ok {
+			return mismatch(obj, v, path)
+		}
+		if len(arr.Elements) != v.Len() {
+			return pathError(path, "cannot convert ARRAY of length %d to %s", len(arr.Elements), v.Type())
+		}
+		return dec.visit(arr, path, func() error {
+			for i, elem := range arr.Elements {
+				if err := dec.decode(elem, v.Index(i), fmt.Sprintf("%s[%d]", path, i)); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+
+	case reflect.Map:
+		m, ok := obj.(*object.Map)
+		if !ok {
+			return mismatch(obj, v, path)
+		}
+		if !isKeyKind(v.Type().Key().Kind()) {
+			return pathError(path, "cannot convert MAP to %s: map key must be string, number or bool", v.Type())
+		}
+		result := reflect.MakeMapWithSize(v.Type(), m.Len())
+		err := dec.visit(m, path, func() error {
+			for _, pair := range m.Pairs() {
+				key := reflect.New(v.Type().Key()).Elem()
+				if err := dec.decode(pair.Key, key, path); err != nil {
+					return err
+				}
+				value := reflect.New(v.Type().Elem()).Elem()
+				if err := dec.decode(pair.Value, value, fmt.Sprintf("%s[%s]", path, pair.Key.Inspect())); err != nil {
+					return err
+				}
+				result.SetMapIndex(key, value)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		v.Set(result)
+		return nil
+
+	case reflect.Struct:
+		m, ok := obj.(*object.Map)
+		if !ok {
+			return mismatch(obj, v, path)
+		}
+		return dec.visit(m, path, func() error {
+			for _, f := range structFields(v.Type()) {
+				pair, ok := m.Get(&object.String{Value: f.name})
+				if !ok {
+					continue
+				}
+				if err := dec.decode(pair.Value, v.FieldByIndex(f.index), joinPath(path, f.name)); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	}
+
+	return mismatch(obj, v, path)
+}
+
+// toAny は obj を any に入れる Go の値に変換する
+func (dec *decoder) toAny(obj object.Object, path string) (any, error) {
+	switch obj := obj.(type) {
+	case *object.Null:
+		return nil, nil
+	case *object.Boolean:
+		return obj.Value, nil
+	case *object.Number:
+		return obj.Value, nil
+	case *object.String:
+		return obj.Value, nil
+	case *object.Time:
+		return obj.Value, nil
+	case *object.Array:
+		result := make([]any, len(obj.Elements))
+		err := dec.visit(obj, path, func() error {
+			for i, elem := range obj.Elements {
+				value, err := dec.toAny(elem, fmt.Sprintf("%s[%d]", path, i))
+				if err != nil {
+					return err
+				}
+				result[i] = value
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		return result, nil
+	case *object.Map:
+		result := make(map[string]any, obj.Len())
+		err := dec.visit(obj, path, func() error {
+			for _, pair := range obj.Pairs() {
+				var key string
+				switch k := pair.Key.(type) {
+				case *object.String:
+					key = k.Value
+				default:
+					key = k.Inspect()
+				}
+				if _, ok := result[key]; ok {
+					return pathError(path, "duplicate key %q after converting keys to strings", key)
+				}
+				value, err := dec.toAny(pair.Value, fmt.Sprintf("%s[%s]", path, pair.Key.Inspect()))
+				if err != nil {
+					return err
+				}
+				result[key] = value
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		return result, nil
+	}
+	return nil, pathError(path, "cannot convert %s to a Go value", obj.Type())
+}
+
+// integer は obj を整数として取り出す（小数部がある場合はエラー）
+func integer(obj object.Object, v reflect.Value, path string) (float64, error) {
+	n, ok := obj.(*object.Number)
+	if !ok {
+		return 0, mismatch(obj, v, path)
+	}
+	if n.Value != math.Trunc(n.Value) {
+		return 0, pathError(path, "cannot convert %s to %s: not an integer", n.Inspect(), v.Type())
+	}
+	return n.Value, nil
+}
+
+func decodeTime(obj object.Object, v reflect.Value, path string) error {
+	if t, ok := obj.(*object.Time); ok {
+		v.Set(reflect.ValueOf(t.Value))
+		return nil
+	}
+	s, ok := obj.(*object.String)
+	if !ok {
+		return mismatch(obj, v, path)
+	}
+	t, err := time.Parse(time.RFC3339Nano, s.Value)
+	if err != nil {
+		return pathError(path, "cannot convert %q to time.Time: expected RFC 3339 format", s.Value)
+	}
+	v.Set(reflect.ValueOf(t))
+	return nil
+}
+
+// mismatch は型が合わない場合のエラーを作成する
+func mismatch(obj object.Object, v reflect.Value, path string) error {
+	return pathError(path, "cannot convert %s to %s", obj.Type(), v.Type())
+}
@@ -0,0 +1,228 @@
+package convert
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"sugu/object"
+)
+
+// FromJSON は JSON を Sugu の値に変換する
+// オブジェクトはマップ、配列は配列、数値は数値になる
+// 不正な JSON は "invalid JSON at offset N: ..." のエラーになる（N は問題のある文字の先頭からのバイト位置）
+func FromJSON(data []byte) (object.Object, error) {
+	// 構文は先にまとめて検査する（Decoder.Token より正確な位置が分かる）
+	// 入れ子の深さも encoding/json の上限（10000）で制限されるので、decodeJSON の再帰は深くならない
+	var raw json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		var syntaxErr *json.SyntaxError
+		if !errors.As(err, &syntaxErr) {
+			return nil, err
+		}
+		offset := syntaxErr.Offset
+		if !strings.HasPrefix(syntaxErr.Error(), "unexpected end") {
+			offset-- // Offset は問題のある文字を読んだ後の位置
+		}
+		return nil, fmt.Errorf("invalid JSON at offset %d: %s", offset, syntaxErr)
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	obj, err := decodeJSON(dec)
+	if err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+func decodeJSON(dec *json.Decoder) (object.Object, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) {
+			// 構文は検査済みなので、ここでのエラーは float64 で表せない数値だけ
+			number := strings.TrimPrefix(typeErr.Value, "number ")
+			return nil, fmt.Errorf("invalid JSON at offset %d: number %s is out of range", typeErr.Offset-int64(len(number)), number)
+		}
+		return nil, err
+	}
+
+	switch tok := tok.(type) {
+	case nil:
+		return object.NULL, nil
+	case bool:
+		return nativeBool(tok), nil
+	case float64:
+		return &object.Number{Value: tok}, nil
+	case string:
+		return &object.String{Value: tok}, nil
+	}
+
+	if tok == json.Delim('[') {
+		elements := []object.Object{}
+		for dec.More() {
+			elem, err := decodeJSON(dec)
+			if err != nil {
+				return nil, err
+			}
+			elements = append(elements, elem)
+		}
+		if _, err := dec.Token(); err != nil {
+			return nil, err
+		}
+		return &object.Array{Elements: elements}, nil
+	}
+
+	m := &object.Map{}
+	for dec.More() {
+		key, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		value, err := decodeJSON(dec)
+		if err != nil {
+			return nil, err
+		}
+		m.Set(&object.String{Value: key.(string)}, value)
+	}
+	if _, err := dec.Token(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// ToJSON は Sugu の値を JSON に変換する
+// indent が空でなければ、1 段ごとに indent で字下げして複数行に整形する
+// 数値は Inspect と同じ形式、日時は RFC 3339 形式の文字列で出力する。マップは挿入順に出力し、キーは文字列にする（数値と真偽値のキーは "1"、"true"）
+// 関数や NaN など JSON で表せない値と、自分自身を含む配列・マップはエラーになる
+func ToJSON(obj object.Object, indent string) ([]byte, error) {
+	enc := &jsonEncoder{indent: indent, visiting: make(map[object.Object]bool)}
+	if err := enc.encode(obj, "", 0); err != nil {
+		return nil, err
+	}
+	return enc.buf.Bytes(), nil
+}
+
+// jsonEncoder は ToJSON の変換状態
+type jsonEncoder struct {
+	buf      bytes.Buffer
+	indent   string
+	visiting map[object.Object]bool // 変換中の配列とマップ（循環参照の検出に使用）
+}
+
+func (enc *jsonEncoder) encode(obj object.Object, path string, depth int) error {
+	switch obj := obj.(type) {
+	case *object.Null:
+		enc.buf.WriteString("null")
+	case *object.Boolean:
+		enc.buf.WriteString(obj.Inspect())
+	case *object.Number:
+		if math.IsNaN(obj.Value) || math.IsInf(obj.Value, 0) {
+			return pathError(path, "cannot convert %s to JSON", obj.Inspect())
+		}
+		enc.buf.WriteString(obj.Inspect())
+	case *object.String:
+		writeJSONString(&enc.buf, obj.Value)
+	case *object.Time:
+		writeJSONString(&enc.buf, obj.Inspect())
+	case *object.Array:
+		return enc.visit(obj, path, func() error {
+			return enc.encodeArray(obj, path, depth)
+		})
+	case *object.Map:
+		return enc.visit(obj, path, func() error {
+			return enc.encodeMap(obj, path, depth)
+		})
+	default:
+		return pathError(path, "cannot convert %s to JSON", obj.Type())
+	}
+	return nil
+}
+
+// visit は obj を変換中として記録して f を実行する
+func (enc *jsonEncoder) visit(obj object.Object, path string, f func() error) error {
+	if enc.visiting[obj] {
+		return pathError(path, "cannot convert cyclic %s to JSON", obj.Type())
+	}
+	enc.visiting[obj] = true
+	defer delete(enc.visiting, obj)
+	return f()
+}
+
+func (enc *jsonEncoder) encodeArray(arr *object.Array, path string, depth int) error {
+	if len(arr.Elements) == 0 {
+		enc.buf.WriteString("[]")
+		return nil
+	}
+	enc.buf.WriteByte('[')
+	for i, elem := range arr.Elements {
+		if i > 0 {
+			enc.buf.WriteByte(',')
+		}
+		enc.newline(depth + 1)
+		if err := enc.encode(elem, fmt.Sprintf("%s[%d]", path, i), depth+1); err != nil {
+			return err
+		}
+	}
+	enc.newline(depth)
+	enc.buf.WriteByte(']')
+	return nil
+}
+
+// encodeMap はマップを挿入順に出力する
+func (enc *jsonEncoder) encodeMap(m *object.Map, path string, depth int) error {
+	if m.Len() == 0 {
+		enc.buf.WriteString("{}")
+		return nil
+	}
+
+	seen := make(map[string]bool, m.Len())
+	enc.buf.WriteByte('{')
+	for i, pair := range m.Pairs() {
+		key := pair.Key.Inspect()
+		if s, ok := pair.Key.(*object.String); ok {
+			key = s.Value
+		}
+		if seen[key] {
+			return pathError(path, "duplicate key %q after converting keys to strings", key)
+		}
+		seen[key] = true
+
+		if i > 0 {
+			enc.buf.WriteByte(',')
+		}
+		enc.newline(depth + 1)
+		writeJSONString(&enc.buf, key)
+		enc.buf.WriteByte(':')
+		if enc.indent != "" {
+			enc.buf.WriteByte(' ')
+		}
+		if err := enc.encode(pair.Value, fmt.Sprintf("%s[%s]", path, pair.Key.Inspect()), depth+1); err != nil {
+			return err
+		}
+	}
+	enc.newline(depth)
+	enc.buf.WriteByte('}')
+	return nil
+}
+
+// newline は整形する場合に改行して depth 段の字下げを出力する
+func (enc *jsonEncoder) newline(depth int) {
+	if enc.indent == "" {
+		return
+	}
+	enc.buf.WriteByte('\n')
+	for i := 0; i < depth; i++ {
+		enc.buf.WriteString(enc.indent)
+	}
+}
+
+// writeJSONString は s を JSON の文字列として出力する（HTML の文字はエスケープしない）
+func writeJSONString(buf *bytes.Buffer, s string) {
+	e := json.NewEncoder(buf)
+	e.SetEscapeHTML(false)
+	e.Encode(s)
+	buf.Truncate(buf.Len() - 1) // Encode が付ける改行を取り除く
+}
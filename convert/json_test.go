@@ -0,0 +1,133 @@
+package convert
+
+import (
+	"math"
+	"strings"
+	"sugu/object"
+	"testing"
+	"time"
+)
+
+func TestFromJSON(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`null`, "null"},
+		{`true`, "true"},
+		{`-0.5`, "-0.5"},
+		{`1e3`, "1000"},
+		{`"あ\t"`, "あ\t"},
+		{` [1, [2, [3]]] `, "[1, [2, [3]]]"},
+		{`{"a": {"b": []}}`, "{a: {b: []}}"},
+		// 重複したキーは後の値になる
+		{`{"a": 1, "a": 2}`, "{a: 2}"},
+	}
+
+	for _, tt := range tests {
+		obj, err := FromJSON([]byte(tt.input))
+		if err != nil {
+			t.Errorf("FromJSON(%q) failed: %s", tt.input, err)
+			continue
+		}
+		if obj.Inspect() != tt.expected {
+			t.Errorf("wrong result for %q. expected=%q, got=%q", tt.input, tt.expected, obj.Inspect())
+		}
+	}
+
+	// null と真偽値はシングルトン
+	for input, expected := range map[string]object.Object{"null": object.NULL, "true": object.TRUE, "false": object.FALSE} {
+		if obj, _ := FromJSON([]byte(input)); obj != expected {
+			t.Errorf("FromJSON(%q) should return the singleton. got=%p", input, obj)
+		}
+	}
+}
+
+func TestFromJSONErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{``, "invalid JSON at offset 0: unexpected end of JSON input"},
+		{`{"a": 1`, "invalid JSON at offset 7: unexpected end of JSON input"},
+		{`[1, 2,]`, "invalid JSON at offset 6: invalid character ']' looking for beginning of value"},
+		{`{"a" 1}`, "invalid JSON at offset 5: invalid character '1' after object key"},
+		{`[1] x`, "invalid JSON at offset 4: invalid character 'x' after top-level value"},
+		{`{} {}`, "invalid JSON at offset 3: invalid character '{' after top-level value"},
+		{`1e400`, "invalid JSON at offset 0: number 1e400 is out of range"},
+		{`[1, 2e999]`, "invalid JSON at offset 4: number 2e999 is out of range"},
+		{strings.Repeat("[", 10001), "invalid JSON at offset 10000: exceeded max depth"},
+	}
+
+	for _, tt := range tests {
+		_, err := FromJSON([]byte(tt.input))
+		if err == nil {
+			t.Errorf("expected error for %.20q", tt.input)
+			continue
+		}
+		if err.Error() != tt.expected {
+			t.Errorf("wrong error for %.20q. expected=%q, got=%q", tt.input, tt.expected, err.Error())
+		}
+	}
+}
+
+func TestToJSON(t *testing.T) {
+	nested := newMap(
+		&object.String{Value: "list"}, &object.Array{Elements: numbers(1, 2)},
+		&object.String{Value: "empty"}, newMap(),
+	)
+
+	tests := []struct {
+		input    object.Object
+		indent   string
+		expected string
+	}{
+		{object.NULL, "", "null"},
+		{&object.Number{Value: 3}, "", "3"},
+		{&object.Number{Value: -2.5}, "", "-2.5"},
+		{&object.Number{Value: 1e21}, "", "1e+21"},
+		{&object.String{Value: "<a href=\"x\">&</a>\n"}, "", `"<a href=\"x\">&</a>\n"`},
+		{&object.String{Value: "日本語 "}, "", `"日本語\u2028"`},
+		{nested, "", `{"list":[1,2],"empty":{}}`},
+		{nested, "  ", "{\n  \"list\": [\n    1,\n    2\n  ],\n  \"empty\": {}\n}"},
+		{newMap(&object.Number{Value: 2}, object.TRUE, object.FALSE, object.NULL), "", `{"2":true,"false":null}`},
+		{&object.Time{Value: time.Date(2000, 1, 2, 3, 4, 5, 5e8, time.FixedZone("", 9*60*60))}, "", `"2000-01-02T03:04:05.5+09:00"`},
+	}
+
+	for _, tt := range tests {
+		data, err := ToJSON(tt.input, tt.indent)
+		if err != nil {
+			t.Errorf("ToJSON(%s) failed: %s", tt.input.Inspect(), err)
+			continue
+		}
+		if string(data) != tt.expected {
+			t.Errorf("wrong JSON for %s. expected=%q, got=%q", tt.input.Inspect(), tt.expected, string(data))
+		}
+	}
+}
+
+func TestToJSONErrors(t *testing.T) {
+	cyclic := &object.Array{}
+	cyclic.Elements = []object.Object{object.NULL, newMap(&object.String{Value: "back"}, cyclic)}
+
+	tests := []struct {
+		input    object.Object
+		expected string
+	}{
+		{&object.Number{Value: math.NaN()}, "cannot convert NaN to JSON"},
+		{&object.Array{Elements: []object.Object{&object.Number{Value: math.Inf(1)}}}, "[0]: cannot convert +Inf to JSON"},
+		{newMap(&object.String{Value: "f"}, &object.Builtin{}), "[f]: cannot convert BUILTIN to JSON"},
+		{cyclic, "[1][back]: cannot convert cyclic ARRAY to JSON"},
+	}
+
+	for _, tt := range tests {
+		_, err := ToJSON(tt.input, "")
+		if err == nil {
+			t.Errorf("expected error for %T", tt.input)
+			continue
+		}
+		if err.Error() != tt.expected {
+			t.Errorf("wrong error. expected=%q, got=%q", tt.expected, err.Error())
+		}
+	}
+}
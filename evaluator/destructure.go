@@ -0,0 +1,77 @@
+package evaluator
+
+import (
+	"strconv"
+	"sugu/ast"
+	"sugu/object"
+)
+
+// BindPattern は value を pattern に従って分解し、パターンの変数を env に宣言する（constant なら const として宣言する）
+// 配列のパターンで足りない要素と、マップのパターンで存在しないキーは null になる
+// 余った要素は ...rest があればその配列になり、なければ無視される
+// 配列のパターンに配列以外、マップのパターンにマップ以外の値を渡すとエラーを返す
+func BindPattern(env *object.Environment, pattern ast.Pattern, value object.Object, constant bool) *object.Error {
+	switch pattern := pattern.(type) {
+	case *ast.Identifier:
+		if constant {
+			env.SetConst(pattern.Value, value)
+		} else {
+			env.Set(pattern.Value, value)
+		}
+		return nil
+
+	case *ast.ArrayPattern:
+		arr, ok := value.(*object.Array)
+		if !ok {
+			return newError(object.TYPE_ERROR, "cannot destructure %s with array pattern %s", value.Type(), pattern.String())
+		}
+		for i, elem := range pattern.Elements {
+			var v object.Object = NULL
+			if i < len(arr.Elements) {
+				v = arr.Elements[i]
+			}
+			if errObj := BindPattern(env, elem, v, constant); errObj != nil {
+				return errObj
+			}
+		}
+		if pattern.Rest != nil {
+			rest := []object.Object{}
+			if len(arr.Elements) > len(pattern.Elements) {
+				rest = append(rest, arr.Elements[len(pattern.Elements):]...)
+			}
+			return BindPattern(env, pattern.Rest, &object.Array{Elements: rest}, constant)
+		}
+		return nil
+
+	case *ast.MapPattern:
+		m, ok := value.(*object.Map)
+		if !ok {
+			return newError(object.TYPE_ERROR, "cannot destructure %s with map pattern %s", value.Type(), pattern.String())
+		}
+		for _, pair := range pattern.Pairs {
+			var v object.Object = NULL
+			if found, ok := m.Get(patternKey(pair.Key)); ok {
+				v = found.Value
+			}
+			if errObj := BindPattern(env, pair.Value, v, constant); errObj != nil {
+				return errObj
+			}
+		}
+		return nil
+	}
+	return newError(object.GENERIC_ERROR, "unknown pattern: %T", pattern)
+}
+
+// patternKey はマップのパターンのキー（文字列・数値・真偽値のリテラル）を値にする
+func patternKey(key ast.Expression) object.Hashable {
+	switch key := key.(type) {
+	case *ast.NumberLiteral:
+		n, _ := strconv.ParseFloat(key.Value, 64)
+		return &object.Number{Value: n}
+	case *ast.BooleanLiteral:
+		return nativeBoolToBooleanObject(key.Value)
+	case *ast.StringLiteral:
+		return &object.String{Value: key.Value}
+	}
+	return &object.String{Value: key.String()}
+}
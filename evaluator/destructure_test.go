@@ -0,0 +1,124 @@
+package evaluator
+
+import (
+	"sugu/object"
+	"testing"
+)
+
+func TestDestructuringDeclaration(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`const [a, b] = [1, 2]; [b, a]`, "[2, 1]"},
+		{`const [a, b, ...rest] = [1, 2, 3, 4]; rest`, "[3, 4]"},
+		{`const [a, ...rest] = [1]; rest`, "[]"},
+		// 足りない要素は null、余った要素は無視する
+		{`const [a, b, c] = [1, 2]; c`, "null"},
+		{`const [a] = [1, 2, 3]; a`, "1"},
+		{`const [] = [1]; 1`, "1"},
+		{`mut {"name": n, "age": a} = {"name": "Sugu", "age": 3}; n + " " + string(a)`, "Sugu 3"},
+		{`const {"missing": m} = {}; m`, "null"},
+		{`const {1: one, true: yes} = {1: "a", true: "b"}; one + yes`, "ab"},
+		// 入れ子のパターン
+		{`const [x, [y, z]] = [1, [2, 3]]; x + y + z`, "6"},
+		{`const {"pos": [x, y]} = {"pos": [3, 4]}; x * y`, "12"},
+		{`const [{"id": id}, ...others] = [{"id": 7}, {"id": 8}]; [id, len(others)]`, "[7, 1]"},
+		// 宣言の値は右辺の値
+		{`const [a] = [5]`, "[5]"},
+		// 関数から複数の値を返す
+		{`func divmod(a, b) => [floor(a / b), a % b]; const [q, r] = divmod(7, 2); [q, r]`, "[3, 1]"},
+		{`mut [a, b] = [1, 2]; a = 10; b += 1; [a, b]`, "[10, 3]"},
+		{`mut n = 0; for (mut [a, b] = [0, 3]; a < b; a++) { n++; } n`, "3"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("input %q: wrong result. expected=%q, got=%q", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestDestructuringErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`const [a, b] = 1;`, "line 1, column 1: cannot destructure NUMBER with array pattern [a, b]"},
+		{`mut {"a": a} = [1];`, "line 1, column 1: cannot destructure ARRAY with map pattern {\"a\": a}"},
+		{`const [[a]] = [1];`, "line 1, column 1: cannot destructure NUMBER with array pattern [a]"},
+		// const で宣言した変数は再代入できない
+		{`const [a, b] = [1, 2]; a = 3;`, "line 1, column 26: cannot reassign to const variable: a"},
+		{`const {"k": v} = {"k": [1]}; v = 2;`, "line 1, column 32: cannot reassign to const variable: v"},
+		{`for ([a, b] in [1]) { }`, "line 1, column 1: cannot destructure NUMBER with array pattern [a, b]"},
+		{`func f([a]) => a; f("x")`, "line 1, column 20: cannot destructure STRING with array pattern [a]"},
+		{`func f([a]) => a; f()`, "line 1, column 20: cannot destructure NULL with array pattern [a]"},
+		// デフォルト値が合わない場合はパラメータの位置
+		{`func f([a] = 1) => a; f()`, "line 1, column 8: cannot destructure NUMBER with array pattern [a]"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("input %q: object is not Error. got=%T (%+v)", tt.input, evaluated, evaluated)
+			continue
+		}
+		if errObj.Message != tt.expected {
+			t.Errorf("input %q: wrong error message. expected=%q, got=%q", tt.input, tt.expected, errObj.Message)
+		}
+	}
+}
+
+func TestDestructuringForIn(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`mut s = 0; for ([a, b] in [[1, 2], [3, 4]]) { s += a * b; } s`, "14"},
+		{`mut names = []; for ({"name": n} in [{"name": "a"}, {"name": "b"}]) { names = push(names, n); } names`, "[a, b]"},
+		{`mut out = []; for (i, [a, ...rest] in [[1, 2, 3], [4]]) { out = push(out, [i, a, rest]); } out`, "[[0, 1, [2, 3]], [1, 4, []]]"},
+		{`mut out = []; for (k, {"x": x} in {"p": {"x": 1}, "q": {"x": 2}}) { out = push(out, k + string(x)); } out`, "[p1, q2]"},
+		// ループの変数は反復ごとに作り直され、クロージャはその反復の値を捕捉する
+		{`mut fs = []; for ([a] in [[1], [2]]) { fs = push(fs, func() => a); } map(fs, func(f) => f())`, "[1, 2]"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("input %q: wrong result. expected=%q, got=%q", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+
+	// for-in のパターンの変数は const
+	input := `for ([a] in [[1]]) { a = 2; }`
+	evaluated := testEval(input)
+	if errObj, ok := evaluated.(*object.Error); !ok || errObj.Text() != "cannot reassign to const variable: a" {
+		t.Errorf("input %q: expected const error. got=%s", input, evaluated.Inspect())
+	}
+}
+
+func TestDestructuringParameters(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`func f([a, b]) => a + b; f([1, 2])`, "3"},
+		{`func f({"x": x, "y": y}, scale) => [x * scale, y * scale]; f({"x": 1, "y": 2}, 10)`, "[10, 20]"},
+		{`map([[1, 2], [3, 4]], func([a, b]) => a * b)`, "[2, 12]"},
+		{`func f([first, ...others], ...rest) => [first, others, rest]; f([1, 2], 3)`, "[1, [2], [3]]"},
+		// デフォルト値も分割代入する
+		{`func f([a, b] = [1, 2]) => a + b; [f(), f([10, 20])]`, "[3, 30]"},
+		// パラメータは mut
+		{`func f([a]) => { a++; return a; }; f([1])`, "2"},
+		{`func([a, b], {"k": v}) => a`, "func([a, b], {\"k\": v}) => { ... }"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("input %q: wrong result. expected=%q, got=%q", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
@@ -0,0 +1,27 @@
+package evaluator_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sugu/ast"
+	"sugu/evaluator"
+	"sugu/object"
+	"sugu/vm"
+	"testing"
+)
+
+// TestMain は評価器のテストを木構造評価器で実行した後、
+// 同じテストケースを VM バックエンドでもう一度実行し、両者の結果が一致することを確認する
+func TestMain(m *testing.M) {
+	code := m.Run()
+	if code != 0 {
+		os.Exit(code)
+	}
+
+	fmt.Println("=== engine: vm")
+	evaluator.SetTestEngine(func(ctx context.Context, ev *evaluator.Evaluator, program *ast.Program, env *object.Environment) object.Object {
+		return vm.New(ev).EvalContext(ctx, program, env)
+	})
+	os.Exit(m.Run())
+}
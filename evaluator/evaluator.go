@@ -1,39 +1,113 @@
 package evaluator
 
 import (
+	"bufio"
+	"context"
 	"fmt"
+	"io"
 	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
 	"strconv"
+	"strings"
 	"sugu/ast"
 	"sugu/object"
+	"time"
 )
 
-// シングルトン
+// シングルトン（object パッケージのものと同じ）
 var (
-	NULL  = &object.Null{}
-	TRUE  = &object.Boolean{Value: true}
-	FALSE = &object.Boolean{Value: false}
+	NULL  = object.NULL
+	TRUE  = object.TRUE
+	FALSE = object.FALSE
 )
 
-// Eval はASTノードを評価してオブジェクトを返す
+// Evaluator は評価器の状態を保持する
+// import されたモジュールのキャッシュなど、1 回の実行の間で共有される状態を持つ
+type Evaluator struct {
+	modules map[string]*module // 絶対パスをキーにした評価済みモジュール
+	current *module            // 現在評価中のモジュール
+	loading []*module          // 評価中の import の連鎖（循環 import の検出に使用）
+	root    *module            // 最初に評価されたファイル
+	file    string             // 現在評価中のコードのファイル名（エラー位置の表示に使用）
+	engine  Engine             // モジュールの評価に使うバックエンド（nil なら木構造評価器）
+
+	limits   Limits          // 実行の上限
+	ctx      context.Context // 実行中の context（EvalContext の外では nil）
+	steps    int64           // 実行したステップ数
+	exceeded *object.Error   // 上限に達したときのエラー（達していなければ nil）
+
+	calls []object.StackFrame // 現在の呼び出し履歴（外側の呼び出しから順）
+	site  *ast.CallExpression // 実行中の組み込み関数の呼び出し（組み込み関数から呼び出す関数の呼び出し履歴に使用）
+
+	sources map[string][]string // ファイル名ごとのソースコードの行（エラーの抜粋の表示に使用）
+	named   []string            // SetNamedSource で登録した名前（古い順）
+
+	builtins  map[string]*object.Builtin // この Evaluator の組み込み関数
+	stdin     *bufio.Reader              // in() が読み込む入力
+	stdout    io.Writer                  // out() と outln() の出力先
+	stderr    io.Writer                  // errout() と erroutln() の出力先
+	caller    Caller                     // Call と組み込み関数から関数を呼び出すバックエンド（nil なら木構造評価器）
+	regexps   map[string]*regexp.Regexp  // コンパイル済みの正規表現のキャッシュ（パターンをキーにする）
+	clock     func() time.Time           // now() が返す現在時刻
+	transport http.RoundTripper          // httpRequest などの通信に使う（nil なら通信を禁止する）
+
+	strictArity bool // 関数の引数の数が合わない呼び出しをエラーにするか
+}
+
+// New は新しい Evaluator を作成する
+// filename は評価するファイルのパスで、import の相対パスはこのファイルを基準に解決される
+// REPL などファイルを持たない場合は空文字列を渡す（カレントディレクトリ基準になる）
+func New(filename string) *Evaluator {
+	e := &Evaluator{modules: make(map[string]*module)}
+	e.root = &module{}
+	if filename != "" {
+		e.root.path = filename
+		if abs, err := filepath.Abs(filename); err == nil {
+			e.root.path = abs
+		}
+		e.root.name = filepath.Base(filename)
+		e.loading = []*module{e.root}
+	}
+	e.current, e.file = e.root, e.root.name
+	e.stdin, e.stdout, e.stderr = bufio.NewReader(os.Stdin), os.Stdout, os.Stderr
+	e.clock, e.transport = time.Now, http.DefaultTransport
+	e.builtins = e.newBuiltins()
+	return e
+}
+
+// Eval は新しい Evaluator でASTノードを評価する
 func Eval(node ast.Node, env *object.Environment) object.Object {
+	return New("").Eval(node, env)
+}
+
+// Eval はASTノードを評価してオブジェクトを返す
+func (e *Evaluator) Eval(node ast.Node, env *object.Environment) object.Object {
 	switch node := node.(type) {
 	// プログラム
 	case *ast.Program:
-		return evalProgram(node, env)
+		return e.evalProgram(node, env)
 
 	// 文
 	case *ast.ExpressionStatement:
-		return Eval(node.Expression, env)
+		return e.Eval(node.Expression, env)
 
 	case *ast.BlockStatement:
-		return evalBlockStatement(node, env)
+		return e.evalBlockStatement(node, env)
 
 	case *ast.VariableStatement:
-		val := Eval(node.Value, env)
+		val := e.Eval(node.Value, env)
 		if isError(val) {
 			return val
 		}
+		if node.Pattern != nil {
+			if errObj := BindPattern(env, node.Pattern, val, node.Token.Literal == "const"); errObj != nil {
+				return e.locate(errObj, node.Token)
+			}
+			return val
+		}
 		if node.Token.Literal == "const" {
 			env.SetConst(node.Name.Value, val)
 		} else {
@@ -42,26 +116,26 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 		return val
 
 	case *ast.ReturnStatement:
-		val := Eval(node.ReturnValue, env)
+		val := e.Eval(node.ReturnValue, env)
 		if isError(val) {
 			return val
 		}
 		return &object.ReturnValue{Value: val}
 
 	case *ast.IfStatement:
-		return evalIfStatement(node, env)
+		return e.evalIfStatement(node, env)
 
 	case *ast.WhileStatement:
-		return evalWhileStatement(node, env)
+		return e.evalWhileStatement(node, env)
 
 	case *ast.ForStatement:
-		return evalForStatement(node, env)
+		return e.evalForStatement(node, env)
 
 	case *ast.ForInStatement:
-		return evalForInStatement(node, env)
+		return e.locate(e.evalForInStatement(node, env), node.Token)
 
 	case *ast.SwitchStatement:
-		return evalSwitchStatement(node, env)
+		return e.evalSwitchStatement(node, env)
 
 	case *ast.BreakStatement:
 		return &breakValue{}
@@ -70,25 +144,34 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 		return &continueValue{}
 
 	case *ast.TryStatement:
-		return evalTryStatement(node, env)
+		return e.evalTryStatement(node, env)
 
 	case *ast.ThrowStatement:
-		return evalThrowStatement(node, env)
+		return e.evalThrowStatement(node, env)
+
+	case *ast.ImportStatement:
+		return e.evalImportStatement(node, env)
+
+	case *ast.ExportStatement:
+		return e.evalExportStatement(node, env)
 
 	// 式
 	case *ast.Identifier:
-		return evalIdentifier(node, env)
+		return e.evalIdentifier(node, env)
 
 	case *ast.NumberLiteral:
 		val, err := strconv.ParseFloat(node.Value, 64)
 		if err != nil {
-			return newErrorWithPos(node.Token.Line, node.Token.Column, "could not parse %q as number", node.Value)
+			return e.newErrorWithPos(node.Token.Line, node.Token.Column, object.VALUE_ERROR, "could not parse %q as number", node.Value)
 		}
 		return &object.Number{Value: val}
 
 	case *ast.StringLiteral:
 		return &object.String{Value: node.Value}
 
+	case *ast.TemplateLiteral:
+		return e.evalTemplateLiteral(node, env)
+
 	case *ast.BooleanLiteral:
 		return nativeBoolToBooleanObject(node.Value)
 
@@ -96,100 +179,114 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 		return NULL
 
 	case *ast.PrefixExpression:
-		right := Eval(node.Right, env)
+		right := e.Eval(node.Right, env)
 		if isError(right) {
 			return right
 		}
-		return evalPrefixExpression(node.Operator, right)
+		return e.locate(evalPrefixExpression(node.Operator, right), node.Token)
 
 	case *ast.InfixExpression:
 		// 論理演算子は短絡評価のため特別扱い
 		if node.Operator == "&&" || node.Operator == "||" {
-			return evalLogicalExpression(node, env)
+			return e.evalLogicalExpression(node, env)
 		}
-		left := Eval(node.Left, env)
+		left := e.Eval(node.Left, env)
 		if isError(left) {
 			return left
 		}
-		right := Eval(node.Right, env)
+		right := e.Eval(node.Right, env)
 		if isError(right) {
 			return right
 		}
-		return evalInfixExpression(node.Operator, left, right)
+		return e.locate(evalInfixExpression(node.Operator, left, right), node.Token)
 
 	case *ast.FunctionLiteral:
-		params := node.Parameters
-		body := node.Body
 		name := ""
 		if node.Name != nil {
 			name = node.Name.Value
 		}
-		fn := &object.Function{Parameters: params, Body: body, Env: env, Name: name}
+		fn := &object.Function{
+			Parameters: node.Parameters,
+			Defaults:   node.Defaults,
+			Patterns:   node.Patterns,
+			Rest:       node.Rest,
+			Body:       node.Body,
+			Env:        env,
+			Name:       name,
+			File:       e.file,
+		}
 		if name != "" {
 			env.Set(name, fn)
 		}
 		return fn
 
 	case *ast.CallExpression:
-		function := Eval(node.Function, env)
+		function := e.Eval(node.Function, env)
 		if isError(function) {
 			return function
 		}
-		args := evalExpressions(node.Arguments, env)
+		args := e.evalExpressions(node.Arguments, env)
 		if len(args) == 1 && isError(args[0]) {
 			return args[0]
 		}
-		return applyFunction(function, args)
+		return e.locate(e.callFunction(node, function, args), node.Token)
 
 	case *ast.AssignExpression:
-		return evalAssignExpression(node, env)
+		return e.evalAssignExpression(node, env)
 
 	case *ast.ArrayLiteral:
-		elements := evalExpressions(node.Elements, env)
+		elements := e.evalExpressions(node.Elements, env)
 		if len(elements) == 1 && isError(elements[0]) {
 			return elements[0]
 		}
 		return &object.Array{Elements: elements}
 
 	case *ast.IndexExpression:
-		left := Eval(node.Left, env)
+		left := e.Eval(node.Left, env)
 		if isError(left) {
 			return left
 		}
-		index := Eval(node.Index, env)
+		index := e.Eval(node.Index, env)
 		if isError(index) {
 			return index
 		}
-		return evalIndexExpression(left, index)
+		return e.locate(evalIndexExpression(left, index), node.Token)
 
 	case *ast.MapLiteral:
-		return evalMapLiteral(node, env)
+		return e.locate(e.evalMapLiteral(node, env), node.Token)
 
 	case *ast.IndexAssignExpression:
-		return evalIndexAssignExpression(node, env)
+		return e.locate(e.evalIndexAssignExpression(node, env), node.Token)
 
 	case *ast.PostfixExpression:
-		return evalPostfixExpression(node, env)
+		return e.locate(e.evalPostfixExpression(node, env), node.Token)
 
 	case *ast.CompoundAssignExpression:
-		return evalCompoundAssignExpression(node, env)
+		return e.locate(e.evalCompoundAssignExpression(node, env), node.Token)
 
 	case *ast.IndexCompoundAssignExpression:
-		return evalIndexCompoundAssignExpression(node, env)
+		return e.locate(e.evalIndexCompoundAssignExpression(node, env), node.Token)
 
 	case *ast.SliceExpression:
-		return evalSliceExpression(node, env)
+		return e.locate(e.evalSliceExpression(node, env), node.Token)
 	}
 
 	return nil
 }
 
 // evalProgram はプログラム全体を評価
-func evalProgram(program *ast.Program, env *object.Environment) object.Object {
+func (e *Evaluator) evalProgram(program *ast.Program, env *object.Environment) object.Object {
+	if errObj := e.checkLoopControl(program); errObj != nil {
+		return errObj
+	}
+
 	var result object.Object
 
 	for _, statement := range program.Statements {
-		result = Eval(statement, env)
+		if errObj := e.Step(); errObj != nil {
+			return errObj
+		}
+		result = e.Eval(statement, env)
 
 		switch result := result.(type) {
 		case *object.ReturnValue:
@@ -198,7 +295,7 @@ func evalProgram(program *ast.Program, env *object.Environment) object.Object {
 			return result
 		case *throwValue:
 			// キャッチされなかったthrowはエラーとして扱う
-			return newError("uncaught exception: %s", result.Value.Inspect())
+			return UncaughtError(result.Value, result.Stack)
 		}
 	}
 
@@ -206,11 +303,14 @@ func evalProgram(program *ast.Program, env *object.Environment) object.Object {
 }
 
 // evalBlockStatement はブロック文を評価
-func evalBlockStatement(block *ast.BlockStatement, env *object.Environment) object.Object {
+func (e *Evaluator) evalBlockStatement(block *ast.BlockStatement, env *object.Environment) object.Object {
 	var result object.Object
 
 	for _, statement := range block.Statements {
-		result = Eval(statement, env)
+		if errObj := e.Step(); errObj != nil {
+			return errObj
+		}
+		result = e.Eval(statement, env)
 
 		if result != nil {
 			rt := result.Type()
@@ -235,21 +335,21 @@ func evalBlockStatement(block *ast.BlockStatement, env *object.Environment) obje
 }
 
 // evalIdentifier は識別子を評価
-func evalIdentifier(node *ast.Identifier, env *object.Environment) object.Object {
+func (e *Evaluator) evalIdentifier(node *ast.Identifier, env *object.Environment) object.Object {
 	if val, ok := env.Get(node.Value); ok {
 		return val
 	}
 
-	if builtin, ok := builtins[node.Value]; ok {
+	if builtin, ok := e.builtins[node.Value]; ok {
 		return builtin
 	}
 
-	return newErrorWithPos(node.Token.Line, node.Token.Column, "identifier not found: %s", node.Value)
+	return e.newErrorWithPos(node.Token.Line, node.Token.Column, object.REFERENCE_ERROR, "identifier not found: %s", node.Value)
 }
 
 // evalAssignExpression は代入式を評価
-func evalAssignExpression(node *ast.AssignExpression, env *object.Environment) object.Object {
-	val := Eval(node.Value, env)
+func (e *Evaluator) evalAssignExpression(node *ast.AssignExpression, env *object.Environment) object.Object {
+	val := e.Eval(node.Value, env)
 	if isError(val) {
 		return val
 	}
@@ -258,60 +358,53 @@ func evalAssignExpression(node *ast.AssignExpression, env *object.Environment) o
 
 	// 変数が存在するか確認
 	if _, ok := env.Get(name); !ok {
-		return newErrorWithPos(node.Name.Token.Line, node.Name.Token.Column, "identifier not found: %s", name)
+		return e.newErrorWithPos(node.Name.Token.Line, node.Name.Token.Column, object.REFERENCE_ERROR, "identifier not found: %s", name)
 	}
 
 	// const変数への再代入をチェック
 	if env.IsConst(name) {
-		return newErrorWithPos(node.Token.Line, node.Token.Column, "cannot reassign to const variable: %s", name)
+		return e.newErrorWithPos(node.Token.Line, node.Token.Column, object.TYPE_ERROR, "cannot reassign to const variable: %s", name)
 	}
 
 	// 変数が定義されているスコープで値を更新
 	result, ok := env.Update(name, val)
 	if !ok {
-		return newErrorWithPos(node.Token.Line, node.Token.Column, "failed to update variable: %s", name)
+		return e.newErrorWithPos(node.Token.Line, node.Token.Column, object.REFERENCE_ERROR, "failed to update variable: %s", name)
 	}
 	return result
 }
 
 // evalIndexAssignExpression はインデックス代入式を評価（arr[0] = 10, map["key"] = value）
-func evalIndexAssignExpression(node *ast.IndexAssignExpression, env *object.Environment) object.Object {
+func (e *Evaluator) evalIndexAssignExpression(node *ast.IndexAssignExpression, env *object.Environment) object.Object {
 	// 左辺が識別子の場合、const チェックを行う
 	if ident, ok := node.Left.(*ast.Identifier); ok {
 		if env.IsConst(ident.Value) {
-			return newError("cannot modify const variable: %s", ident.Value)
+			return newError(object.TYPE_ERROR, "cannot modify const variable: %s", ident.Value)
 		}
 	}
 
-	left := Eval(node.Left, env)
+	left := e.Eval(node.Left, env)
 	if isError(left) {
 		return left
 	}
 
-	index := Eval(node.Index, env)
+	index := e.Eval(node.Index, env)
 	if isError(index) {
 		return index
 	}
 
-	val := Eval(node.Value, env)
+	val := e.Eval(node.Value, env)
 	if isError(val) {
 		return val
 	}
 
-	switch obj := left.(type) {
-	case *object.Array:
-		return evalArrayIndexAssignment(obj, index, val)
-	case *object.Map:
-		return evalMapIndexAssignment(obj, index, val)
-	default:
-		return newError("index assignment not supported: %s", left.Type())
-	}
+	return IndexAssign(left, index, val)
 }
 
 // evalArrayIndexAssignment は配列のインデックス代入を評価
 func evalArrayIndexAssignment(array *object.Array, index, val object.Object) object.Object {
 	if index.Type() != object.NUMBER_OBJ {
-		return newError("array index must be a number, got %s", index.Type())
+		return newError(object.TYPE_ERROR, "array index must be a number, got %s", index.Type())
 	}
 
 	idx := int64(index.(*object.Number).Value)
@@ -323,7 +416,7 @@ func evalArrayIndexAssignment(array *object.Array, index, val object.Object) obj
 	}
 
 	if idx < 0 || idx >= length {
-		return newError("array index out of bounds: %d (length: %d)", idx, length)
+		return newError(object.INDEX_ERROR, "array index out of bounds: %d (length: %d)", idx, length)
 	}
 
 	array.Elements[idx] = val
@@ -334,19 +427,19 @@ func evalArrayIndexAssignment(array *object.Array, index, val object.Object) obj
 func evalMapIndexAssignment(mapObj *object.Map, index, val object.Object) object.Object {
 	key, ok := index.(object.Hashable)
 	if !ok {
-		return newError("unusable as hash key: %s", index.Type())
+		return newError(object.TYPE_ERROR, "unusable as hash key: %s", index.Type())
 	}
 
-	mapObj.Pairs[key.HashKey()] = object.HashPair{Key: index, Value: val}
+	mapObj.Set(key, val)
 	return val
 }
 
 // evalExpressions は式のリストを評価
-func evalExpressions(exps []ast.Expression, env *object.Environment) []object.Object {
+func (e *Evaluator) evalExpressions(exps []ast.Expression, env *object.Environment) []object.Object {
 	var result []object.Object
 
-	for _, e := range exps {
-		evaluated := Eval(e, env)
+	for _, exp := range exps {
+		evaluated := e.Eval(exp, env)
 		if isError(evaluated) {
 			return []object.Object{evaluated}
 		}
@@ -364,7 +457,7 @@ func evalPrefixExpression(operator string, right object.Object) object.Object {
 	case "-":
 		return evalMinusPrefixOperatorExpression(right)
 	default:
-		return newError("unknown operator: %s%s", operator, right.Type())
+		return newError(object.TYPE_ERROR, "unknown operator: %s%s", operator, right.Type())
 	}
 }
 
@@ -385,7 +478,7 @@ func evalBangOperatorExpression(right object.Object) object.Object {
 // evalMinusPrefixOperatorExpression は-前置演算子を評価
 func evalMinusPrefixOperatorExpression(right object.Object) object.Object {
 	if right.Type() != object.NUMBER_OBJ {
-		return newError("unknown operator: -%s", right.Type())
+		return newError(object.TYPE_ERROR, "unknown operator: -%s", right.Type())
 	}
 	value := right.(*object.Number).Value
 	return &object.Number{Value: -value}
@@ -398,14 +491,19 @@ func evalInfixExpression(operator string, left, right object.Object) object.Obje
 		return evalNumberInfixExpression(operator, left, right)
 	case left.Type() == object.STRING_OBJ && right.Type() == object.STRING_OBJ:
 		return evalStringInfixExpression(operator, left, right)
+	case left.Type() == object.TIME_OBJ && right.Type() == object.TIME_OBJ:
+		return evalTimeInfixExpression(operator, left, right)
+	case operator == "+" && isStringAndException(left, right):
+		// catch したエラーは文字列と連結するとメッセージになる（"Error: " + e）
+		return &object.String{Value: left.Inspect() + right.Inspect()}
 	case operator == "==":
-		return nativeBoolToBooleanObject(left == right)
+		return nativeBoolToBooleanObject(isEqual(left, right))
 	case operator == "!=":
-		return nativeBoolToBooleanObject(left != right)
+		return nativeBoolToBooleanObject(!isEqual(left, right))
 	case left.Type() != right.Type():
-		return newError("type mismatch: %s %s %s", left.Type(), operator, right.Type())
+		return newError(object.TYPE_ERROR, "type mismatch: %s %s %s", left.Type(), operator, right.Type())
 	default:
-		return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+		return newError(object.TYPE_ERROR, "unknown operator: %s %s %s", left.Type(), operator, right.Type())
 	}
 }
 
@@ -423,12 +521,12 @@ func evalNumberInfixExpression(operator string, left, right object.Object) objec
 		return &object.Number{Value: leftVal * rightVal}
 	case "/":
 		if rightVal == 0 {
-			return newError("division by zero")
+			return newError(object.DIVISION_BY_ZERO, "division by zero")
 		}
 		return &object.Number{Value: leftVal / rightVal}
 	case "%":
 		if rightVal == 0 {
-			return newError("division by zero")
+			return newError(object.DIVISION_BY_ZERO, "division by zero")
 		}
 		return &object.Number{Value: math.Mod(leftVal, rightVal)}
 	case "<":
@@ -444,7 +542,7 @@ func evalNumberInfixExpression(operator string, left, right object.Object) objec
 	case "!=":
 		return nativeBoolToBooleanObject(leftVal != rightVal)
 	default:
-		return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+		return newError(object.TYPE_ERROR, "unknown operator: %s %s %s", left.Type(), operator, right.Type())
 	}
 }
 
@@ -461,13 +559,36 @@ func evalStringInfixExpression(operator string, left, right object.Object) objec
 	case "!=":
 		return nativeBoolToBooleanObject(leftVal != rightVal)
 	default:
-		return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+		return newError(object.TYPE_ERROR, "unknown operator: %s %s %s", left.Type(), operator, right.Type())
+	}
+}
+
+// evalTimeInfixExpression は日時の比較を評価する（タイムゾーンが違っても同じ時刻なら等しい）
+func evalTimeInfixExpression(operator string, left, right object.Object) object.Object {
+	leftVal := left.(*object.Time).Value
+	rightVal := right.(*object.Time).Value
+
+	switch operator {
+	case "<":
+		return nativeBoolToBooleanObject(leftVal.Before(rightVal))
+	case ">":
+		return nativeBoolToBooleanObject(leftVal.After(rightVal))
+	case "<=":
+		return nativeBoolToBooleanObject(!leftVal.After(rightVal))
+	case ">=":
+		return nativeBoolToBooleanObject(!leftVal.Before(rightVal))
+	case "==":
+		return nativeBoolToBooleanObject(leftVal.Equal(rightVal))
+	case "!=":
+		return nativeBoolToBooleanObject(!leftVal.Equal(rightVal))
+	default:
+		return newError(object.TYPE_ERROR, "unknown operator: %s %s %s", left.Type(), operator, right.Type())
 	}
 }
 
 // evalLogicalExpression は論理演算子を短絡評価する
-func evalLogicalExpression(node *ast.InfixExpression, env *object.Environment) object.Object {
-	left := Eval(node.Left, env)
+func (e *Evaluator) evalLogicalExpression(node *ast.InfixExpression, env *object.Environment) object.Object {
+	left := e.Eval(node.Left, env)
 	if isError(left) {
 		return left
 	}
@@ -476,38 +597,41 @@ func evalLogicalExpression(node *ast.InfixExpression, env *object.Environment) o
 		if !isTruthy(left) {
 			return left
 		}
-		return Eval(node.Right, env)
+		return e.Eval(node.Right, env)
 	}
 
 	// ||
 	if isTruthy(left) {
 		return left
 	}
-	return Eval(node.Right, env)
+	return e.Eval(node.Right, env)
 }
 
 // evalIfStatement はif文を評価
-func evalIfStatement(ie *ast.IfStatement, env *object.Environment) object.Object {
-	condition := Eval(ie.Condition, env)
+func (e *Evaluator) evalIfStatement(ie *ast.IfStatement, env *object.Environment) object.Object {
+	condition := e.Eval(ie.Condition, env)
 	if isError(condition) {
 		return condition
 	}
 
 	if isTruthy(condition) {
-		return Eval(ie.Consequence, env)
+		return e.Eval(ie.Consequence, env)
 	} else if ie.Alternative != nil {
-		return Eval(ie.Alternative, env)
+		return e.Eval(ie.Alternative, env)
 	} else {
 		return NULL
 	}
 }
 
 // evalWhileStatement はwhile文を評価
-func evalWhileStatement(ws *ast.WhileStatement, env *object.Environment) object.Object {
+func (e *Evaluator) evalWhileStatement(ws *ast.WhileStatement, env *object.Environment) object.Object {
 	var result object.Object = NULL
 
 	for {
-		condition := Eval(ws.Condition, env)
+		if errObj := e.Step(); errObj != nil {
+			return errObj
+		}
+		condition := e.Eval(ws.Condition, env)
 		if isError(condition) {
 			return condition
 		}
@@ -516,7 +640,7 @@ func evalWhileStatement(ws *ast.WhileStatement, env *object.Environment) object.
 			break
 		}
 
-		result = Eval(ws.Body, env)
+		result = e.Eval(ws.Body, env)
 		if isError(result) {
 			return result
 		}
@@ -539,13 +663,13 @@ func evalWhileStatement(ws *ast.WhileStatement, env *object.Environment) object.
 }
 
 // evalForStatement はfor文を評価
-func evalForStatement(fs *ast.ForStatement, env *object.Environment) object.Object {
+func (e *Evaluator) evalForStatement(fs *ast.ForStatement, env *object.Environment) object.Object {
 	// 新しいスコープを作成
 	forEnv := object.NewEnclosedEnvironment(env)
 
 	// 初期化
 	if fs.Init != nil {
-		init := Eval(fs.Init, forEnv)
+		init := e.Eval(fs.Init, forEnv)
 		if isError(init) {
 			return init
 		}
@@ -554,9 +678,13 @@ func evalForStatement(fs *ast.ForStatement, env *object.Environment) object.Obje
 	var result object.Object = NULL
 
 	for {
+		if errObj := e.Step(); errObj != nil {
+			return errObj
+		}
+
 		// 条件チェック
 		if fs.Condition != nil {
-			condition := Eval(fs.Condition, forEnv)
+			condition := e.Eval(fs.Condition, forEnv)
 			if isError(condition) {
 				return condition
 			}
@@ -566,7 +694,7 @@ func evalForStatement(fs *ast.ForStatement, env *object.Environment) object.Obje
 		}
 
 		// ボディ実行
-		result = Eval(fs.Body, forEnv)
+		result = e.Eval(fs.Body, forEnv)
 		if isError(result) {
 			return result
 		}
@@ -579,7 +707,7 @@ func evalForStatement(fs *ast.ForStatement, env *object.Environment) object.Obje
 		if _, ok := result.(*continueValue); ok {
 			// 更新式を実行してから続行
 			if fs.Update != nil {
-				update := Eval(fs.Update, forEnv)
+				update := e.Eval(fs.Update, forEnv)
 				if isError(update) {
 					return update
 				}
@@ -593,7 +721,7 @@ func evalForStatement(fs *ast.ForStatement, env *object.Environment) object.Obje
 
 		// 更新式
 		if fs.Update != nil {
-			update := Eval(fs.Update, forEnv)
+			update := e.Eval(fs.Update, forEnv)
 			if isError(update) {
 				return update
 			}
@@ -604,39 +732,45 @@ func evalForStatement(fs *ast.ForStatement, env *object.Environment) object.Obje
 }
 
 // evalForInStatement はfor-in文を評価
-func evalForInStatement(node *ast.ForInStatement, env *object.Environment) object.Object {
-	iterable := Eval(node.Iterable, env)
+func (e *Evaluator) evalForInStatement(node *ast.ForInStatement, env *object.Environment) object.Object {
+	iterable := e.Eval(node.Iterable, env)
 	if isError(iterable) {
 		return iterable
 	}
 
 	switch obj := iterable.(type) {
 	case *object.Array:
-		return evalForInArray(node, obj, env)
+		return e.evalForInArray(node, obj, env)
 	case *object.Map:
-		return evalForInMap(node, obj, env)
+		return e.evalForInMap(node, obj, env)
 	default:
-		return newError("for-in requires ARRAY or MAP, got %s", iterable.Type())
+		return newError(object.TYPE_ERROR, "for-in requires ARRAY or MAP, got %s", iterable.Type())
 	}
 }
 
 // evalForInArray は配列に対する for-in を評価
-func evalForInArray(node *ast.ForInStatement, arr *object.Array, env *object.Environment) object.Object {
+func (e *Evaluator) evalForInArray(node *ast.ForInStatement, arr *object.Array, env *object.Environment) object.Object {
 	var result object.Object = NULL
 
 	for i, elem := range arr.Elements {
+		if errObj := e.Step(); errObj != nil {
+			return errObj
+		}
 		iterEnv := object.NewEnclosedEnvironment(env)
 
+		var errObj *object.Error
 		if node.Value != nil {
 			// for (index, item in arr)
-			iterEnv.SetConst(node.Key.Value, &object.Number{Value: float64(i)})
-			iterEnv.SetConst(node.Value.Value, elem)
+			errObj = bindLoopVariables(iterEnv, node, &object.Number{Value: float64(i)}, elem)
 		} else {
 			// for (item in arr)
-			iterEnv.SetConst(node.Key.Value, elem)
+			errObj = bindLoopVariables(iterEnv, node, elem, nil)
+		}
+		if errObj != nil {
+			return errObj
 		}
 
-		result = Eval(node.Body, iterEnv)
+		result = e.Eval(node.Body, iterEnv)
 		if isError(result) {
 			return result
 		}
@@ -658,22 +792,21 @@ func evalForInArray(node *ast.ForInStatement, arr *object.Array, env *object.Env
 }
 
 // evalForInMap はマップに対する for-in を評価
-func evalForInMap(node *ast.ForInStatement, mapObj *object.Map, env *object.Environment) object.Object {
+func (e *Evaluator) evalForInMap(node *ast.ForInStatement, mapObj *object.Map, env *object.Environment) object.Object {
 	var result object.Object = NULL
 
-	for _, pair := range mapObj.Pairs {
+	for _, pair := range mapObj.Pairs() {
+		if errObj := e.Step(); errObj != nil {
+			return errObj
+		}
 		iterEnv := object.NewEnclosedEnvironment(env)
 
-		if node.Value != nil {
-			// for (key, value in map)
-			iterEnv.SetConst(node.Key.Value, pair.Key)
-			iterEnv.SetConst(node.Value.Value, pair.Value)
-		} else {
-			// for (key in map)
-			iterEnv.SetConst(node.Key.Value, pair.Key)
+		// for (key, value in map) / for (key in map)
+		if errObj := bindLoopVariables(iterEnv, node, pair.Key, pair.Value); errObj != nil {
+			return errObj
 		}
 
-		result = Eval(node.Body, iterEnv)
+		result = e.Eval(node.Body, iterEnv)
 		if isError(result) {
 			return result
 		}
@@ -694,21 +827,58 @@ func evalForInMap(node *ast.ForInStatement, mapObj *object.Map, env *object.Envi
 	return result
 }
 
+// bindLoopVariables は for-in の変数に key と value を const として束縛する（単一変数形式では key だけ）
+// パターンの変数は分割代入で束縛する
+func bindLoopVariables(env *object.Environment, node *ast.ForInStatement, key, value object.Object) *object.Error {
+	if node.KeyPattern != nil {
+		if errObj := BindPattern(env, node.KeyPattern, key, true); errObj != nil {
+			return errObj
+		}
+	} else {
+		env.SetConst(node.Key.Value, key)
+	}
+
+	if node.Value == nil {
+		return nil
+	}
+	if node.ValuePattern != nil {
+		return BindPattern(env, node.ValuePattern, value, true)
+	}
+	env.SetConst(node.Value.Value, value)
+	return nil
+}
+
+// evalTemplateLiteral はテンプレート文字列の ${} の式を評価し、Inspect の形式で文字列に埋め込む
+func (e *Evaluator) evalTemplateLiteral(node *ast.TemplateLiteral, env *object.Environment) object.Object {
+	var out strings.Builder
+	for i, str := range node.Strings {
+		out.WriteString(str)
+		if i < len(node.Expressions) {
+			value := e.Eval(node.Expressions[i], env)
+			if isFailure(value) {
+				return value
+			}
+			out.WriteString(value.Inspect())
+		}
+	}
+	return &object.String{Value: out.String()}
+}
+
 // evalSwitchStatement はswitch文を評価
-func evalSwitchStatement(ss *ast.SwitchStatement, env *object.Environment) object.Object {
-	value := Eval(ss.Value, env)
+func (e *Evaluator) evalSwitchStatement(ss *ast.SwitchStatement, env *object.Environment) object.Object {
+	value := e.Eval(ss.Value, env)
 	if isError(value) {
 		return value
 	}
 
 	for _, caseClause := range ss.Cases {
-		caseValue := Eval(caseClause.Value, env)
+		caseValue := e.Eval(caseClause.Value, env)
 		if isError(caseValue) {
 			return caseValue
 		}
 
 		if isEqual(value, caseValue) {
-			result := Eval(caseClause.Body, env)
+			result := e.Eval(caseClause.Body, env)
 			// breakを無視（switch内では自動break）
 			if _, ok := result.(*breakValue); ok {
 				return NULL
@@ -719,7 +889,7 @@ func evalSwitchStatement(ss *ast.SwitchStatement, env *object.Environment) objec
 
 	// default節
 	if ss.Default != nil {
-		result := Eval(ss.Default, env)
+		result := e.Eval(ss.Default, env)
 		if _, ok := result.(*breakValue); ok {
 			return NULL
 		}
@@ -729,35 +899,180 @@ func evalSwitchStatement(ss *ast.SwitchStatement, env *object.Environment) objec
 	return NULL
 }
 
+// callFunction は呼び出し履歴を記録して関数を呼び出す
+// 関数から戻ってきたエラーと throw には、まだ持っていなければその時点の呼び出し履歴を付ける
+func (e *Evaluator) callFunction(node *ast.CallExpression, function object.Object, args []object.Object) object.Object {
+	fn, ok := function.(*object.Function)
+	if !ok {
+		prev := e.site
+		e.site = node
+		result := e.applyFunction(function, args)
+		e.site = prev
+		return result
+	}
+
+	if maxDepth := e.MaxCallDepth(); len(e.calls) >= maxDepth {
+		return e.newErrorWithPos(node.Token.Line, node.Token.Column, object.RANGE_ERROR, "maximum call depth exceeded (%d)", maxDepth)
+	}
+
+	env, errObj := e.ExtendFunctionEnv(fn, args)
+	if errObj != nil {
+		return errObj
+	}
+
+	e.calls = append(e.calls, object.StackFrame{
+		Function: fn.Name,
+		File:     e.file,
+		Line:     node.Token.Line,
+		Column:   node.Token.Column,
+	})
+	result := e.evalFunctionBody(fn, env)
+
+	switch result := result.(type) {
+	case *object.Error:
+		if result.Stack == nil {
+			result.Stack = e.stackTrace()
+		}
+	case *throwValue:
+		if result.Stack == nil {
+			result.Stack = e.stackTrace()
+		}
+	}
+
+	e.calls = e.calls[:len(e.calls)-1]
+	return result
+}
+
+// stackTrace は現在の呼び出し履歴を内側の呼び出しから順に返す（関数の中でなければ nil）
+func (e *Evaluator) stackTrace() []object.StackFrame {
+	if len(e.calls) == 0 {
+		return nil
+	}
+	stack := make([]object.StackFrame, len(e.calls))
+	for i, frame := range e.calls {
+		stack[len(e.calls)-1-i] = frame
+	}
+	return stack
+}
+
 // applyFunction は関数を適用
-func applyFunction(fn object.Object, args []object.Object) object.Object {
+func (e *Evaluator) applyFunction(fn object.Object, args []object.Object) object.Object {
 	switch fn := fn.(type) {
 	case *object.Function:
-		extendedEnv := extendFunctionEnv(fn, args)
-		evaluated := Eval(fn.Body, extendedEnv)
-		return unwrapReturnValue(evaluated)
+		env, errObj := e.ExtendFunctionEnv(fn, args)
+		if errObj != nil {
+			return errObj
+		}
+		return e.evalFunctionBody(fn, env)
 
 	case *object.Builtin:
 		return fn.Fn(args...)
 
 	default:
-		return newError("not a function: %s", fn.Type())
+		return newError(object.TYPE_ERROR, "not a function: %s", fn.Type())
 	}
 }
 
-// extendFunctionEnv は関数用の新しい環境を作成
-func extendFunctionEnv(fn *object.Function, args []object.Object) *object.Environment {
+// evalFunctionBody は引数を束縛したスコープ env で、引数が渡されなかったパラメータのデフォルト値を評価してから関数の本体を評価する
+func (e *Evaluator) evalFunctionBody(fn *object.Function, env *object.Environment) object.Object {
+	// エラー位置が関数の定義されたファイルを指すよう切り替える
+	prevFile := e.file
+	e.file = fn.File
+	defer func() { e.file = prevFile }()
+
+	// デフォルト値は前のパラメータを参照できるよう、左から順に評価する
+	for i, param := range fn.Parameters {
+		def := fn.Default(i)
+		if def == nil || env.Exists(param.Value) {
+			continue
+		}
+		value := e.Eval(def, env)
+		if isFailure(value) {
+			return value
+		}
+		env.Set(param.Value, value)
+		if pattern := fn.Pattern(i); pattern != nil {
+			if errObj := BindPattern(env, pattern, value, false); errObj != nil {
+				return e.locate(errObj, param.Token)
+			}
+		}
+	}
+
+	return unwrapReturnValue(e.Eval(fn.Body, env))
+}
+
+// SetStrictArity は関数の引数の数を厳密に確認するかを設定する（既定は確認しない）
+// 確認する場合、デフォルト値のないパラメータより少ない引数や、...rest のない関数への余分な引数はエラーになる
+func (e *Evaluator) SetStrictArity(strict bool) {
+	e.strictArity = strict
+}
+
+// ExtendFunctionEnv は関数 fn の引数 args を束縛した新しいスコープを作る
+// 足りない引数は null になるが、デフォルト値を持つパラメータは束縛せずに残す（呼び出し側がデフォルト値を評価して束縛する）
+// 分割代入のパラメータは、引数をパターンに従って分解して束縛する
+// 余った引数は ...rest があればその配列になり、なければ捨てられる
+// SetStrictArity で確認を有効にしている場合、引数の数が合わなければエラーを返す
+func (e *Evaluator) ExtendFunctionEnv(fn *object.Function, args []object.Object) (*object.Environment, *object.Error) {
+	if e.strictArity {
+		if errObj := checkArity(fn, len(args)); errObj != nil {
+			return nil, errObj
+		}
+	}
+
 	env := object.NewEnclosedEnvironment(fn.Env)
 
 	for paramIdx, param := range fn.Parameters {
+		var arg object.Object = NULL
 		if paramIdx < len(args) {
-			env.Set(param.Value, args[paramIdx])
-		} else {
-			env.Set(param.Value, NULL)
+			arg = args[paramIdx]
+		} else if fn.Default(paramIdx) != nil {
+			continue
+		}
+		// 分割代入のパラメータも仮の識別子で束縛しておく（デフォルト値を評価するかの判定に使う）
+		env.Set(param.Value, arg)
+		if pattern := fn.Pattern(paramIdx); pattern != nil {
+			if errObj := BindPattern(env, pattern, arg, false); errObj != nil {
+				return nil, errObj
+			}
 		}
 	}
 
-	return env
+	if fn.Rest != nil {
+		rest := []object.Object{}
+		if len(args) > len(fn.Parameters) {
+			rest = append(rest, args[len(fn.Parameters):]...)
+		}
+		env.Set(fn.Rest.Value, &object.Array{Elements: rest})
+	}
+
+	return env, nil
+}
+
+// checkArity は argc 個の引数で fn を呼び出せるかを確認する
+func checkArity(fn *object.Function, argc int) *object.Error {
+	required := 0
+	for i := range fn.Parameters {
+		if fn.Default(i) == nil {
+			required++
+		}
+	}
+	max := len(fn.Parameters)
+	if argc >= required && (argc <= max || fn.Rest != nil) {
+		return nil
+	}
+
+	var want string
+	switch {
+	case fn.Rest != nil:
+		want = fmt.Sprintf("%d+", required)
+	case required == max:
+		want = strconv.Itoa(required)
+	case required+1 == max:
+		want = fmt.Sprintf("%d or %d", required, max)
+	default:
+		want = fmt.Sprintf("%d to %d", required, max)
+	}
+	return newError(object.ARGUMENT_ERROR, "wrong number of arguments. got=%d, want=%s", argc, want)
 }
 
 // unwrapReturnValue はReturnValueをアンラップ
@@ -790,7 +1105,20 @@ func isTruthy(obj object.Object) bool {
 	}
 }
 
+// isEqual は == の規則で値を比較する
+// 配列とマップは要素を再帰的に比較する（マップはキーの順序によらない）
+// catch したエラーと文字列は、エラーの位置情報を含まないメッセージで比較する
+// 関数などその他の値は同じオブジェクトの場合だけ等しい
 func isEqual(a, b object.Object) bool {
+	return deepEqual(a, b, nil)
+}
+
+// deepEqual は isEqual の本体
+// comparing は比較中の配列・マップの組で、循環参照をたどって同じ組に戻った場合は等しいとみなす
+func deepEqual(a, b object.Object, comparing map[[2]object.Object]bool) bool {
+	if isStringAndException(a, b) {
+		return exceptionMessageEqual(a, b)
+	}
 	if a.Type() != b.Type() {
 		return false
 	}
@@ -802,25 +1130,83 @@ func isEqual(a, b object.Object) bool {
 		return a.Value == b.(*object.String).Value
 	case *object.Boolean:
 		return a.Value == b.(*object.Boolean).Value
+	case *object.Time:
+		return a.Value.Equal(b.(*object.Time).Value)
 	case *object.Null:
 		return true
+	case *object.Array, *object.Map:
+		if a == b {
+			return true
+		}
+		key := [2]object.Object{a, b}
+		if comparing[key] {
+			return true
+		}
+		if comparing == nil {
+			comparing = make(map[[2]object.Object]bool)
+		}
+		comparing[key] = true
+		defer delete(comparing, key)
+		if a, ok := a.(*object.Array); ok {
+			return arrayEqual(a, b.(*object.Array), comparing)
+		}
+		return mapEqual(a.(*object.Map), b.(*object.Map), comparing)
 	default:
 		return a == b
 	}
 }
 
-func newError(format string, a ...interface{}) *object.Error {
-	return &object.Error{Message: fmt.Sprintf(format, a...)}
+func arrayEqual(a, b *object.Array, comparing map[[2]object.Object]bool) bool {
+	if len(a.Elements) != len(b.Elements) {
+		return false
+	}
+	for i, elem := range a.Elements {
+		if !deepEqual(elem, b.Elements[i], comparing) {
+			return false
+		}
+	}
+	return true
 }
 
-func newErrorWithPos(line, column int, format string, a ...interface{}) *object.Error {
-	msg := fmt.Sprintf(format, a...)
-	return &object.Error{Message: fmt.Sprintf("line %d, column %d: %s", line, column, msg)}
+func mapEqual(a, b *object.Map, comparing map[[2]object.Object]bool) bool {
+	if a.Len() != b.Len() {
+		return false
+	}
+	for _, pair := range a.Pairs() {
+		other, ok := b.Get(pair.Key.(object.Hashable))
+		if !ok || !deepEqual(pair.Value, other.Value, comparing) {
+			return false
+		}
+	}
+	return true
+}
+
+// isSame は same() の規則で値を比較する
+// 配列とマップは同じオブジェクトの場合だけ等しく、それ以外の値は == と同じ
+func isSame(a, b object.Object) bool {
+	switch a.(type) {
+	case *object.Array, *object.Map:
+		return a == b
+	}
+	return isEqual(a, b)
+}
+
+// newError は kind の種類のエラーを作成する
+func newError(kind, format string, a ...interface{}) *object.Error {
+	return &object.Error{Message: fmt.Sprintf(format, a...), Kind: kind}
+}
+
+// newErrorWithPos は位置情報付きのエラーを作成する
+// ファイルを評価している場合はファイル名も位置情報に含める
+func (e *Evaluator) newErrorWithPos(line, column int, kind, format string, a ...interface{}) *object.Error {
+	return e.ErrorAt(e.file, line, column, kind, format, a...)
 }
 
 // evalIndexExpression はインデックスアクセスを評価
 func evalIndexExpression(left, index object.Object) object.Object {
 	switch {
+	case left.Type() == object.EXCEPTION_OBJ && index.Type() == object.STRING_OBJ:
+		return evalExceptionIndexExpression(left.(*object.Exception), index.(*object.String).Value)
 	case left.Type() == object.ARRAY_OBJ && index.Type() == object.NUMBER_OBJ:
 		return evalArrayIndexExpression(left, index)
 	case left.Type() == object.STRING_OBJ && index.Type() == object.NUMBER_OBJ:
@@ -828,35 +1214,34 @@ func evalIndexExpression(left, index object.Object) object.Object {
 	case left.Type() == object.MAP_OBJ:
 		return evalMapIndexExpression(left, index)
 	default:
-		return newError("index operator not supported: %s", left.Type())
+		return newError(object.TYPE_ERROR, "index operator not supported: %s", left.Type())
 	}
 }
 
 // evalMapLiteral はマップリテラルを評価
-func evalMapLiteral(node *ast.MapLiteral, env *object.Environment) object.Object {
-	pairs := make(map[object.HashKey]object.HashPair)
+func (e *Evaluator) evalMapLiteral(node *ast.MapLiteral, env *object.Environment) object.Object {
+	mapObj := &object.Map{}
 
-	for keyNode, valueNode := range node.Pairs {
-		key := Eval(keyNode, env)
+	for _, pair := range node.Pairs {
+		key := e.Eval(pair.Key, env)
 		if isError(key) {
 			return key
 		}
 
-		hashKey, ok := key.(object.Hashable)
-		if !ok {
-			return newError("unusable as hash key: %s", key.Type())
+		hashKey, errObj := HashKey(key)
+		if errObj != nil {
+			return errObj
 		}
 
-		value := Eval(valueNode, env)
+		value := e.Eval(pair.Value, env)
 		if isError(value) {
 			return value
 		}
 
-		hashed := hashKey.HashKey()
-		pairs[hashed] = object.HashPair{Key: key, Value: value}
+		mapObj.Set(hashKey, value)
 	}
 
-	return &object.Map{Pairs: pairs}
+	return mapObj
 }
 
 // evalMapIndexExpression はマップのインデックスアクセスを評価
@@ -865,10 +1250,10 @@ func evalMapIndexExpression(mapObj, index object.Object) object.Object {
 
 	key, ok := index.(object.Hashable)
 	if !ok {
-		return newError("unusable as hash key: %s", index.Type())
+		return newError(object.TYPE_ERROR, "unusable as hash key: %s", index.Type())
 	}
 
-	pair, ok := mapObject.Pairs[key.HashKey()]
+	pair, ok := mapObject.Get(key)
 	if !ok {
 		return NULL
 	}
@@ -935,61 +1320,107 @@ func (c *continueValue) Inspect() string         { return "continue" }
 // throw用の内部型
 type throwValue struct {
 	Value object.Object
+	Stack []object.StackFrame // throw した時点の呼び出し履歴
 }
 
 func (t *throwValue) Type() object.ObjectType { return "THROW" }
 func (t *throwValue) Inspect() string         { return "throw " + t.Value.Inspect() }
 
-// evalTryStatement はtry/catch文を評価
-func evalTryStatement(ts *ast.TryStatement, env *object.Environment) object.Object {
+// evalTryStatement はtry/catch/finally文を評価
+func (e *Evaluator) evalTryStatement(ts *ast.TryStatement, env *object.Environment) object.Object {
 	// tryブロックを評価
-	result := Eval(ts.TryBlock, env)
+	result := e.Eval(ts.TryBlock, env)
+
+	// 実行の上限に達した場合はキャッチせず、finallyも実行せずに中断する
+	if e.LimitExceeded() {
+		return result
+	}
+
+	if ts.CatchBlock != nil {
+		result = e.evalCatchBlock(ts, result, env)
+		if e.LimitExceeded() {
+			return result
+		}
+	}
+
+	if ts.FinallyBlock == nil {
+		return result
+	}
 
+	// finallyブロックは正常終了・throw・エラー・return/break/continue のいずれでも実行する
+	// finallyブロック自体が return/break/continue/throw/エラーで抜けた場合はそちらを優先する
+	finallyResult := e.Eval(ts.FinallyBlock, env)
+	if isAbrupt(finallyResult) {
+		return finallyResult
+	}
+	return result
+}
+
+// evalCatchBlock はtryブロックの結果がthrowまたはエラーであればcatchブロックを実行する
+func (e *Evaluator) evalCatchBlock(ts *ast.TryStatement, result object.Object, env *object.Environment) object.Object {
 	// throwされた場合、catchブロックを実行
 	if thrown, ok := result.(*throwValue); ok {
 		// 新しいスコープでcatchブロックを実行
 		catchEnv := object.NewEnclosedEnvironment(env)
 		catchEnv.Set(ts.CatchParam.Value, thrown.Value)
-		return Eval(ts.CatchBlock, catchEnv)
+		return e.Eval(ts.CatchBlock, catchEnv)
 	}
 
-	// Errorオブジェクト（組み込み関数などからのエラー）もキャッチ
+	// Errorオブジェクト（組み込み関数などからのエラー）もキャッチし、Exception として渡す
 	if errObj, ok := result.(*object.Error); ok {
+		// 関数の外に出ていないエラーは、発生した時点の呼び出し履歴が現在の呼び出し履歴と同じ
+		if errObj.Stack == nil {
+			errObj.Stack = e.stackTrace()
+		}
 		catchEnv := object.NewEnclosedEnvironment(env)
-		catchEnv.Set(ts.CatchParam.Value, &object.String{Value: errObj.Message})
-		return Eval(ts.CatchBlock, catchEnv)
+		catchEnv.Set(ts.CatchParam.Value, object.NewException(errObj))
+		return e.Eval(ts.CatchBlock, catchEnv)
 	}
 
 	return result
 }
 
+// isAbrupt は評価結果が文の並びを途中で抜けるもの（return/break/continue/throw/エラー）かを返す
+func isAbrupt(obj object.Object) bool {
+	switch obj.(type) {
+	case *object.ReturnValue, *object.Error, *breakValue, *continueValue, *throwValue:
+		return true
+	}
+	return false
+}
+
 // evalThrowStatement はthrow文を評価
-func evalThrowStatement(ts *ast.ThrowStatement, env *object.Environment) object.Object {
-	val := Eval(ts.Value, env)
+func (e *Evaluator) evalThrowStatement(ts *ast.ThrowStatement, env *object.Environment) object.Object {
+	val := e.Eval(ts.Value, env)
 	if isError(val) {
 		return val
 	}
-	return &throwValue{Value: val}
+
+	stack := e.stackTrace()
+	if exc, ok := val.(*object.Exception); ok {
+		e.ThrowException(exc, e.file, ts.Token.Line, ts.Token.Column, stack)
+	}
+	return &throwValue{Value: val, Stack: stack}
 }
 
 // evalPostfixExpression は後置演算子式（x++, x--）を評価
-func evalPostfixExpression(node *ast.PostfixExpression, env *object.Environment) object.Object {
+func (e *Evaluator) evalPostfixExpression(node *ast.PostfixExpression, env *object.Environment) object.Object {
 	ident, ok := node.Operand.(*ast.Identifier)
 	if !ok {
-		return newError("postfix operator %s requires a variable", node.Operator)
+		return newError(object.TYPE_ERROR, "postfix operator %s requires a variable", node.Operator)
 	}
 
 	current, ok := env.Get(ident.Value)
 	if !ok {
-		return newErrorWithPos(ident.Token.Line, ident.Token.Column, "identifier not found: %s", ident.Value)
+		return e.newErrorWithPos(ident.Token.Line, ident.Token.Column, object.REFERENCE_ERROR, "identifier not found: %s", ident.Value)
 	}
 
 	if env.IsConst(ident.Value) {
-		return newError("cannot reassign to const variable: %s", ident.Value)
+		return newError(object.TYPE_ERROR, "cannot reassign to const variable: %s", ident.Value)
 	}
 
 	if current.Type() != object.NUMBER_OBJ {
-		return newError("postfix operator %s not supported: %s", node.Operator, current.Type())
+		return newError(object.TYPE_ERROR, "postfix operator %s not supported: %s", node.Operator, current.Type())
 	}
 
 	currentVal := current.(*object.Number).Value
@@ -1005,19 +1436,19 @@ func evalPostfixExpression(node *ast.PostfixExpression, env *object.Environment)
 }
 
 // evalCompoundAssignExpression は複合代入式（x += y）を評価
-func evalCompoundAssignExpression(node *ast.CompoundAssignExpression, env *object.Environment) object.Object {
+func (e *Evaluator) evalCompoundAssignExpression(node *ast.CompoundAssignExpression, env *object.Environment) object.Object {
 	name := node.Name.Value
 
 	current, ok := env.Get(name)
 	if !ok {
-		return newErrorWithPos(node.Name.Token.Line, node.Name.Token.Column, "identifier not found: %s", name)
+		return e.newErrorWithPos(node.Name.Token.Line, node.Name.Token.Column, object.REFERENCE_ERROR, "identifier not found: %s", name)
 	}
 
 	if env.IsConst(name) {
-		return newError("cannot reassign to const variable: %s", name)
+		return newError(object.TYPE_ERROR, "cannot reassign to const variable: %s", name)
 	}
 
-	val := Eval(node.Value, env)
+	val := e.Eval(node.Value, env)
 	if isError(val) {
 		return val
 	}
@@ -1034,25 +1465,25 @@ func evalCompoundAssignExpression(node *ast.CompoundAssignExpression, env *objec
 }
 
 // evalIndexCompoundAssignExpression はインデックス複合代入式（arr[i] += y）を評価
-func evalIndexCompoundAssignExpression(node *ast.IndexCompoundAssignExpression, env *object.Environment) object.Object {
+func (e *Evaluator) evalIndexCompoundAssignExpression(node *ast.IndexCompoundAssignExpression, env *object.Environment) object.Object {
 	// const チェック
 	if ident, ok := node.Left.(*ast.Identifier); ok {
 		if env.IsConst(ident.Value) {
-			return newError("cannot modify const variable: %s", ident.Value)
+			return newError(object.TYPE_ERROR, "cannot modify const variable: %s", ident.Value)
 		}
 	}
 
-	left := Eval(node.Left, env)
+	left := e.Eval(node.Left, env)
 	if isError(left) {
 		return left
 	}
 
-	index := Eval(node.Index, env)
+	index := e.Eval(node.Index, env)
 	if isError(index) {
 		return index
 	}
 
-	val := Eval(node.Value, env)
+	val := e.Eval(node.Value, env)
 	if isError(val) {
 		return val
 	}
@@ -1071,19 +1502,12 @@ func evalIndexCompoundAssignExpression(node *ast.IndexCompoundAssignExpression,
 	}
 
 	// 代入
-	switch obj := left.(type) {
-	case *object.Array:
-		return evalArrayIndexAssignment(obj, index, newVal)
-	case *object.Map:
-		return evalMapIndexAssignment(obj, index, newVal)
-	default:
-		return newError("index assignment not supported: %s", left.Type())
-	}
+	return IndexAssign(left, index, newVal)
 }
 
 // evalSliceExpression はスライス式を評価
-func evalSliceExpression(node *ast.SliceExpression, env *object.Environment) object.Object {
-	left := Eval(node.Left, env)
+func (e *Evaluator) evalSliceExpression(node *ast.SliceExpression, env *object.Environment) object.Object {
+	left := e.Eval(node.Left, env)
 	if isError(left) {
 		return left
 	}
@@ -1092,37 +1516,30 @@ func evalSliceExpression(node *ast.SliceExpression, env *object.Environment) obj
 	var hasLow, hasHigh bool
 
 	if node.Low != nil {
-		lowObj := Eval(node.Low, env)
+		lowObj := e.Eval(node.Low, env)
 		if isError(lowObj) {
 			return lowObj
 		}
-		if lowObj.Type() != object.NUMBER_OBJ {
-			return newError("slice index must be a number, got %s", lowObj.Type())
+		idx, errObj := SliceIndex(lowObj)
+		if errObj != nil {
+			return errObj
 		}
-		low = int64(lowObj.(*object.Number).Value)
-		hasLow = true
+		low, hasLow = idx, true
 	}
 
 	if node.High != nil {
-		highObj := Eval(node.High, env)
+		highObj := e.Eval(node.High, env)
 		if isError(highObj) {
 			return highObj
 		}
-		if highObj.Type() != object.NUMBER_OBJ {
-			return newError("slice index must be a number, got %s", highObj.Type())
+		idx, errObj := SliceIndex(highObj)
+		if errObj != nil {
+			return errObj
 		}
-		high = int64(highObj.(*object.Number).Value)
-		hasHigh = true
+		high, hasHigh = idx, true
 	}
 
-	switch obj := left.(type) {
-	case *object.Array:
-		return evalArraySlice(obj, low, high, hasLow, hasHigh)
-	case *object.String:
-		return evalStringSlice(obj, low, high, hasLow, hasHigh)
-	default:
-		return newError("slice operator not supported: %s", left.Type())
-	}
+	return SliceOperation(left, low, high, hasLow, hasHigh)
 }
 
 // evalArraySlice は配列のスライスを評価
@@ -1,6 +1,7 @@
 package evaluator
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"sugu/lexer"
@@ -75,6 +76,79 @@ func TestEvalBooleanExpression(t *testing.T) {
 	}
 }
 
+func TestStructuralEquality(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{`[1, 2] == [1, 2]`, true},
+		{`[1, 2] != [1, 2]`, false},
+		{`[1, 2] == [2, 1]`, false},
+		{`[1, 2] == [1, 2, 3]`, false},
+		{`[] == []`, true},
+		{`[[1, "a"], null] == [[1, "a"], null]`, true},
+		{`[1] == ["1"]`, false},
+		{`[1] == 1`, false},
+		// マップはキーの順序によらず比較する
+		{`{"a": 1, "b": [2]} == {"b": [2], "a": 1}`, true},
+		{`{"a": 1} == {"a": 1, "b": 2}`, false},
+		{`{"a": 1} == {"a": 2}`, false},
+		{`{1: "x"} == {"1": "x"}`, false},
+		{`{} == []`, false},
+		{`mut a = [1]; a == a`, true},
+		// 関数は同じオブジェクトの場合だけ等しい
+		{`const f = func() => { 1 }; f == f`, true},
+		{`[func() => { 1 }] == [func() => { 1 }]`, false},
+		// 自分自身を含む配列とマップ
+		{`mut a = [1]; a = push(a, null); a[1] = a; mut b = [1, null]; b[1] = b; a == b`, true},
+		{`mut a = {"v": 1}; a["self"] = a; mut b = {"v": 1}; b["self"] = b; a == b`, true},
+		{`mut a = {"v": 1}; a["self"] = a; mut b = {"v": 2}; b["self"] = b; a != b`, true},
+		{`mut a = [1, null]; a[1] = a; mut b = [1, [1, null]]; b[1][1] = b; a == b`, true},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if !testBooleanObject(t, evaluated, tt.expected) {
+			t.Errorf("input: %q", tt.input)
+		}
+	}
+}
+
+func TestSameBuiltin(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`same([1], [1])`, false},
+		{`const a = [1]; same(a, a)`, true},
+		{`const m = {"a": 1}; const n = m; same(m, n)`, true},
+		{`same({}, {})`, false},
+		// 配列とマップ以外は == と同じ
+		{`same(1, 1)`, true},
+		{`same("a", "a")`, true},
+		{`same(null, null)`, true},
+		{`same(1, "1")`, false},
+		{`same(1)`, "wrong number of arguments. got=1, want=2"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("input %q: no error object returned. got=%T(%+v)", tt.input, evaluated, evaluated)
+				continue
+			}
+			if errObj.Text() != expected {
+				t.Errorf("input %q: wrong error message. expected=%q, got=%q", tt.input, expected, errObj.Text())
+			}
+		}
+	}
+}
+
 func TestBangOperator(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -189,27 +263,27 @@ func TestErrorHandling(t *testing.T) {
 	}{
 		{
 			"5 + true;",
-			"type mismatch: NUMBER + BOOLEAN",
+			"line 1, column 3: type mismatch: NUMBER + BOOLEAN",
 		},
 		{
 			"5 + true; 5;",
-			"type mismatch: NUMBER + BOOLEAN",
+			"line 1, column 3: type mismatch: NUMBER + BOOLEAN",
 		},
 		{
 			"-true",
-			"unknown operator: -BOOLEAN",
+			"line 1, column 1: unknown operator: -BOOLEAN",
 		},
 		{
 			"true + false;",
-			"unknown operator: BOOLEAN + BOOLEAN",
+			"line 1, column 6: unknown operator: BOOLEAN + BOOLEAN",
 		},
 		{
 			"5; true + false; 5",
-			"unknown operator: BOOLEAN + BOOLEAN",
+			"line 1, column 9: unknown operator: BOOLEAN + BOOLEAN",
 		},
 		{
 			"if (10 > 1) { true + false; }",
-			"unknown operator: BOOLEAN + BOOLEAN",
+			"line 1, column 20: unknown operator: BOOLEAN + BOOLEAN",
 		},
 		{
 			"foobar",
@@ -217,11 +291,11 @@ func TestErrorHandling(t *testing.T) {
 		},
 		{
 			`"Hello" - "World"`,
-			"unknown operator: STRING - STRING",
+			"line 1, column 9: unknown operator: STRING - STRING",
 		},
 		{
 			"10 / 0",
-			"division by zero",
+			"line 1, column 4: division by zero",
 		},
 	}
 
@@ -369,6 +443,42 @@ sum;
 	testNumberObject(t, testEval(input), 25)
 }
 
+func TestLoopControlOutsideLoop(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"break;", "line 1, column 1: break outside loop"},
+		{"if (true) {\n  continue;\n}", "line 2, column 3: continue outside loop"},
+		{"while (true) { func() => { break; } }", "line 1, column 28: break outside loop"},
+		{"switch (1) { case 1: { continue; } }", "line 1, column 24: continue outside loop"},
+		// 関数の中の break は呼び出し元のループを止めず、実行前にエラーになる
+		{"mut n = 0; func f() => { break; } for (x in [1, 2, 3]) { f(); n++; }", "line 1, column 26: break outside loop"},
+		{"func f() => { continue; }", "line 1, column 15: continue outside loop"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("input %q: object is not Error. got=%T (%+v)", tt.input, evaluated, evaluated)
+			continue
+		}
+		if errObj.Message != tt.expected {
+			t.Errorf("input %q: wrong error message. expected=%q, got=%q", tt.input, tt.expected, errObj.Message)
+		}
+		if errObj.Kind != object.SYNTAX_ERROR {
+			t.Errorf("input %q: wrong error kind. expected=%q, got=%q", tt.input, object.SYNTAX_ERROR, errObj.Kind)
+		}
+	}
+
+	// ループや switch の中の break / continue はエラーにならない
+	input := `mut out = []; for (x in [1, 2, 3]) { switch (x) { case 2: { continue; } case 3: { break; } } out = push(out, x); } out`
+	if evaluated := testEval(input); evaluated.Inspect() != "[1, 3]" {
+		t.Errorf("input %q: wrong result. expected=%q, got=%q", input, "[1, 3]", evaluated.Inspect())
+	}
+}
+
 func TestForStatement(t *testing.T) {
 	input := `
 mut sum = 0;
@@ -414,6 +524,18 @@ switch (x) {
 }
 result;
 `, 99},
+		{`
+mut result = 0;
+switch ([1, {"a": 2}]) {
+  case [1, {"a": 3}]: {
+    result = 10;
+  }
+  case [1, {"a": 2}]: {
+    result = 20;
+  }
+}
+result;
+`, 20},
 	}
 
 	for _, tt := range tests {
@@ -504,8 +626,8 @@ func TestBuiltinLen(t *testing.T) {
 				t.Errorf("object is not Error. got=%T (%+v)", evaluated, evaluated)
 				continue
 			}
-			if errObj.Message != expected {
-				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			if errObj.Text() != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Text())
 			}
 		}
 	}
@@ -545,8 +667,8 @@ func TestBuiltinPush(t *testing.T) {
 				t.Errorf("object is not Error. got=%T (%+v)", evaluated, evaluated)
 				continue
 			}
-			if errObj.Message != expected {
-				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			if errObj.Text() != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Text())
 			}
 		}
 	}
@@ -596,8 +718,8 @@ func TestBuiltinFirstLastRest(t *testing.T) {
 				t.Errorf("object is not Error. got=%T (%+v)", evaluated, evaluated)
 				continue
 			}
-			if errObj.Message != expected {
-				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			if errObj.Text() != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Text())
 			}
 		}
 	}
@@ -638,8 +760,8 @@ func TestBuiltinKeysValuesErrors(t *testing.T) {
 			t.Errorf("object is not Error. got=%T (%+v)", evaluated, evaluated)
 			continue
 		}
-		if errObj.Message != tt.expected {
-			t.Errorf("wrong error message. expected=%q, got=%q", tt.expected, errObj.Message)
+		if errObj.Text() != tt.expected {
+			t.Errorf("wrong error message. expected=%q, got=%q", tt.expected, errObj.Text())
 		}
 	}
 }
@@ -647,12 +769,21 @@ func TestBuiltinKeysValuesErrors(t *testing.T) {
 // ヘルパー関数
 
 func testEval(input string) object.Object {
+	return testEvalWith(context.Background(), input, nil)
+}
+
+// testEvalWith は setup で設定した Evaluator で input を ctx のもとで評価する（setup が nil なら既定の設定）
+func testEvalWith(ctx context.Context, input string, setup func(*Evaluator)) object.Object {
 	l := lexer.New(input)
 	p := parser.New(l)
 	program := p.ParseProgram()
 	env := object.NewEnvironment()
 
-	return Eval(program, env)
+	ev := New("")
+	if setup != nil {
+		setup(ev)
+	}
+	return testEngine(ctx, ev, program, env)
 }
 
 func testNumberObject(t *testing.T, obj object.Object, expected float64) bool {
@@ -830,18 +961,18 @@ func TestMapLiterals(t *testing.T) {
 		t.Fatalf("Eval didn't return Map. got=%T (%+v)", evaluated, evaluated)
 	}
 
-	expected := map[object.HashKey]float64{
-		(&object.String{Value: "one"}).HashKey():   1,
-		(&object.String{Value: "two"}).HashKey():   2,
-		(&object.String{Value: "three"}).HashKey(): 3,
+	expected := map[*object.String]float64{
+		{Value: "one"}:   1,
+		{Value: "two"}:   2,
+		{Value: "three"}: 3,
 	}
 
-	if len(result.Pairs) != len(expected) {
-		t.Fatalf("Map has wrong num of pairs. got=%d", len(result.Pairs))
+	if result.Len() != len(expected) {
+		t.Fatalf("Map has wrong num of pairs. got=%d", result.Len())
 	}
 
 	for expectedKey, expectedValue := range expected {
-		pair, ok := result.Pairs[expectedKey]
+		pair, ok := result.Get(expectedKey)
 		if !ok {
 			t.Errorf("no pair for given key in Pairs")
 		}
@@ -922,8 +1053,8 @@ func TestMapUnhashableKey(t *testing.T) {
 	}
 
 	expectedMessage := "unusable as hash key: ARRAY"
-	if errObj.Message != expectedMessage {
-		t.Errorf("wrong error message. expected=%q, got=%q", expectedMessage, errObj.Message)
+	if errObj.Text() != expectedMessage {
+		t.Errorf("wrong error message. expected=%q, got=%q", expectedMessage, errObj.Text())
 	}
 }
 
@@ -1002,8 +1133,8 @@ func TestBuiltinPop(t *testing.T) {
 				t.Errorf("object is not Error. got=%T (%+v)", evaluated, evaluated)
 				continue
 			}
-			if errObj.Message != expected {
-				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			if errObj.Text() != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Text())
 			}
 		}
 	}
@@ -1051,8 +1182,8 @@ func TestModuloByZero(t *testing.T) {
 			t.Errorf("input %q: no error object returned. got=%T(%+v)", tt.input, evaluated, evaluated)
 			continue
 		}
-		if errObj.Message != tt.expected {
-			t.Errorf("input %q: wrong error message. expected=%q, got=%q", tt.input, tt.expected, errObj.Message)
+		if errObj.Text() != tt.expected {
+			t.Errorf("input %q: wrong error message. expected=%q, got=%q", tt.input, tt.expected, errObj.Text())
 		}
 	}
 }
@@ -1101,7 +1232,7 @@ func TestUnicodeStringIndex(t *testing.T) {
 }
 
 func TestMapInspectOrder(t *testing.T) {
-	// マップのInspect()が安定した順序を返すことを確認
+	// マップのInspect()が挿入順を返すことを確認
 	input := `{"b": 2, "a": 1, "c": 3}`
 
 	// 複数回実行しても同じ結果になることを確認
@@ -1113,13 +1244,61 @@ func TestMapInspectOrder(t *testing.T) {
 		}
 
 		inspect := result.Inspect()
-		expected := "{a: 1, b: 2, c: 3}"
+		expected := "{b: 2, a: 1, c: 3}"
 		if inspect != expected {
 			t.Errorf("iteration %d: Map.Inspect() wrong. got=%q, want=%q", i, inspect, expected)
 		}
 	}
 }
 
+func TestMapInsertionOrder(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`keys({"b": 2, "a": 1, 3: "c", true: 0})`, `[b, a, 3, true]`},
+		{`values({"b": 2, "a": 1, "c": 3})`, `[2, 1, 3]`},
+		// 既存のキーへの代入は順序を変えない
+		{`mut m = {"x": 1, "y": 2}; m["x"] = 10; m["z"] = 3; m`, `{x: 10, y: 2, z: 3}`},
+		// 削除したキーを再び追加すると末尾になる
+		{`mut m = {"x": 1, "y": 2, "z": 3}; delete(m, "x"); m["x"] = 4; keys(m)`, `[y, z, x]`},
+		{`mut m = {"a": 1, "b": 2}; mut s = ""; for (k, v in m) { s = s + k + string(v); } s`, `a1b2`},
+		// 反復中の追加と削除は反復に影響しない
+		{`mut m = {"a": 1, "b": 2}; mut s = ""; for (k in m) { delete(m, "b"); m["c"] = 3; s = s + k; } s + string(keys(m))`, `ab[a, c]`},
+		{`mut m = {}; for (mut i = 0; i < 100; i++) { m[i] = i; } for (mut i = 0; i < 99; i++) { delete(m, i); } m["x"] = 0; m`, `{99: 99, x: 0}`},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("input %q: wrong result. expected=%q, got=%q", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestMapKeyEquality(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		// "Aa" と "BB" は同じ HashKey になるが別のキー
+		{`{"Aa": 1, "BB": 2}`, `{Aa: 1, BB: 2}`},
+		{`mut m = {"Aa": 1}; m["BB"] = 2; m["Aa"] + m["BB"]`, `3`},
+		{`mut m = {"AaAa": 1, "BBBB": 2}; delete(m, "AaAa"); string(m["AaAa"]) + " " + string(m["BBBB"])`, `null 2`},
+		// 0 と -0 は同じキー
+		{`mut m = {0: "zero"}; m[-0]`, `zero`},
+		{`mut m = {0: "a"}; m[-0] = "b"; m`, `{0: b}`},
+		{`{1: "number", "1": "string", true: "bool"}`, `{1: number, 1: string, true: bool}`},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("input %q: wrong result. expected=%q, got=%q", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
 func TestArrayIndexAssignment(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -1152,8 +1331,8 @@ func TestArrayIndexAssignment(t *testing.T) {
 				t.Errorf("input %q: object is not Error. got=%T (%+v)", tt.input, evaluated, evaluated)
 				continue
 			}
-			if errObj.Message != expected {
-				t.Errorf("input %q: wrong error message. expected=%q, got=%q", tt.input, expected, errObj.Message)
+			if errObj.Text() != expected {
+				t.Errorf("input %q: wrong error message. expected=%q, got=%q", tt.input, expected, errObj.Text())
 			}
 		}
 	}
@@ -1212,8 +1391,8 @@ func TestIndexAssignmentToImmutableString(t *testing.T) {
 	}
 
 	expected := "index assignment not supported: STRING"
-	if errObj.Message != expected {
-		t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+	if errObj.Text() != expected {
+		t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Text())
 	}
 }
 
@@ -1237,8 +1416,47 @@ func TestIndexAssignmentToConstVariable(t *testing.T) {
 			t.Errorf("input %q: no error object returned. got=%T (%+v)", tt.input, evaluated, evaluated)
 			continue
 		}
+		if errObj.Text() != tt.expected {
+			t.Errorf("input %q: wrong error message. expected=%q, got=%q", tt.input, tt.expected, errObj.Text())
+		}
+	}
+}
+
+func TestRuntimeErrorPositions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		// 演算子
+		{`mut x = -"a";`, "line 1, column 9: unknown operator: -STRING"},
+		{"mut a = 1;\nmut b = a * \"x\";", "line 2, column 11: type mismatch: NUMBER * STRING"},
+		{`[1, 2] + 3`, "line 1, column 8: type mismatch: ARRAY + NUMBER"},
+		// インデックス
+		{`[1, 2][true]`, "line 1, column 7: index operator not supported: ARRAY"},
+		{`mut a = [1]; a[5] = 1;`, "line 1, column 19: array index out of bounds: 5 (length: 1)"},
+		{`mut a = [1]; a[0] += "x";`, "line 1, column 19: type mismatch: NUMBER + STRING"},
+		{`"abc"[true:]`, "line 1, column 6: slice index must be a number, got BOOLEAN"},
+		{`mut m = {[1]: 2};`, "line 1, column 9: unusable as hash key: ARRAY"},
+		// 代入と後置演算子
+		{`mut s = "a"; s++;`, "line 1, column 15: postfix operator ++ not supported: STRING"},
+		{`mut s = [1]; s -= 1;`, "line 1, column 16: type mismatch: ARRAY - NUMBER"},
+		// 組み込み関数と関数呼び出しは呼び出しの位置
+		{`mut n = len(1);`, "line 1, column 12: argument to `len` not supported, got NUMBER"},
+		{`mut n = 5; n(1);`, "line 1, column 13: not a function: NUMBER"},
+		{"func f() => {\n  return int(\"x\");\n}\nf();", "line 2, column 13: cannot convert \"x\" to int"},
+		// for-in
+		{`for (x in 1) {}`, "line 1, column 1: for-in requires ARRAY or MAP, got NUMBER"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("no error object returned for %q. got=%T(%+v)", tt.input, evaluated, evaluated)
+			continue
+		}
 		if errObj.Message != tt.expected {
-			t.Errorf("input %q: wrong error message. expected=%q, got=%q", tt.input, tt.expected, errObj.Message)
+			t.Errorf("wrong error message for %q. expected=%q, got=%q", tt.input, tt.expected, errObj.Message)
 		}
 	}
 }
@@ -1335,12 +1553,158 @@ func TestTryCatchWithBuiltinError(t *testing.T) {
 		t.Fatalf("object is not String. got=%T (%+v)", evaluated, evaluated)
 	}
 
-	expected := "caught: division by zero"
+	expected := "caught: line 1, column 10: division by zero"
 	if str.Value != expected {
 		t.Errorf("wrong value. expected=%q, got=%q", expected, str.Value)
 	}
 }
 
+func TestTryFinallyStatement(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		// 正常終了・throw・エラーのいずれでも finally を実行する
+		{`mut log = ""; try { log += "t"; } finally { log += "f"; } log`, "tf"},
+		{`mut log = ""; try { throw "x"; } catch (e) { log += "c"; } finally { log += "f"; } log`, "cf"},
+		{`mut log = ""; try { try { throw "x"; } finally { log += "f"; } } catch (e) { log += e; } log`, "fx"},
+		{`mut log = ""; try { try { 10 / 0 } finally { log += "f"; } } catch (e) { log += e["kind"]; } log`, "fDivisionByZero"},
+		{`mut log = ""; try { try { throw "a"; } catch (e) { throw "b"; } finally { log += "f"; } } catch (e) { log += e; } log`, "fb"},
+		// 文の値は try（または catch）ブロックの値
+		{`try { 1 } finally { 2 }`, 1},
+		{`try { throw 1; } catch (e) { 5 } finally { 2 }`, 5},
+		// return で抜ける場合も finally を実行する
+		{`mut log = ""; func f() => { try { return "r"; } finally { log += "f"; } } f() + log`, "rf"},
+		{`mut log = ""; func f() => { try { try { return 1; } finally { log += "a"; } } finally { log += "b"; } } f(); log`, "ab"},
+		{`mut i = "outer"; func f() => { try { for (mut i = 0; i < 3; i++) { return i; } } finally { i = "changed"; } } f(); i`, "changed"},
+		// break / continue で抜ける場合も finally を実行する
+		{`mut log = ""; for (x in [1, 2, 3]) { try { if (x == 2) { break; } log += "b"; } finally { log += "f"; } } log`, "bff"},
+		{`mut i = 0; mut log = ""; while (i < 3) { i++; try { if (i == 2) { continue; } log += "b"; } finally { log += "f"; } } log`, "bffbf"},
+		// finally ブロックの return / break / throw は元の結果より優先される
+		{`func f() => { try { return 1; } finally { return 2; } } f()`, 2},
+		{`func f() => { try { throw "x"; } finally { return "ok"; } } f()`, "ok"},
+		{`try { try { throw "a"; } finally { throw "b"; } } catch (e) { e }`, "b"},
+		{`mut s = 0; for (x in [1, 2, 3]) { try { s += x; } finally { if (x == 2) { break; } } } s`, 3},
+		{`for (x in [1]) { try { throw "x"; } finally { break; } } "done"`, "done"},
+		{`func f() => { for (x in [1, 2]) { try { return "r"; } finally { break; } } return "after"; } f()`, "after"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testNumberObject(t, evaluated, float64(expected))
+		case string:
+			str, ok := evaluated.(*object.String)
+			if !ok {
+				t.Errorf("object is not String for %q. got=%T (%+v)", tt.input, evaluated, evaluated)
+				continue
+			}
+			if str.Value != expected {
+				t.Errorf("wrong value for %q. expected=%q, got=%q", tt.input, expected, str.Value)
+			}
+		}
+	}
+}
+
+func TestCaughtErrorObject(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		// エラーの種類
+		{`try { 10 / 0 } catch (e) { e["kind"] }`, "DivisionByZero"},
+		{`try { len(1) } catch (e) { e["kind"] }`, "TypeError"},
+		{`try { len() } catch (e) { e["kind"] }`, "ArgumentError"},
+		{`try { int("abc") } catch (e) { e["kind"] }`, "ValueError"},
+		{`try { readFile("/nonexistent/dir/file.txt") } catch (e) { e["kind"] }`, "IOError"},
+		{`mut a = [1]; try { a[5] = 1; } catch (e) { e["kind"] }`, "IndexError"},
+		{`try { missing } catch (e) { e["kind"] }`, "ReferenceError"},
+		{`const c = 1; try { c = 2; } catch (e) { e["kind"] }`, "TypeError"},
+		// メッセージと位置
+		{`try { 10 / 0 } catch (e) { e["message"] }`, "division by zero"},
+		{`try { missing } catch (e) { e["message"] }`, "identifier not found: missing"},
+		{"try {\n  missing;\n} catch (e) { e[\"line\"] }", 2},
+		{"try {\n  missing;\n} catch (e) { e[\"column\"] }", 3},
+		{`try { 10 / 0 } catch (e) { e["file"] }`, nil},
+		{`try { missing } catch (e) { e["unknown"] }`, nil},
+		// 呼び出し履歴
+		{`func f() => { missing; } try { f(); } catch (e) { len(e["stack"]) }`, 1},
+		{`func f() => { missing; } try { f(); } catch (e) { e["stack"][0]["function"] }`, "f"},
+		{`func f() => { missing; } try { f(); } catch (e) { e["stack"][0]["column"] }`, 33},
+		{`try { missing } catch (e) { len(e["stack"]) }`, 0},
+		// 文字列との連結や表示では位置付きのメッセージになる
+		{`try { missing } catch (e) { "Error: " + e }`, "Error: line 1, column 7: identifier not found: missing"},
+		{`try { missing } catch (e) { type(e) }`, "EXCEPTION"},
+		// 文字列との比較では位置情報を含まないメッセージで比較する
+		{`try { 10 / 0 } catch (e) { e == "division by zero" }`, true},
+		{`try { 10 / 0 } catch (e) { "division by zero" == e }`, true},
+		{`try { 10 / 0 } catch (e) { e != "division by zero" }`, false},
+		{`try { 10 / 0 } catch (e) { e == "line 1, column 10: division by zero" }`, false},
+		{`try { 10 / 0 } catch (e) { contains(["division by zero"], e) }`, true},
+		{`try { throw error("oops"); } catch (e) { e == "oops" }`, true},
+		// error() で作ったエラーを throw する
+		{`try { throw error("bad input", "ValueError"); } catch (e) { e["kind"] + ": " + e["message"] }`, "ValueError: bad input"},
+		{`error("oops")["kind"]`, "Error"},
+		{"try {\n  throw error(\"oops\");\n} catch (e) { e[\"line\"] }", 2},
+		{`func f() => { throw error("oops"); } try { f(); } catch (e) { e["stack"][0]["function"] }`, "f"},
+		// 投げ直したエラーは元の情報を保つ
+		{`func f() => { try { 10 / 0 } catch (e) { throw e; } } try { f(); } catch (e) { e["kind"] }`, "DivisionByZero"},
+		{"func f() => {\n  try { missing; } catch (e) { throw e; }\n}\ntry { f(); } catch (e) { e[\"line\"] }", 2},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testNumberObject(t, evaluated, float64(expected))
+		case string:
+			str, ok := evaluated.(*object.String)
+			if !ok {
+				t.Errorf("object is not String for %q. got=%T (%+v)", tt.input, evaluated, evaluated)
+				continue
+			}
+			if str.Value != expected {
+				t.Errorf("wrong value for %q. expected=%q, got=%q", tt.input, expected, str.Value)
+			}
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		case nil:
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
+func TestUncaughtErrorObject(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+		kind     string
+	}{
+		{`throw error("bad input", "ValueError");`, "line 1, column 1: bad input", "ValueError"},
+		{"try { missing; } catch (e) {\n  throw e;\n}", "line 1, column 7: identifier not found: missing", "ReferenceError"},
+		{`throw "plain";`, "uncaught exception: plain", "Error"},
+		// finally の後も元のエラーのまま
+		{"try { missing; } finally { 1; }", "line 1, column 7: identifier not found: missing", "ReferenceError"},
+	}
+
+	for _, tt := range tests {
+		errObj, ok := testEval(tt.input).(*object.Error)
+		if !ok {
+			t.Errorf("no error object returned for %q", tt.input)
+			continue
+		}
+		if errObj.Message != tt.expected {
+			t.Errorf("wrong error message for %q. expected=%q, got=%q", tt.input, tt.expected, errObj.Message)
+		}
+		if errObj.Kind != tt.kind {
+			t.Errorf("wrong error kind for %q. expected=%q, got=%q", tt.input, tt.kind, errObj.Kind)
+		}
+	}
+}
+
 func TestBuiltinInt(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -1377,8 +1741,8 @@ func TestBuiltinInt(t *testing.T) {
 				t.Errorf("input %q: object is not Error. got=%T (%+v)", tt.input, evaluated, evaluated)
 				continue
 			}
-			if errObj.Message != expected {
-				t.Errorf("input %q: wrong error message. expected=%q, got=%q", tt.input, expected, errObj.Message)
+			if errObj.Text() != expected {
+				t.Errorf("input %q: wrong error message. expected=%q, got=%q", tt.input, expected, errObj.Text())
 			}
 		}
 	}
@@ -1416,8 +1780,8 @@ func TestBuiltinFloat(t *testing.T) {
 				t.Errorf("input %q: object is not Error. got=%T (%+v)", tt.input, evaluated, evaluated)
 				continue
 			}
-			if errObj.Message != expected {
-				t.Errorf("input %q: wrong error message. expected=%q, got=%q", tt.input, expected, errObj.Message)
+			if errObj.Text() != expected {
+				t.Errorf("input %q: wrong error message. expected=%q, got=%q", tt.input, expected, errObj.Text())
 			}
 		}
 	}
@@ -1696,8 +2060,8 @@ func TestForInTypeError(t *testing.T) {
 			t.Errorf("input %q: no error. got=%T(%+v)", tt.input, evaluated, evaluated)
 			continue
 		}
-		if errObj.Message != tt.expected {
-			t.Errorf("input %q: wrong error. expected=%q, got=%q", tt.input, tt.expected, errObj.Message)
+		if errObj.Text() != tt.expected {
+			t.Errorf("input %q: wrong error. expected=%q, got=%q", tt.input, tt.expected, errObj.Text())
 		}
 	}
 }
@@ -1728,8 +2092,8 @@ func TestAbsBuiltin(t *testing.T) {
 				t.Errorf("input %q: no error. got=%T(%+v)", tt.input, evaluated, evaluated)
 				continue
 			}
-			if errObj.Message != expected {
-				t.Errorf("input %q: wrong error. expected=%q, got=%q", tt.input, expected, errObj.Message)
+			if errObj.Text() != expected {
+				t.Errorf("input %q: wrong error. expected=%q, got=%q", tt.input, expected, errObj.Text())
 			}
 		}
 	}
@@ -1790,8 +2154,8 @@ func TestFloorCeilRoundBuiltin(t *testing.T) {
 			t.Errorf("input %q: no error. got=%T(%+v)", tt.input, evaluated, evaluated)
 			continue
 		}
-		if errObj.Message != tt.expected {
-			t.Errorf("input %q: wrong error. expected=%q, got=%q", tt.input, tt.expected, errObj.Message)
+		if errObj.Text() != tt.expected {
+			t.Errorf("input %q: wrong error. expected=%q, got=%q", tt.input, tt.expected, errObj.Text())
 		}
 	}
 }
@@ -1822,8 +2186,8 @@ func TestSqrtBuiltin(t *testing.T) {
 				t.Errorf("input %q: no error. got=%T(%+v)", tt.input, evaluated, evaluated)
 				continue
 			}
-			if errObj.Message != expected {
-				t.Errorf("input %q: wrong error. expected=%q, got=%q", tt.input, expected, errObj.Message)
+			if errObj.Text() != expected {
+				t.Errorf("input %q: wrong error. expected=%q, got=%q", tt.input, expected, errObj.Text())
 			}
 		}
 	}
@@ -1856,8 +2220,8 @@ func TestPowBuiltin(t *testing.T) {
 				t.Errorf("input %q: no error. got=%T(%+v)", tt.input, evaluated, evaluated)
 				continue
 			}
-			if errObj.Message != expected {
-				t.Errorf("input %q: wrong error. expected=%q, got=%q", tt.input, expected, errObj.Message)
+			if errObj.Text() != expected {
+				t.Errorf("input %q: wrong error. expected=%q, got=%q", tt.input, expected, errObj.Text())
 			}
 		}
 	}
@@ -1898,8 +2262,8 @@ func TestMinMaxBuiltin(t *testing.T) {
 				t.Errorf("input %q: no error. got=%T(%+v)", tt.input, evaluated, evaluated)
 				continue
 			}
-			if errObj.Message != expected {
-				t.Errorf("input %q: wrong error. expected=%q, got=%q", tt.input, expected, errObj.Message)
+			if errObj.Text() != expected {
+				t.Errorf("input %q: wrong error. expected=%q, got=%q", tt.input, expected, errObj.Text())
 			}
 		}
 	}
@@ -1924,8 +2288,8 @@ func TestRandomBuiltin(t *testing.T) {
 	if !ok {
 		t.Fatalf("no error object returned. got=%T(%+v)", evaluated, evaluated)
 	}
-	if errObj.Message != "wrong number of arguments. got=1, want=0" {
-		t.Errorf("wrong error message. got=%q", errObj.Message)
+	if errObj.Text() != "wrong number of arguments. got=1, want=0" {
+		t.Errorf("wrong error message. got=%q", errObj.Text())
 	}
 }
 
@@ -1997,8 +2361,8 @@ func TestDeleteBuiltinErrors(t *testing.T) {
 			t.Errorf("input %q: no error object returned. got=%T(%+v)", tt.input, evaluated, evaluated)
 			continue
 		}
-		if errObj.Message != tt.expected {
-			t.Errorf("input %q: wrong error message. expected=%q, got=%q", tt.input, tt.expected, errObj.Message)
+		if errObj.Text() != tt.expected {
+			t.Errorf("input %q: wrong error message. expected=%q, got=%q", tt.input, tt.expected, errObj.Text())
 		}
 	}
 }
@@ -2056,8 +2420,8 @@ func TestSplitBuiltin(t *testing.T) {
 			t.Errorf("input %q: no error. got=%T(%+v)", tt.input, evaluated, evaluated)
 			continue
 		}
-		if errObj.Message != tt.expected {
-			t.Errorf("input %q: wrong error. expected=%q, got=%q", tt.input, tt.expected, errObj.Message)
+		if errObj.Text() != tt.expected {
+			t.Errorf("input %q: wrong error. expected=%q, got=%q", tt.input, tt.expected, errObj.Text())
 		}
 	}
 }
@@ -2104,8 +2468,8 @@ func TestJoinBuiltin(t *testing.T) {
 			t.Errorf("input %q: no error. got=%T(%+v)", tt.input, evaluated, evaluated)
 			continue
 		}
-		if errObj.Message != tt.expected {
-			t.Errorf("input %q: wrong error. expected=%q, got=%q", tt.input, tt.expected, errObj.Message)
+		if errObj.Text() != tt.expected {
+			t.Errorf("input %q: wrong error. expected=%q, got=%q", tt.input, tt.expected, errObj.Text())
 		}
 	}
 }
@@ -2152,8 +2516,8 @@ func TestTrimBuiltin(t *testing.T) {
 			t.Errorf("input %q: no error. got=%T(%+v)", tt.input, evaluated, evaluated)
 			continue
 		}
-		if errObj.Message != tt.expected {
-			t.Errorf("input %q: wrong error. expected=%q, got=%q", tt.input, tt.expected, errObj.Message)
+		if errObj.Text() != tt.expected {
+			t.Errorf("input %q: wrong error. expected=%q, got=%q", tt.input, tt.expected, errObj.Text())
 		}
 	}
 }
@@ -2207,8 +2571,8 @@ func TestSubstringBuiltin(t *testing.T) {
 			t.Errorf("input %q: no error. got=%T(%+v)", tt.input, evaluated, evaluated)
 			continue
 		}
-		if errObj.Message != tt.expected {
-			t.Errorf("input %q: wrong error. expected=%q, got=%q", tt.input, tt.expected, errObj.Message)
+		if errObj.Text() != tt.expected {
+			t.Errorf("input %q: wrong error. expected=%q, got=%q", tt.input, tt.expected, errObj.Text())
 		}
 	}
 }
@@ -2253,8 +2617,8 @@ func TestIndexOfBuiltin(t *testing.T) {
 			t.Errorf("input %q: no error. got=%T(%+v)", tt.input, evaluated, evaluated)
 			continue
 		}
-		if errObj.Message != tt.expected {
-			t.Errorf("input %q: wrong error. expected=%q, got=%q", tt.input, tt.expected, errObj.Message)
+		if errObj.Text() != tt.expected {
+			t.Errorf("input %q: wrong error. expected=%q, got=%q", tt.input, tt.expected, errObj.Text())
 		}
 	}
 }
@@ -2301,8 +2665,8 @@ func TestToUpperBuiltin(t *testing.T) {
 			t.Errorf("input %q: no error. got=%T(%+v)", tt.input, evaluated, evaluated)
 			continue
 		}
-		if errObj.Message != tt.expected {
-			t.Errorf("input %q: wrong error. expected=%q, got=%q", tt.input, tt.expected, errObj.Message)
+		if errObj.Text() != tt.expected {
+			t.Errorf("input %q: wrong error. expected=%q, got=%q", tt.input, tt.expected, errObj.Text())
 		}
 	}
 }
@@ -2349,8 +2713,8 @@ func TestToLowerBuiltin(t *testing.T) {
 			t.Errorf("input %q: no error. got=%T(%+v)", tt.input, evaluated, evaluated)
 			continue
 		}
-		if errObj.Message != tt.expected {
-			t.Errorf("input %q: wrong error. expected=%q, got=%q", tt.input, tt.expected, errObj.Message)
+		if errObj.Text() != tt.expected {
+			t.Errorf("input %q: wrong error. expected=%q, got=%q", tt.input, tt.expected, errObj.Text())
 		}
 	}
 }
@@ -2421,8 +2785,8 @@ func TestPostfixErrors(t *testing.T) {
 			t.Errorf("input %q: no error object returned. got=%T(%+v)", tt.input, evaluated, evaluated)
 			continue
 		}
-		if errObj.Message != tt.expected {
-			t.Errorf("input %q: wrong error message. expected=%q, got=%q", tt.input, tt.expected, errObj.Message)
+		if errObj.Text() != tt.expected {
+			t.Errorf("input %q: wrong error message. expected=%q, got=%q", tt.input, tt.expected, errObj.Text())
 		}
 	}
 }
@@ -2494,8 +2858,8 @@ func TestCompoundAssignmentErrors(t *testing.T) {
 			t.Errorf("input %q: no error object returned. got=%T(%+v)", tt.input, evaluated, evaluated)
 			continue
 		}
-		if errObj.Message != tt.expected {
-			t.Errorf("input %q: wrong error message. expected=%q, got=%q", tt.input, tt.expected, errObj.Message)
+		if errObj.Text() != tt.expected {
+			t.Errorf("input %q: wrong error message. expected=%q, got=%q", tt.input, tt.expected, errObj.Text())
 		}
 	}
 }
@@ -2681,8 +3045,8 @@ func TestSliceErrors(t *testing.T) {
 			t.Errorf("input %q: no error object returned. got=%T(%+v)", tt.input, evaluated, evaluated)
 			continue
 		}
-		if errObj.Message != tt.expected {
-			t.Errorf("input %q: wrong error message. expected=%q, got=%q", tt.input, tt.expected, errObj.Message)
+		if errObj.Text() != tt.expected {
+			t.Errorf("input %q: wrong error message. expected=%q, got=%q", tt.input, tt.expected, errObj.Text())
 		}
 	}
 }
@@ -2703,6 +3067,10 @@ func TestContainsBuiltin(t *testing.T) {
 		{`contains(["a", "b", "c"], "d")`, false},
 		// 配列: 型が異なる
 		{`contains([1, 2, 3], "1")`, false},
+		// 配列: 要素の配列とマップは中身で比較する
+		{`contains([[1, 2], [3]], [3])`, true},
+		{`contains([{"a": 1}], {"a": 1})`, true},
+		{`contains([{"a": 1}], {"a": 2})`, false},
 		// 文字列: 部分文字列が存在する
 		{`contains("hello world", "world")`, true},
 		{`contains("hello world", "hello")`, true},
@@ -2733,8 +3101,8 @@ func TestContainsBuiltin(t *testing.T) {
 				t.Errorf("input %q: no error object returned. got=%T(%+v)", tt.input, evaluated, evaluated)
 				continue
 			}
-			if errObj.Message != expected {
-				t.Errorf("input %q: wrong error message. expected=%q, got=%q", tt.input, expected, errObj.Message)
+			if errObj.Text() != expected {
+				t.Errorf("input %q: wrong error message. expected=%q, got=%q", tt.input, expected, errObj.Text())
 			}
 		}
 	}
@@ -2805,8 +3173,8 @@ func TestConcatBuiltinErrors(t *testing.T) {
 			t.Errorf("input %q: no error object returned. got=%T(%+v)", tt.input, evaluated, evaluated)
 			continue
 		}
-		if errObj.Message != tt.expected {
-			t.Errorf("input %q: wrong error message. expected=%q, got=%q", tt.input, tt.expected, errObj.Message)
+		if errObj.Text() != tt.expected {
+			t.Errorf("input %q: wrong error message. expected=%q, got=%q", tt.input, tt.expected, errObj.Text())
 		}
 	}
 }
@@ -2887,8 +3255,183 @@ func TestReplaceBuiltin(t *testing.T) {
 			t.Errorf("input %q: no error. got=%T(%+v)", tt.input, evaluated, evaluated)
 			continue
 		}
-		if errObj.Message != tt.expected {
-			t.Errorf("input %q: wrong error. expected=%q, got=%q", tt.input, tt.expected, errObj.Message)
+		if errObj.Text() != tt.expected {
+			t.Errorf("input %q: wrong error. expected=%q, got=%q", tt.input, tt.expected, errObj.Text())
+		}
+	}
+}
+
+func TestErrorStackTrace(t *testing.T) {
+	tests := []struct {
+		input    string
+		message  string
+		expected []string
+	}{
+		{
+			"func inner() => {\n  return missing;\n}\nfunc outer() => {\n  return inner();\n}\nouter();",
+			"line 2, column 10: identifier not found: missing",
+			[]string{"inner (line 5, column 15)", "outer (line 7, column 6)"},
+		},
+		{
+			"const f = func() => { throw \"boom\"; };\nf();",
+			"uncaught exception: boom",
+			[]string{"<anonymous> (line 2, column 2)"},
+		},
+		{
+			"func check(x) => { if (x > 2) { return len(1); } return check(x + 1); }\ncheck(0);",
+			"line 1, column 43: argument to `len` not supported, got NUMBER",
+			[]string{
+				"check (line 1, column 62)",
+				"check (line 1, column 62)",
+				"check (line 1, column 62)",
+				"check (line 2, column 6)",
+			},
+		},
+		// catch したエラーを投げ直すと、元のエラーの位置と呼び出し履歴が残る
+		{
+			"func fail() => { missing; }\nfunc rethrow() => { try { fail(); } catch (e) { throw e; } }\nrethrow();",
+			"line 1, column 18: identifier not found: missing",
+			[]string{"fail (line 2, column 31)", "rethrow (line 3, column 8)"},
+		},
+		// トップレベルのエラーは呼び出し履歴を持たない
+		{"missing;", "line 1, column 1: identifier not found: missing", nil},
+	}
+
+	for _, tt := range tests {
+		result := testEval(tt.input)
+		errObj, ok := result.(*object.Error)
+		if !ok {
+			t.Errorf("no error object returned for %q. got=%T(%+v)", tt.input, result, result)
+			continue
+		}
+		if errObj.Message != tt.message {
+			t.Errorf("wrong error message for %q. expected=%q, got=%q", tt.input, tt.message, errObj.Message)
+		}
+
+		frames := []string{}
+		for _, frame := range errObj.Stack {
+			frames = append(frames, frame.String())
+		}
+		if fmt.Sprint(frames) != fmt.Sprint(tt.expected) {
+			t.Errorf("wrong stack for %q.\nexpected=%q\ngot=%q", tt.input, tt.expected, frames)
+		}
+	}
+}
+
+func TestJsonParseBuiltin(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`jsonParse("42")`, "42"},
+		{`jsonParse("-1.5e2")`, "-150"},
+		{`jsonParse("\"hi\\nthere\"")`, "hi\nthere"},
+		{`jsonParse("[1, \"a\", true, null]")`, "[1, a, true, null]"},
+		{`jsonParse("{\"name\": \"Alice\", \"tags\": [\"x\"]}")["tags"][0]`, "x"},
+		{`jsonParse("{\"user\": {\"age\": 30}}")["user"]["age"] + 1`, "31"},
+		{`type(jsonParse("{}"))`, "MAP"},
+		{`len(jsonParse(" [ ] "))`, "0"},
+		// false と null は偽として扱われる
+		{`if (jsonParse("false") || jsonParse("null")) { "truthy" } else { "falsy" }`, "falsy"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if isError(evaluated) {
+			t.Errorf("input %q: unexpected error: %s", tt.input, evaluated.Inspect())
+			continue
+		}
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("input %q: wrong value. expected=%q, got=%q", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+
+	errTests := []struct {
+		input    string
+		kind     string
+		expected string
+	}{
+		{`jsonParse("{\"a\": }")`, object.VALUE_ERROR, "invalid JSON at offset 6: invalid character '}' looking for beginning of value"},
+		{`jsonParse("[1, 2")`, object.VALUE_ERROR, "invalid JSON at offset 5: unexpected end of JSON input"},
+		{`jsonParse("")`, object.VALUE_ERROR, "invalid JSON at offset 0: unexpected end of JSON input"},
+		{`jsonParse("1 2")`, object.VALUE_ERROR, "invalid JSON at offset 2: invalid character '2' after top-level value"},
+		{`jsonParse(1)`, object.TYPE_ERROR, "argument to `jsonParse` must be STRING, got NUMBER"},
+		{`jsonParse()`, object.ARGUMENT_ERROR, "wrong number of arguments. got=0, want=1"},
+		{`try { jsonParse("nope") } catch (e) { throw e["kind"] }`, object.GENERIC_ERROR, "uncaught exception: ValueError"},
+	}
+	for _, tt := range errTests {
+		evaluated := testEval(tt.input)
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("input %q: no error. got=%T(%+v)", tt.input, evaluated, evaluated)
+			continue
+		}
+		if errObj.Kind != tt.kind || errObj.Text() != tt.expected {
+			t.Errorf("input %q: wrong error. expected=%s %q, got=%s %q", tt.input, tt.kind, tt.expected, errObj.Kind, errObj.Text())
 		}
 	}
 }
+
+func TestJsonStringifyBuiltin(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`jsonStringify(null)`, "null"},
+		{`jsonStringify(true)`, "true"},
+		{`jsonStringify(42)`, "42"},
+		{`jsonStringify(0.1 + 0.2)`, "0.30000000000000004"},
+		{`jsonStringify(1 / 3)`, "0.3333333333333333"},
+		{`jsonStringify("a\"b<c>")`, `"a\"b<c>"`},
+		{`jsonStringify([1, "two", [3], {}])`, `[1,"two",[3],{}]`},
+		{`jsonStringify({"b": 1, "a": [true]})`, `{"b":1,"a":[true]}`},
+		{`jsonStringify({1: "one", true: "yes"})`, `{"1":"one","true":"yes"}`},
+		{`jsonStringify({"a": [1, {"b": null}], "c": []}, 2)`, "{\n  \"a\": [\n    1,\n    {\n      \"b\": null\n    }\n  ],\n  \"c\": []\n}"},
+		{`jsonStringify([1], "\t")`, "[\n\t1\n]"},
+		{`jsonStringify([1], 0)`, "[1]"},
+		// jsonParse と往復できる
+		{`jsonStringify(jsonParse("{\"x\": [1.5, \"y\"]}"))`, `{"x":[1.5,"y"]}`},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		str, ok := evaluated.(*object.String)
+		if !ok {
+			t.Errorf("input %q: object is not String. got=%T (%+v)", tt.input, evaluated, evaluated)
+			continue
+		}
+		if str.Value != tt.expected {
+			t.Errorf("input %q: wrong value. expected=%q, got=%q", tt.input, tt.expected, str.Value)
+		}
+	}
+
+	errTests := []struct {
+		input    string
+		expected string
+	}{
+		{`mut a = [1]; a[0] = a; jsonStringify(a)`, "[0]: cannot convert cyclic ARRAY to JSON"},
+		{`mut m = {"self": null}; m["self"] = {"inner": m}; jsonStringify(m)`, "[self][inner]: cannot convert cyclic MAP to JSON"},
+		{`jsonStringify({"f": func(x) => { x }})`, "[f]: cannot convert FUNCTION to JSON"},
+		{`jsonStringify({1: "a", "1": "b"})`, `duplicate key "1" after converting keys to strings`},
+		{`jsonStringify(1, 11)`, "indent must be an integer between 0 and 10, got 11"},
+		{`jsonStringify(1, true)`, "second argument to `jsonStringify` must be NUMBER or STRING, got BOOLEAN"},
+		{`jsonStringify()`, "wrong number of arguments. got=0, want=1 or 2"},
+	}
+	for _, tt := range errTests {
+		evaluated := testEval(tt.input)
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("input %q: no error. got=%T(%+v)", tt.input, evaluated, evaluated)
+			continue
+		}
+		if errObj.Text() != tt.expected {
+			t.Errorf("input %q: wrong error. expected=%q, got=%q", tt.input, tt.expected, errObj.Text())
+		}
+	}
+
+	// 同じ配列を複数回含むのは循環ではない
+	evaluated := testEval(`const shared = [1]; jsonStringify([shared, shared])`)
+	if evaluated.Inspect() != "[[1],[1]]" {
+		t.Errorf("shared array should be stringified twice. got=%q", evaluated.Inspect())
+	}
+}
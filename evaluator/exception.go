@@ -0,0 +1,106 @@
+package evaluator
+
+import "sugu/object"
+
+// このファイルは catch で受け取るエラー（object.Exception）の扱いをまとめる
+// 木構造評価器と VM バックエンドで共有する
+
+// ThrowException は throw された Exception に throw した位置と呼び出し履歴を記録する
+// error() で作った Exception のように位置を持たない場合だけ記録し、
+// catch したエラーを投げ直した場合は元の位置と呼び出し履歴をそのまま残す
+func (e *Evaluator) ThrowException(exc *object.Exception, file string, line, column int, stack []object.StackFrame) {
+	if exc.Line == 0 {
+		exc.File, exc.Line, exc.Column = file, line, column
+		exc.Source = e.SourceLine(file, line)
+	}
+	if exc.Stack == nil {
+		exc.Stack = stack
+	}
+}
+
+// UncaughtError はキャッチされなかった throw の値をプログラムを終了させるエラーに変換する
+// Exception は元のエラーに戻し、それ以外の値は "uncaught exception" のエラーにする
+func UncaughtError(value object.Object, stack []object.StackFrame) *object.Error {
+	if exc, ok := value.(*object.Exception); ok {
+		return exc.ToError()
+	}
+	errObj := newError(object.GENERIC_ERROR, "uncaught exception: %s", value.Inspect())
+	errObj.Stack = stack
+	return errObj
+}
+
+// isStringAndException は一方が文字列、もう一方が Exception かを返す
+func isStringAndException(left, right object.Object) bool {
+	return (left.Type() == object.STRING_OBJ && right.Type() == object.EXCEPTION_OBJ) ||
+		(left.Type() == object.EXCEPTION_OBJ && right.Type() == object.STRING_OBJ)
+}
+
+// exceptionMessageEqual は Exception と文字列を、Exception の位置情報を含まないメッセージで比較する
+// catch の変数がメッセージの文字列だったころの e == "division by zero" のような比較をそのまま使えるようにする
+func exceptionMessageEqual(left, right object.Object) bool {
+	if exc, ok := left.(*object.Exception); ok {
+		return exc.Message == right.(*object.String).Value
+	}
+	return right.(*object.Exception).Message == left.(*object.String).Value
+}
+
+// evalExceptionIndexExpression は e["message"] などで Exception の情報を取り出す
+// 存在しない名前はマップと同じく null を返す
+func evalExceptionIndexExpression(exc *object.Exception, name string) object.Object {
+	switch name {
+	case "message":
+		return &object.String{Value: exc.Message}
+	case "kind":
+		return &object.String{Value: exc.Kind}
+	case "file":
+		if exc.File == "" {
+			return NULL
+		}
+		return &object.String{Value: exc.File}
+	case "line":
+		return positionNumber(exc.Line)
+	case "column":
+		return positionNumber(exc.Column)
+	case "stack":
+		frames := make([]object.Object, len(exc.Stack))
+		for i, frame := range exc.Stack {
+			frames[i] = stackFrameMap(frame)
+		}
+		return &object.Array{Elements: frames}
+	default:
+		return NULL
+	}
+}
+
+// positionNumber は行・列を数値にする（不明な場合は null）
+func positionNumber(n int) object.Object {
+	if n == 0 {
+		return NULL
+	}
+	return &object.Number{Value: float64(n)}
+}
+
+// stackFrameMap は呼び出し履歴の 1 フレームを {"function", "file", "line", "column"} のマップにする
+func stackFrameMap(frame object.StackFrame) *object.Map {
+	var function, file object.Object = NULL, NULL
+	if frame.Function != "" {
+		function = &object.String{Value: frame.Function}
+	}
+	if frame.File != "" {
+		file = &object.String{Value: frame.File}
+	}
+
+	m := &object.Map{}
+	for _, field := range []struct {
+		name  string
+		value object.Object
+	}{
+		{"function", function},
+		{"file", file},
+		{"line", positionNumber(frame.Line)},
+		{"column", positionNumber(frame.Column)},
+	} {
+		m.Set(&object.String{Value: field.name}, field.value)
+	}
+	return m
+}
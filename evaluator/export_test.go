@@ -0,0 +1,18 @@
+package evaluator
+
+import (
+	"context"
+	"sugu/ast"
+	"sugu/object"
+)
+
+// testEngine は testEval と testEvalFile が使う評価バックエンド
+var testEngine = func(ctx context.Context, ev *Evaluator, program *ast.Program, env *object.Environment) object.Object {
+	return ev.EvalContext(ctx, program, env)
+}
+
+// SetTestEngine は testEval と testEvalFile が使う評価バックエンドを差し替える
+// 同じテストケースを VM バックエンドでも実行するために使う（engine_test.go）
+func SetTestEngine(engine func(ctx context.Context, ev *Evaluator, program *ast.Program, env *object.Environment) object.Object) {
+	testEngine = engine
+}
@@ -0,0 +1,180 @@
+package evaluator
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"sugu/object"
+)
+
+// formatBuiltins は書式を指定して文字列を作る組み込み関数を作成する
+func (e *Evaluator) formatBuiltins() map[string]*object.Builtin {
+	return map[string]*object.Builtin{
+		"format": {
+			Fn: func(args ...object.Object) object.Object {
+				str, errObj := formatArgs("format", args)
+				if errObj != nil {
+					return errObj
+				}
+				return &object.String{Value: str}
+			},
+		},
+		"outf": {
+			Fn: func(args ...object.Object) object.Object {
+				str, errObj := formatArgs("outf", args)
+				if errObj != nil {
+					return errObj
+				}
+				fmt.Fprint(e.stdout, str)
+				return NULL
+			},
+		},
+	}
+}
+
+// formatArgs は組み込み関数 name の引数（書式文字列と値）を検査して Format に渡す
+func formatArgs(name string, args []object.Object) (string, *object.Error) {
+	if len(args) < 1 {
+		return "", newError(object.ARGUMENT_ERROR, "wrong number of arguments. got=%d, want=1+", len(args))
+	}
+	format, ok := args[0].(*object.String)
+	if !ok {
+		return "", newError(object.TYPE_ERROR, "first argument to `%s` must be STRING, got %s", name, args[0].Type())
+	}
+	return Format(name, format.Value, args[1:])
+}
+
+// Format は printf 形式の書式 format に args を埋め込んだ文字列を返す（name はエラーメッセージに使う関数名）
+// 指定子は %[フラグ][幅][.精度]動詞 で、フラグは - + 0 と空白、動詞は次のとおり
+//
+//	%s %v  任意の値（Inspect の形式）
+//	%d     整数
+//	%f     小数（精度の既定は 6 桁）
+//	%x %X  整数の 16 進数
+//	%%     % そのもの
+//
+// %(key)s のように名前を付けた指定子は、唯一の引数のマップの key の値を使う
+// 引数の数や型が指定子と合わない場合はエラーを返す
+func Format(name, format string, args []object.Object) (string, *object.Error) {
+	var out strings.Builder
+	used := 0
+	named := false
+
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			out.WriteByte(format[i])
+			continue
+		}
+		start := i
+		i++
+
+		// 名前付きの指定子
+		key, isNamed := "", false
+		if i < len(format) && format[i] == '(' {
+			end := strings.IndexByte(format[i:], ')')
+			if end < 0 {
+				return "", newError(object.VALUE_ERROR, "unterminated placeholder name in format string of `%s`: %s", name, format[start:])
+			}
+			key, isNamed = format[i+1:i+end], true
+			i += end + 1
+		}
+
+		// フラグ・幅・精度
+		specStart := i
+		for i < len(format) && strings.IndexByte("-+0 ", format[i]) >= 0 {
+			i++
+		}
+		for i < len(format) && '0' <= format[i] && format[i] <= '9' {
+			i++
+		}
+		if i < len(format) && format[i] == '.' {
+			i++
+			for i < len(format) && '0' <= format[i] && format[i] <= '9' {
+				i++
+			}
+		}
+		if i >= len(format) {
+			return "", newError(object.VALUE_ERROR, "incomplete placeholder at end of format string of `%s`: %s", name, format[start:])
+		}
+		spec, verb, placeholder := format[specStart:i], format[i], format[start:i+1]
+
+		if verb == '%' && !isNamed {
+			out.WriteByte('%')
+			continue
+		}
+		if !strings.ContainsRune("svdfxX", rune(verb)) {
+			return "", newError(object.VALUE_ERROR, "unknown verb %%%c in format string of `%s`", verb, name)
+		}
+
+		// 値を取り出す
+		var arg object.Object
+		if isNamed {
+			if used > 0 {
+				return "", newError(object.VALUE_ERROR, "cannot mix named and positional placeholders in `%s`", name)
+			}
+			named = true
+			m, ok := formatMap(args)
+			if !ok {
+				return "", newError(object.TYPE_ERROR, "named placeholder %s in `%s` requires a single MAP argument", placeholder, name)
+			}
+			pair, ok := m.Get(&object.String{Value: key})
+			if !ok {
+				return "", newError(object.VALUE_ERROR, "key %q for %s not found in `%s` argument", key, placeholder, name)
+			}
+			arg = pair.Value
+		} else {
+			if named {
+				return "", newError(object.VALUE_ERROR, "cannot mix named and positional placeholders in `%s`", name)
+			}
+			if used >= len(args) {
+				return "", newError(object.ARGUMENT_ERROR, "missing argument for %s in `%s`", placeholder, name)
+			}
+			arg = args[used]
+			used++
+		}
+
+		value, errObj := formatValue(name, placeholder, verb, arg)
+		if errObj != nil {
+			return "", errObj
+		}
+		fmt.Fprintf(&out, "%"+spec+string(verb), value)
+	}
+
+	if !named && used < len(args) {
+		return "", newError(object.ARGUMENT_ERROR, "too many arguments to `%s`: got=%d, used=%d", name, len(args), used)
+	}
+	return out.String(), nil
+}
+
+// formatMap は名前付きの指定子の値を取り出すマップ（唯一の引数）を返す
+func formatMap(args []object.Object) (*object.Map, bool) {
+	if len(args) != 1 {
+		return nil, false
+	}
+	m, ok := args[0].(*object.Map)
+	return m, ok
+}
+
+// formatValue は指定子 placeholder の動詞 verb に渡す Go の値に arg を変換する
+func formatValue(name, placeholder string, verb byte, arg object.Object) (any, *object.Error) {
+	switch verb {
+	case 's', 'v':
+		return arg.Inspect(), nil
+	}
+
+	num, ok := arg.(*object.Number)
+	if !ok {
+		return nil, newError(object.TYPE_ERROR, "%s in `%s` requires NUMBER, got %s", placeholder, name, arg.Type())
+	}
+	if verb == 'f' {
+		return num.Value, nil
+	}
+	if math.Trunc(num.Value) != num.Value {
+		return nil, newError(object.VALUE_ERROR, "%s in `%s` requires an integer, got %s", placeholder, name, num.Inspect())
+	}
+	// float64 では math.MaxInt64 が 2^63 に丸められるため、2^63 以上を範囲外にする
+	if num.Value < math.MinInt64 || num.Value >= math.MaxInt64 {
+		return nil, newError(object.VALUE_ERROR, "%s in `%s` is out of the 64-bit integer range, got %s", placeholder, name, num.Inspect())
+	}
+	return int64(num.Value), nil
+}
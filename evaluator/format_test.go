@@ -0,0 +1,90 @@
+package evaluator
+
+import (
+	"bytes"
+	"context"
+	"sugu/object"
+	"testing"
+)
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`format("plain")`, "plain"},
+		{`format("%s is %d", "Sugu", 3)`, "Sugu is 3"},
+		{`format("%s %v", [1, "a"], {"k": null})`, "[1, a] {k: null}"},
+		{`format("%f|%.2f|%.0f", 1.5, 3.14159, 2.5)`, "1.500000|3.14|2"},
+		{`format("[%5s][%-5s][%.2s]", "ab", "ab", "abc")`, "[   ab][ab   ][ab]"},
+		{`format("[%5d][%-5d][%05d][%+d]", 42, 42, 42, 42)`, "[   42][42   ][00042][+42]"},
+		{`format("%8.2f|%-8.2f|", 1234.5, -1.005)`, " 1234.50|-1.00   |"},
+		{`format("%x %X %x", 255, 255, -16)`, "ff FF -10"},
+		{`format("100%%")`, "100%"},
+		// 幅は文字数で数える
+		{`format("[%4s]", "あい")`, "[  あい]"},
+		// 名前付きの指定子
+		{`format("%(name)s: %(price).2f", {"name": "apple", "price": 1.5})`, "apple: 1.50"},
+		{`format("%(x)d %(x)d", {"x": 1})`, "1 1"},
+		{`outf("%d", 1)`, "null"},
+		{`format("%d", pow(2, 62))`, "4611686018427387904"},
+		{`format("%d", -pow(2, 63))`, "-9223372036854775808"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("input %q: wrong result. expected=%q, got=%q", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestFormatErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`format()`, "wrong number of arguments. got=0, want=1+"},
+		{`format(1)`, "first argument to `format` must be STRING, got NUMBER"},
+		{`format("%d %d", 1)`, "missing argument for %d in `format`"},
+		{`format("%d", 1, 2)`, "too many arguments to `format`: got=2, used=1"},
+		{`format("%5d", "x")`, "%5d in `format` requires NUMBER, got STRING"},
+		{`format("%d", 2.5)`, "%d in `format` requires an integer, got 2.5"},
+		{`format("%x", 0.5)`, "%x in `format` requires an integer, got 0.5"},
+		// float64 では 9223372036854775807 は 2^63 に丸められる
+		{`format("%d", 9223372036854775807)`, "%d in `format` is out of the 64-bit integer range, got 9.223372036854776e+18"},
+		{`format("%d", pow(2, 63))`, "%d in `format` is out of the 64-bit integer range, got 9.223372036854776e+18"},
+		{`format("%d", pow(10, 300))`, "%d in `format` is out of the 64-bit integer range, got 1.0000000000000006e+300"},
+		{`format("%q", 1)`, "unknown verb %q in format string of `format`"},
+		{`format("50%")`, "incomplete placeholder at end of format string of `format`: %"},
+		{`format("%(name", {})`, "unterminated placeholder name in format string of `format`: %(name"},
+		{`format("%(name)s", "x")`, "named placeholder %(name)s in `format` requires a single MAP argument"},
+		{`format("%(name)s", {"x": 1})`, "key \"name\" for %(name)s not found in `format` argument"},
+		{`format("%s %(name)s", {"name": 1})`, "cannot mix named and positional placeholders in `format`"},
+		{`outf("%d", "x")`, "%d in `outf` requires NUMBER, got STRING"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("input %q: object is not Error. got=%T (%+v)", tt.input, evaluated, evaluated)
+			continue
+		}
+		if errObj.Text() != tt.expected {
+			t.Errorf("input %q: wrong error message. expected=%q, got=%q", tt.input, tt.expected, errObj.Text())
+		}
+	}
+}
+
+func TestOutf(t *testing.T) {
+	input := `outf("%-6s|%6.2f\n", "apple", 1.5); outf("%-6s|%6.2f\n", "banana", 12)`
+	expected := "apple |  1.50\nbanana| 12.00\n"
+
+	var stdout bytes.Buffer
+	testEvalWith(context.Background(), input, func(ev *Evaluator) { ev.SetStdout(&stdout) })
+
+	if stdout.String() != expected {
+		t.Errorf("wrong output. expected=%q, got=%q", expected, stdout.String())
+	}
+}
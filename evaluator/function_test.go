@@ -0,0 +1,152 @@
+package evaluator
+
+import (
+	"context"
+	"sugu/object"
+	"testing"
+)
+
+func TestExpressionBody(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`const double = func(x) => x * 2; double(4)`, "8"},
+		// 本体の式は後ろに続く呼び出しも含む
+		{`func(a, b) => a + b(1, 2)`, "func(a, b) => { ... }"},
+		{`(func(a, b) => a + b)(1, 2)`, "3"},
+		{`map([1, 2, 3], func(x) => x * x)`, "[1, 4, 9]"},
+		{`func add(x) => func(y) => x + y; add(1)(2)`, "3"},
+		// マップを返す場合は括弧で囲む
+		{`const pair = func(k, v) => ({k: v}); pair("a", 1)`, "{a: 1}"},
+		{`const f = func() => null; f()`, "null"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("input %q: wrong result. expected=%q, got=%q", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestDefaultParameters(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`func f(a, b = 10) => a + b; f(1)`, "11"},
+		{`func f(a, b = 10) => a + b; f(1, 2)`, "3"},
+		// null を渡した場合はデフォルト値を使わない
+		{`func f(a = 1) => a; f(null)`, "null"},
+		// デフォルト値は呼び出しのたびに評価され、前のパラメータを参照できる
+		{`func f(a, b = [a]) => b; const x = f(1); push(x, 2); f(1)`, "[1]"},
+		{`func f(a, b = a * 2, c = a + b) => [a, b, c]; f(1)`, "[1, 2, 3]"},
+		{`func f(a, b = a * 2, c = a + b) => [a, b, c]; f(1, 5)`, "[1, 5, 6]"},
+		// デフォルト値は関数を定義したスコープの変数を参照する
+		{`mut n = 1; func f(a = n) => a; n = 2; f()`, "2"},
+		// デフォルト値のないパラメータに引数がなければ null
+		{`func f(a, b = 2) => [a, b]; f()`, "[null, 2]"},
+		{`func(a = 1, b = 2) => a`, "func(a = 1, b = 2) => { ... }"},
+		// 引数を渡した場合はデフォルト値を評価しない
+		{`mut calls = 0; func g() => { calls++; return 5; }; func h(x = g()) => x; h(); h(1); calls`, "1"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("input %q: wrong result. expected=%q, got=%q", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestRestParameters(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`func f(first, ...rest) => rest; f(1, 2, 3)`, "[2, 3]"},
+		{`func f(first, ...rest) => rest; f(1)`, "[]"},
+		{`func f(first, ...rest) => [first, rest]; f()`, "[null, []]"},
+		{`func f(...all) => len(all); f(1, 2, 3, 4)`, "4"},
+		{`func f(a, b = 2, ...rest) => [a, b, rest]; f(1)`, "[1, 2, []]"},
+		{`func f(a, b = 2, ...rest) => [a, b, rest]; f(1, 3, 4, 5)`, "[1, 3, [4, 5]]"},
+		{`func(a, ...rest) => rest`, "func(a, ...rest) => { ... }"},
+		// 組み込み関数から呼び出した場合も同じ
+		{`reduce([[1, 2], [3]], func(acc, x) => acc + len(x), 0)`, "3"},
+		{`map([1, 2], func(...args) => args)`, "[[1], [2]]"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("input %q: wrong result. expected=%q, got=%q", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestDefaultParameterErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`func f(a = b) => a; f()`, "identifier not found: b"},
+		{`func f(a = 1 / "x") => a; f()`, "type mismatch: NUMBER / STRING"},
+		// 引数を渡した場合はデフォルト値を評価しない
+		{`func f(a = b) => a; f(1) + b`, "identifier not found: b"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("input %q: object is not Error. got=%T (%+v)", tt.input, evaluated, evaluated)
+			continue
+		}
+		if errObj.Text() != tt.expected {
+			t.Errorf("input %q: wrong error message. expected=%q, got=%q", tt.input, tt.expected, errObj.Text())
+		}
+	}
+
+	// デフォルト値の throw は呼び出し元でキャッチできる
+	input := `func fail() => { throw "no"; }; func f(a = fail()) => a; try { f() } catch (e) { "caught " + e }`
+	if evaluated := testEval(input); evaluated.Inspect() != "caught no" {
+		t.Errorf("input %q: wrong result. expected=%q, got=%q", input, "caught no", evaluated.Inspect())
+	}
+}
+
+func TestStrictArity(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string // エラーメッセージ（エラーでなければ結果の Inspect）
+	}{
+		{`func f(a, b) => a + b; f(1, 2)`, "3"},
+		{`func f(a, b) => a + b; f(1)`, "line 1, column 25: wrong number of arguments. got=1, want=2"},
+		{`func f(a, b) => a + b; f(1, 2, 3)`, "line 1, column 25: wrong number of arguments. got=3, want=2"},
+		{`func f(a, b = 1) => a + b; f()`, "line 1, column 29: wrong number of arguments. got=0, want=1 or 2"},
+		{`func f(a, b = 1, c = 2) => a; f(1, 2, 3, 4)`, "line 1, column 32: wrong number of arguments. got=4, want=1 to 3"},
+		{`func f(a, ...rest) => rest; f(1, 2, 3)`, "[2, 3]"},
+		{`func f(a, ...rest) => rest; f()`, "line 1, column 30: wrong number of arguments. got=0, want=1+"},
+		{`func f(a = 1) => a; f()`, "1"},
+		// 組み込み関数から呼び出す関数も確認する
+		{`map([1], func(a, b) => a)`, "line 1, column 4: wrong number of arguments. got=1, want=2"},
+		// 引数の数のエラーは catch できる
+		{`func f() => 1; try { f(1) } catch (e) { "caught" }`, "caught"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEvalWith(context.Background(), tt.input, func(ev *Evaluator) { ev.SetStrictArity(true) })
+		got := evaluated.Inspect()
+		if errObj, ok := evaluated.(*object.Error); ok {
+			got = errObj.Message
+		}
+		if got != tt.expected {
+			t.Errorf("input %q: wrong result. expected=%q, got=%q", tt.input, tt.expected, got)
+		}
+	}
+
+	// 既定では引数の数を確認しない
+	if evaluated := testEval(`func f(a, b) => b; f(1, 2, 3)`); evaluated.Inspect() != "2" {
+		t.Errorf("arity should not be checked by default. got=%q", evaluated.Inspect())
+	}
+}
@@ -0,0 +1,265 @@
+package evaluator
+
+import (
+	"cmp"
+	"slices"
+	"sugu/object"
+)
+
+// higherOrderBuiltins は関数を引数に取る配列の組み込み関数を作成する
+// 引数の関数は e.callback で呼び出し、そのエラーと throw はそのまま呼び出し元に返す
+// どの関数も元の配列を変更せず、新しい配列を返す
+func (e *Evaluator) higherOrderBuiltins() map[string]*object.Builtin {
+	return map[string]*object.Builtin{
+		"map": {
+			Fn: func(args ...object.Object) object.Object {
+				arr, fn, errObj := arrayAndFunction("map", args)
+				if errObj != nil {
+					return errObj
+				}
+				elements := make([]object.Object, len(arr.Elements))
+				for i, elem := range arr.Elements {
+					result := e.callback(fn, []object.Object{elem})
+					if isFailure(result) {
+						return result
+					}
+					elements[i] = result
+				}
+				return &object.Array{Elements: elements}
+			},
+		},
+		"filter": {
+			Fn: func(args ...object.Object) object.Object {
+				arr, fn, errObj := arrayAndFunction("filter", args)
+				if errObj != nil {
+					return errObj
+				}
+				elements := []object.Object{}
+				for _, elem := range arr.Elements {
+					result := e.callback(fn, []object.Object{elem})
+					if isFailure(result) {
+						return result
+					}
+					if isTruthy(result) {
+						elements = append(elements, elem)
+					}
+				}
+				return &object.Array{Elements: elements}
+			},
+		},
+		"reduce": {
+			Fn: func(args ...object.Object) object.Object {
+				if len(args) != 2 && len(args) != 3 {
+					return newError(object.ARGUMENT_ERROR, "wrong number of arguments. got=%d, want=2 or 3", len(args))
+				}
+				arr, fn, errObj := arrayAndFunction("reduce", args[:2])
+				if errObj != nil {
+					return errObj
+				}
+				elements := arr.Elements
+				var acc object.Object
+				if len(args) == 3 {
+					acc = args[2]
+				} else {
+					if len(elements) == 0 {
+						return newError(object.VALUE_ERROR, "`reduce` of empty array with no initial value")
+					}
+					acc, elements = elements[0], elements[1:]
+				}
+				for _, elem := range elements {
+					acc = e.callback(fn, []object.Object{acc, elem})
+					if isFailure(acc) {
+						return acc
+					}
+				}
+				return acc
+			},
+		},
+		"find": {
+			Fn: func(args ...object.Object) object.Object {
+				arr, fn, errObj := arrayAndFunction("find", args)
+				if errObj != nil {
+					return errObj
+				}
+				for _, elem := range arr.Elements {
+					result := e.callback(fn, []object.Object{elem})
+					if isFailure(result) {
+						return result
+					}
+					if isTruthy(result) {
+						return elem
+					}
+				}
+				return NULL
+			},
+		},
+		"some": {
+			Fn: func(args ...object.Object) object.Object {
+				arr, fn, errObj := arrayAndFunction("some", args)
+				if errObj != nil {
+					return errObj
+				}
+				for _, elem := range arr.Elements {
+					result := e.callback(fn, []object.Object{elem})
+					if isFailure(result) {
+						return result
+					}
+					if isTruthy(result) {
+						return TRUE
+					}
+				}
+				return FALSE
+			},
+		},
+		"every": {
+			Fn: func(args ...object.Object) object.Object {
+				arr, fn, errObj := arrayAndFunction("every", args)
+				if errObj != nil {
+					return errObj
+				}
+				for _, elem := range arr.Elements {
+					result := e.callback(fn, []object.Object{elem})
+					if isFailure(result) {
+						return result
+					}
+					if !isTruthy(result) {
+						return FALSE
+					}
+				}
+				return TRUE
+			},
+		},
+		"sort": {
+			Fn: func(args ...object.Object) object.Object {
+				if len(args) != 1 && len(args) != 2 {
+					return newError(object.ARGUMENT_ERROR, "wrong number of arguments. got=%d, want=1 or 2", len(args))
+				}
+				if len(args) == 2 {
+					arr, fn, errObj := arrayAndFunction("sort", args)
+					if errObj != nil {
+						return errObj
+					}
+					return e.sortWith(arr.Elements, fn)
+				}
+				if args[0].Type() != object.ARRAY_OBJ {
+					return newError(object.TYPE_ERROR, "argument to `sort` must be ARRAY, got %s", args[0].Type())
+				}
+				elements := args[0].(*object.Array).Elements
+				return sortByKeys(elements, elements)
+			},
+		},
+		"sortBy": {
+			Fn: func(args ...object.Object) object.Object {
+				arr, fn, errObj := arrayAndFunction("sortBy", args)
+				if errObj != nil {
+					return errObj
+				}
+				keys := make([]object.Object, len(arr.Elements))
+				for i, elem := range arr.Elements {
+					key := e.callback(fn, []object.Object{elem})
+					if isFailure(key) {
+						return key
+					}
+					keys[i] = key
+				}
+				return sortByKeys(arr.Elements, keys)
+			},
+		},
+	}
+}
+
+// arrayAndFunction は (配列, 関数) の 2 つの引数を確認する
+func arrayAndFunction(name string, args []object.Object) (*object.Array, object.Object, *object.Error) {
+	if len(args) != 2 {
+		return nil, nil, newError(object.ARGUMENT_ERROR, "wrong number of arguments. got=%d, want=2", len(args))
+	}
+	arr, ok := args[0].(*object.Array)
+	if !ok {
+		return nil, nil, newError(object.TYPE_ERROR, "argument to `%s` must be ARRAY, got %s", name, args[0].Type())
+	}
+	switch args[1].(type) {
+	case *object.Function, *object.Builtin:
+		return arr, args[1], nil
+	}
+	return nil, nil, newError(object.TYPE_ERROR, "second argument to `%s` must be FUNCTION, got %s", name, args[1].Type())
+}
+
+// isFailure は関数を呼び出した結果がエラーか throw かを返す
+func isFailure(result object.Object) bool {
+	_, _, thrown := Thrown(result)
+	return thrown || isError(result)
+}
+
+// sortWith は比較関数 fn で elements を安定ソートした新しい配列を返す
+// fn(a, b) は a を先にする場合は負の数、b を先にする場合は正の数、同じ順位なら 0 を返す
+func (e *Evaluator) sortWith(elements []object.Object, fn object.Object) object.Object {
+	sorted := slices.Clone(elements)
+	var failure object.Object
+	slices.SortStableFunc(sorted, func(a, b object.Object) int {
+		if failure != nil {
+			return 0
+		}
+		result := e.callback(fn, []object.Object{a, b})
+		if isFailure(result) {
+			failure = result
+			return 0
+		}
+		n, ok := result.(*object.Number)
+		if !ok {
+			failure = newError(object.TYPE_ERROR, "comparison function for `sort` must return NUMBER, got %s", result.Type())
+			return 0
+		}
+		switch {
+		case n.Value < 0:
+			return -1
+		case n.Value > 0:
+			return 1
+		}
+		return 0
+	})
+	if failure != nil {
+		return failure
+	}
+	return &object.Array{Elements: sorted}
+}
+
+// sortByKeys は keys[i] を elements[i] の並べ替えのキーとして、elements を安定ソートした新しい配列を返す
+func sortByKeys(elements, keys []object.Object) object.Object {
+	for _, key := range keys {
+		if key.Type() != object.NUMBER_OBJ && key.Type() != object.STRING_OBJ {
+			return newError(object.TYPE_ERROR, "cannot sort %s: only NUMBER and STRING can be compared", key.Type())
+		}
+	}
+
+	order := make([]int, len(elements))
+	for i := range order {
+		order[i] = i
+	}
+	slices.SortStableFunc(order, func(i, j int) int {
+		return compareValues(keys[i], keys[j])
+	})
+
+	sorted := make([]object.Object, len(elements))
+	for i, idx := range order {
+		sorted[i] = elements[idx]
+	}
+	return &object.Array{Elements: sorted}
+}
+
+// compareValues は sort の既定の順序で a と b を比較する
+// 数値は昇順（NaN は最初）で文字列より前、文字列は文字コード順に並べる
+func compareValues(a, b object.Object) int {
+	switch a := a.(type) {
+	case *object.Number:
+		if b, ok := b.(*object.Number); ok {
+			return cmp.Compare(a.Value, b.Value)
+		}
+		return -1
+	case *object.String:
+		if b, ok := b.(*object.String); ok {
+			return cmp.Compare(a.Value, b.Value)
+		}
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,170 @@
+package evaluator
+
+import (
+	"fmt"
+	"sugu/object"
+	"testing"
+)
+
+func TestHigherOrderBuiltins(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`map([1, 2, 3], func(x) => { return x * 2; })`, "[2, 4, 6]"},
+		{`map([], func(x) => { return x; })`, "[]"},
+		{`map(["1", "2.5"], float)`, "[1, 2.5]"},
+		// クロージャは外側の変数を参照できる
+		{`const n = 10; map([1, 2], func(x) => { return x + n; })`, "[11, 12]"},
+		{`filter([1, 2, 3, 4], func(x) => { return x % 2 == 0; })`, "[2, 4]"},
+		{`filter([1, null, 0, "", false], func(x) => { return x; })`, "[1, 0, ]"},
+		{`reduce([1, 2, 3], func(acc, x) => { return acc + x; })`, "6"},
+		{`reduce([1, 2, 3], func(acc, x) => { return push(acc, x * x); }, [])`, "[1, 4, 9]"},
+		{`reduce([], func(acc, x) => { return acc + x; }, 0)`, "0"},
+		{`find([1, 5, 10], func(x) => { return x > 3; })`, "5"},
+		{`find([1, 2], func(x) => { return x > 3; })`, "null"},
+		{`some([1, 2, 3], func(x) => { return x > 2; })`, "true"},
+		{`some([], func(x) => { return true; })`, "false"},
+		{`every([1, 2, 3], func(x) => { return x > 0; })`, "true"},
+		{`every([1, -2, 3], func(x) => { return x > 0; })`, "false"},
+		{`every([], func(x) => { return false; })`, "true"},
+		// 元の配列は変更しない
+		{`const a = [3, 1, 2]; sort(a); map(a, func(x) => { return x; }); a`, "[3, 1, 2]"},
+		// 関数は引数を超える分を評価しない（some と find は最初に見つかった時点で終わる）
+		{`mut calls = 0; some([1, 2, 3], func(x) => { calls++; return x == 2; }); calls`, "2"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("input %q: wrong result. expected=%q, got=%q", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestSortBuiltins(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`sort([3, 1, 2])`, "[1, 2, 3]"},
+		{`sort(["b", "a", "C"])`, "[C, a, b]"},
+		// 数値は文字列より前
+		{`sort(["b", 2, "a", 1])`, "[1, 2, a, b]"},
+		{`sort([])`, "[]"},
+		{`sort([3, 1, 2], func(a, b) => { return b - a; })`, "[3, 2, 1]"},
+		// 安定ソート（同じ順位の要素は元の順序を保つ）
+		{`sort([[2, "a"], [1, "b"], [2, "c"], [1, "d"]], func(a, b) => { return a[0] - b[0]; })`, "[[1, b], [1, d], [2, a], [2, c]]"},
+		{`sortBy([{"n": "bob", "age": 30}, {"n": "al", "age": 25}, {"n": "cy", "age": 30}], func(p) => { return p["age"]; })`, "[{n: al, age: 25}, {n: bob, age: 30}, {n: cy, age: 30}]"},
+		{`sortBy(["ccc", "a", "bb"], len)`, "[a, bb, ccc]"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("input %q: wrong result. expected=%q, got=%q", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestHigherOrderBuiltinErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		kind     string
+		expected string
+	}{
+		{`map([1])`, object.ARGUMENT_ERROR, "wrong number of arguments. got=1, want=2"},
+		{`map(1, len)`, object.TYPE_ERROR, "argument to `map` must be ARRAY, got NUMBER"},
+		{`filter([1], 1)`, object.TYPE_ERROR, "second argument to `filter` must be FUNCTION, got NUMBER"},
+		{`reduce([1])`, object.ARGUMENT_ERROR, "wrong number of arguments. got=1, want=2 or 3"},
+		{`reduce([], func(a, b) => { return a; })`, object.VALUE_ERROR, "`reduce` of empty array with no initial value"},
+		{`sort([1], len, 1)`, object.ARGUMENT_ERROR, "wrong number of arguments. got=3, want=1 or 2"},
+		{`sort("abc")`, object.TYPE_ERROR, "argument to `sort` must be ARRAY, got STRING"},
+		{`sort([1, true])`, object.TYPE_ERROR, "cannot sort BOOLEAN: only NUMBER and STRING can be compared"},
+		{`sortBy([1, 2], func(x) => { return null; })`, object.TYPE_ERROR, "cannot sort NULL: only NUMBER and STRING can be compared"},
+		{`sort([2, 1], func(a, b) => { return a < b; })`, object.TYPE_ERROR, "comparison function for `sort` must return NUMBER, got BOOLEAN"},
+		// 関数の中のエラーはそのまま伝わる
+		{`map([1, 2], func(x) => { return x + missing; })`, object.REFERENCE_ERROR, "identifier not found: missing"},
+		{`map(["a"], int)`, object.VALUE_ERROR, `cannot convert "a" to int`},
+		{`sort([2, 1], func(a, b) => { return len(a); })`, object.TYPE_ERROR, "argument to `len` not supported, got NUMBER"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("input %q: no error object returned. got=%T(%+v)", tt.input, evaluated, evaluated)
+			continue
+		}
+		if errObj.Kind != tt.kind || errObj.Text() != tt.expected {
+			t.Errorf("input %q: wrong error. expected=%s %q, got=%s %q", tt.input, tt.kind, tt.expected, errObj.Kind, errObj.Text())
+		}
+	}
+}
+
+func TestHigherOrderBuiltinThrow(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		// 関数の throw は呼び出し元の catch で throw された値のまま捕まえられる
+		{`try { map([1, 2], func(x) => { throw {"code": x}; }) } catch (e) { e["code"] }`, "1"},
+		{`try { sort([2, 1], func(a, b) => { throw "cmp"; }) } catch (e) { e }`, "cmp"},
+		{`try { filter([1], func(x) => { throw error("bad", "ValueError"); }) } catch (e) { e["kind"] + ": " + e["message"] }`, "ValueError: bad"},
+		// 組み込み関数のエラーも catch できる
+		{`try { map([1], func(x) => { return len(x); }) } catch (e) { e["kind"] }`, "TypeError"},
+		// 関数の中の try/catch はその中で完結する
+		{`map([1, 2], func(x) => { try { throw x; } catch (e) { return e * 10; } })`, "[10, 20]"},
+		// finally は throw が組み込み関数を通り抜ける場合にも実行される
+		{`mut log = ""; try { try { map([1], func(x) => { throw "a"; }); } finally { log = log + "f"; } } catch (e) { log = log + e; } log`, "fa"},
+		// 入れ子の呼び出し
+		{`map([[1, 2], [3]], func(row) => { return reduce(row, func(a, b) => { return a + b; }); })`, "[3, 3]"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("input %q: wrong result. expected=%q, got=%q", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestHigherOrderBuiltinStackTrace(t *testing.T) {
+	tests := []struct {
+		input    string
+		message  string
+		expected []string
+	}{
+		// 組み込み関数から呼び出した関数は、組み込み関数を呼び出した位置からの呼び出しとして記録する
+		{
+			"func double(x) => {\n  return x * missing;\n}\nfunc run() => {\n  return map([1], double);\n}\nrun();",
+			"line 2, column 14: identifier not found: missing",
+			[]string{"double (line 5, column 13)", "run (line 7, column 4)"},
+		},
+		{
+			"map([1], func(x) => { throw \"boom\"; });",
+			"uncaught exception: boom",
+			[]string{"<anonymous> (line 1, column 4)"},
+		},
+	}
+
+	for _, tt := range tests {
+		result := testEval(tt.input)
+		errObj, ok := result.(*object.Error)
+		if !ok {
+			t.Errorf("no error object returned for %q. got=%T(%+v)", tt.input, result, result)
+			continue
+		}
+		if errObj.Message != tt.message {
+			t.Errorf("wrong error message for %q. expected=%q, got=%q", tt.input, tt.message, errObj.Message)
+		}
+
+		frames := []string{}
+		for _, frame := range errObj.Stack {
+			frames = append(frames, frame.String())
+		}
+		if fmt.Sprint(frames) != fmt.Sprint(tt.expected) {
+			t.Errorf("wrong stack for %q.\nexpected=%q\ngot=%q", tt.input, tt.expected, frames)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package evaluator
+
+import (
+	"bufio"
+	"io"
+	"net/http"
+	"sugu/object"
+	"time"
+)
+
+// このファイルは Go のアプリケーションから Evaluator を使うための機能をまとめる
+// 入出力の差し替え、組み込み関数の登録、Go からの Sugu の関数の呼び出しを提供する
+
+// Caller は Call と組み込み関数から関数を呼び出すバックエンド
+// catch されなかった throw は Throw で作成した値として返す
+type Caller func(fn object.Object, args []object.Object) object.Object
+
+// SetCaller は Call と組み込み関数から関数を呼び出すバックエンドを設定する
+// 設定しない場合は木構造評価器で呼び出す
+func (e *Evaluator) SetCaller(caller Caller) {
+	e.caller = caller
+}
+
+// SetStdin は in() が読み込む入力を設定する（既定は os.Stdin）
+func (e *Evaluator) SetStdin(r io.Reader) {
+	e.stdin = bufio.NewReader(r)
+}
+
+// SetStdout は out() と outln() の出力先を設定する（既定は os.Stdout）
+func (e *Evaluator) SetStdout(w io.Writer) {
+	e.stdout = w
+}
+
+// SetStderr は errout() と erroutln() の出力先を設定する（既定は os.Stderr）
+func (e *Evaluator) SetStderr(w io.Writer) {
+	e.stderr = w
+}
+
+// SetClock は now() が現在時刻を得る関数を設定する（既定は time.Now）
+// テストで固定の時刻を返す関数を設定すると、日時を扱うプログラムの結果が一定になる
+func (e *Evaluator) SetClock(clock func() time.Time) {
+	e.clock = clock
+}
+
+// SetHTTPTransport は httpRequest・httpGet・httpPost の通信に使う http.RoundTripper を設定する（既定は http.DefaultTransport）
+// nil を設定すると通信を禁止し、これらの関数は "network access is disabled" のエラーを返す
+func (e *Evaluator) SetHTTPTransport(transport http.RoundTripper) {
+	e.transport = transport
+}
+
+// DefineBuiltin は組み込み関数を登録する
+// 同じ名前の組み込み関数がある場合は置き換える。登録はこの Evaluator だけに影響する
+func (e *Evaluator) DefineBuiltin(name string, builtin *object.Builtin) {
+	e.builtins[name] = builtin
+}
+
+// Call は関数 fn を args を引数にして呼び出し、戻り値を返す
+// Go から Sugu の関数を呼び出すために使う。Go からの呼び出し自体は呼び出し履歴に記録しない
+// catch されなかった throw はエラーに変換して返す
+func (e *Evaluator) Call(fn object.Object, args []object.Object) object.Object {
+	result := e.callback(fn, args)
+	if value, stack, ok := Thrown(result); ok {
+		return UncaughtError(value, stack)
+	}
+	return result
+}
+
+// callback は組み込み関数の中から関数 fn を呼び出す
+// エラーと throw はそのまま返すので、組み込み関数はそれを自分の戻り値として返す
+// （呼び出し元の try/catch で throw された値のまま捕まえられる）
+// 組み込み関数の呼び出しの中であれば、その位置からの呼び出しとして呼び出し履歴に記録する
+func (e *Evaluator) callback(fn object.Object, args []object.Object) object.Object {
+	if e.caller != nil {
+		return e.caller(fn, args)
+	}
+	if e.site == nil {
+		return e.applyFunction(fn, args)
+	}
+	site := e.site
+	e.site = nil
+	result := e.callFunction(site, fn, args)
+	e.site = site
+	return result
+}
+
+// Throw は value を throw した結果を作成する（stack は throw した時点の呼び出し履歴）
+// Caller が catch されなかった throw を返すために使う
+func Throw(value object.Object, stack []object.StackFrame) object.Object {
+	return &throwValue{Value: value, Stack: stack}
+}
+
+// Thrown は result が throw の結果であれば、throw された値と呼び出し履歴を返す
+func Thrown(result object.Object) (object.Object, []object.StackFrame, bool) {
+	thrown, ok := result.(*throwValue)
+	if !ok {
+		return nil, nil, false
+	}
+	return thrown.Value, thrown.Stack, true
+}
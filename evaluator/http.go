@@ -0,0 +1,206 @@
+package evaluator
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sugu/convert"
+	"sugu/object"
+	"time"
+)
+
+// maxResponseBodySize はレスポンスボディの最大のバイト数
+const maxResponseBodySize = 10 << 20
+
+// httpBuiltins は HTTP のリクエストを送る組み込み関数を作成する
+// 通信には SetHTTPTransport で設定した http.RoundTripper を使い、実行中の context がキャンセルされると中断する
+func (e *Evaluator) httpBuiltins() map[string]*object.Builtin {
+	return map[string]*object.Builtin{
+		"httpRequest": {
+			Fn: func(args ...object.Object) object.Object {
+				if len(args) != 1 {
+					return newError(object.ARGUMENT_ERROR, "wrong number of arguments. got=%d, want=1", len(args))
+				}
+				opts, ok := args[0].(*object.Map)
+				if !ok {
+					return newError(object.TYPE_ERROR, "argument to `httpRequest` must be MAP, got %s", args[0].Type())
+				}
+				return e.httpRequest(opts)
+			},
+		},
+		"httpGet": {
+			Fn: func(args ...object.Object) object.Object {
+				if len(args) != 1 && len(args) != 2 {
+					return newError(object.ARGUMENT_ERROR, "wrong number of arguments. got=%d, want=1 or 2", len(args))
+				}
+				opts := &object.Map{}
+				opts.Set(&object.String{Value: "method"}, &object.String{Value: http.MethodGet})
+				opts.Set(&object.String{Value: "url"}, args[0])
+				if len(args) == 2 {
+					opts.Set(&object.String{Value: "headers"}, args[1])
+				}
+				return e.httpRequest(opts)
+			},
+		},
+		"httpPost": {
+			Fn: func(args ...object.Object) object.Object {
+				if len(args) != 2 && len(args) != 3 {
+					return newError(object.ARGUMENT_ERROR, "wrong number of arguments. got=%d, want=2 or 3", len(args))
+				}
+				opts := &object.Map{}
+				opts.Set(&object.String{Value: "method"}, &object.String{Value: http.MethodPost})
+				opts.Set(&object.String{Value: "url"}, args[0])
+				opts.Set(&object.String{Value: "body"}, args[1])
+				if len(args) == 3 {
+					opts.Set(&object.String{Value: "headers"}, args[2])
+				}
+				return e.httpRequest(opts)
+			},
+		},
+	}
+}
+
+// httpRequest は {method, url, headers, body, timeout} のマップの設定でリクエストを送り、
+// {status, headers, body} のマップを返す（レスポンスのヘッダー名は小文字）
+// 4xx や 5xx のステータスもエラーにせずそのまま返す。通信の失敗は IOError になる
+func (e *Evaluator) httpRequest(opts *object.Map) object.Object {
+	if e.transport == nil {
+		return newError(object.IO_ERROR, "network access is disabled")
+	}
+
+	method := http.MethodGet
+	if v, ok := httpOption(opts, "method"); ok {
+		s, ok := v.(*object.String)
+		if !ok {
+			return newError(object.TYPE_ERROR, "method for `httpRequest` must be STRING, got %s", v.Type())
+		}
+		method = strings.ToUpper(s.Value)
+	}
+
+	v, ok := httpOption(opts, "url")
+	if !ok {
+		return newError(object.VALUE_ERROR, "`httpRequest` requires a url")
+	}
+	rawURL, ok := v.(*object.String)
+	if !ok {
+		return newError(object.TYPE_ERROR, "url for `httpRequest` must be STRING, got %s", v.Type())
+	}
+	target, err := url.Parse(rawURL.Value)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return newError(object.VALUE_ERROR, "invalid url for `httpRequest`: %q", rawURL.Value)
+	}
+
+	header := http.Header{}
+	if v, ok := httpOption(opts, "headers"); ok {
+		headers, ok := v.(*object.Map)
+		if !ok {
+			return newError(object.TYPE_ERROR, "headers for `httpRequest` must be MAP, got %s", v.Type())
+		}
+		for _, pair := range headers.Pairs() {
+			header.Set(pair.Key.Inspect(), pair.Value.Inspect())
+		}
+	}
+
+	var body io.Reader
+	if v, ok := httpOption(opts, "body"); ok {
+		switch v := v.(type) {
+		case *object.String:
+			body = strings.NewReader(v.Value)
+		case *object.Array, *object.Map:
+			// 配列とマップは JSON にして送る
+			data, err := convert.ToJSON(v, "")
+			if err != nil {
+				return newError(object.VALUE_ERROR, "body for `httpRequest`: %s", err)
+			}
+			body = strings.NewReader(string(data))
+			if header.Get("Content-Type") == "" {
+				header.Set("Content-Type", "application/json")
+			}
+		default:
+			return newError(object.TYPE_ERROR, "body for `httpRequest` must be STRING, ARRAY or MAP, got %s", v.Type())
+		}
+	}
+
+	ctx := e.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	var timeout time.Duration
+	if v, ok := httpOption(opts, "timeout"); ok {
+		n, ok := v.(*object.Number)
+		if !ok || !(n.Value > 0) || math.IsInf(n.Value, 0) {
+			return newError(object.VALUE_ERROR, "timeout for `httpRequest` must be a positive NUMBER of seconds, got %s", v.Inspect())
+		}
+		timeout = time.Duration(n.Value * float64(time.Second))
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, target.String(), body)
+	if err != nil {
+		return newError(object.VALUE_ERROR, "invalid request for `httpRequest`: %s", err)
+	}
+	req.Header = header
+
+	client := &http.Client{Transport: e.transport}
+	resp, err := client.Do(req)
+	if err == nil {
+		defer resp.Body.Close()
+		var data []byte
+		if data, err = io.ReadAll(io.LimitReader(resp.Body, maxResponseBodySize+1)); err == nil {
+			if len(data) > maxResponseBodySize {
+				return newError(object.IO_ERROR, "http response for %s %s exceeds %d bytes", method, target.Redacted(), maxResponseBodySize)
+			}
+			return httpResponseMap(resp, data)
+		}
+	}
+
+	// 実行全体の context が終わった場合は、他の上限と同じく実行を中断する
+	if e.ctx != nil && e.ctx.Err() != nil {
+		return e.exceed(e.ctx.Err().Error())
+	}
+	if timeout > 0 && errors.Is(err, context.DeadlineExceeded) {
+		return newError(object.IO_ERROR, "http request %s %s timed out after %s", method, target.Redacted(), timeout)
+	}
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		err = urlErr.Err
+	}
+	return newError(object.IO_ERROR, "http request %s %s failed: %s", method, target.Redacted(), err)
+}
+
+// httpOption はリクエストの設定のマップから key の値を返す（ないか null なら false）
+func httpOption(opts *object.Map, key string) (object.Object, bool) {
+	pair, ok := opts.Get(&object.String{Value: key})
+	if !ok || pair.Value == NULL {
+		return nil, false
+	}
+	return pair.Value, true
+}
+
+// httpResponseMap はレスポンスを {status, headers, body} のマップにする
+// 同じ名前のヘッダーが複数ある場合は ", " でつなぐ
+func httpResponseMap(resp *http.Response, body []byte) *object.Map {
+	names := make([]string, 0, len(resp.Header))
+	for name := range resp.Header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	headers := &object.Map{}
+	for _, name := range names {
+		headers.Set(&object.String{Value: strings.ToLower(name)}, &object.String{Value: strings.Join(resp.Header[name], ", ")})
+	}
+
+	m := &object.Map{}
+	m.Set(&object.String{Value: "status"}, &object.Number{Value: float64(resp.StatusCode)})
+	m.Set(&object.String{Value: "headers"}, headers)
+	m.Set(&object.String{Value: "body"}, &object.String{Value: string(body)})
+	return m
+}
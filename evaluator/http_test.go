@@ -0,0 +1,175 @@
+package evaluator
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sugu/object"
+	"testing"
+	"time"
+)
+
+// roundTripperFunc は関数を http.RoundTripper として使う
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
+
+// newEchoServer はリクエストの内容を返すテスト用のサーバーを起動する
+func newEchoServer(t *testing.T) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/missing":
+			http.NotFound(w, r)
+			return
+		case "/slow":
+			select {
+			case <-time.After(2 * time.Second):
+			case <-r.Context().Done():
+			}
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("X-Method", r.Method)
+		w.Header().Add("X-Multi", "a")
+		w.Header().Add("X-Multi", "b")
+		fmt.Fprintf(w, "%s %s|%s|%s|%s", r.Method, r.URL.RequestURI(), r.Header.Get("Content-Type"), r.Header.Get("X-Token"), body)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestHTTPBuiltins(t *testing.T) {
+	server := newEchoServer(t)
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`httpGet(URL + "/path?q=1")["body"]`, "GET /path?q=1|||"},
+		{`httpGet(URL, {"X-Token": "secret"})["body"]`, "GET /||secret|"},
+		{`const r = httpGet(URL); [r["status"], r["headers"]["x-method"], r["headers"]["x-multi"]]`, "[200, GET, a, b]"},
+		{`httpPost(URL, "plain")["body"]`, "POST /|||plain"},
+		// 配列とマップは JSON で送る
+		{`httpPost(URL, {"a": [1, 2]})["body"]`, `POST /|application/json||{"a":[1,2]}`},
+		{`httpPost(URL, [1], {"Content-Type": "text/plain"})["body"]`, "POST /|text/plain||[1]"},
+		{`httpRequest({"method": "put", "url": URL, "body": "x", "headers": {"X-Token": 1}})["body"]`, "PUT /||1|x"},
+		{`httpRequest({"url": URL, "body": null})["body"]`, "GET /|||"},
+		// エラーのステータスもそのまま返す
+		{`httpGet(URL + "/missing")["status"]`, "404"},
+	}
+
+	for _, tt := range tests {
+		input := fmt.Sprintf("const URL = %q; %s", server.URL, tt.input)
+		evaluated := testEvalWith(context.Background(), input, func(ev *Evaluator) { ev.SetHTTPTransport(server.Client().Transport) })
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("input %q: wrong result. expected=%q, got=%q", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestHTTPErrors(t *testing.T) {
+	server := newEchoServer(t)
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`httpRequest("x")`, "argument to `httpRequest` must be MAP, got STRING"},
+		{`httpRequest({})`, "`httpRequest` requires a url"},
+		{`httpRequest({"url": 1})`, "url for `httpRequest` must be STRING, got NUMBER"},
+		{`httpGet("ftp://example.com")`, "invalid url for `httpRequest`: \"ftp://example.com\""},
+		{`httpGet("/relative")`, "invalid url for `httpRequest`: \"/relative\""},
+		{`httpRequest({"url": URL, "method": 1})`, "method for `httpRequest` must be STRING, got NUMBER"},
+		{`httpRequest({"url": URL, "headers": []})`, "headers for `httpRequest` must be MAP, got ARRAY"},
+		{`httpRequest({"url": URL, "body": 1})`, "body for `httpRequest` must be STRING, ARRAY or MAP, got NUMBER"},
+		{`httpRequest({"url": URL, "timeout": 0})`, "timeout for `httpRequest` must be a positive NUMBER of seconds, got 0"},
+		{`httpRequest({"url": URL + "/slow", "timeout": 0.05})`, "http request GET " + server.URL + "/slow timed out after 50ms"},
+		{`httpGet()`, "wrong number of arguments. got=0, want=1 or 2"},
+		{`httpPost(URL)`, "wrong number of arguments. got=1, want=2 or 3"},
+	}
+
+	for _, tt := range tests {
+		input := fmt.Sprintf("const URL = %q; %s", server.URL, tt.input)
+		evaluated := testEvalWith(context.Background(), input, func(ev *Evaluator) { ev.SetHTTPTransport(server.Client().Transport) })
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("input %q: object is not Error. got=%T (%+v)", tt.input, evaluated, evaluated)
+			continue
+		}
+		if errObj.Text() != tt.expected {
+			t.Errorf("input %q: wrong error message. expected=%q, got=%q", tt.input, tt.expected, errObj.Text())
+		}
+	}
+
+	// 通信の失敗は IOError で、catch できる
+	input := `try { httpGet("http://example.invalid") } catch (e) { e["kind"] }`
+	failing := roundTripperFunc(func(*http.Request) (*http.Response, error) {
+		return nil, fmt.Errorf("connection refused")
+	})
+	if evaluated := testEvalWith(context.Background(), input, func(ev *Evaluator) { ev.SetHTTPTransport(failing) }); evaluated.Inspect() != "IOError" {
+		t.Errorf("input %q: wrong result. got=%q", input, evaluated.Inspect())
+	}
+	evaluated := testEvalWith(context.Background(), `httpGet("http://example.invalid")`, func(ev *Evaluator) { ev.SetHTTPTransport(failing) })
+	if errObj, ok := evaluated.(*object.Error); !ok || errObj.Text() != "http request GET http://example.invalid failed: connection refused" {
+		t.Errorf("wrong error for failed request. got=%s", evaluated.Inspect())
+	}
+}
+
+func TestHTTPTransport(t *testing.T) {
+	// 設定した RoundTripper を通して通信する
+	var got *http.Request
+	transport := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		got = req
+		return &http.Response{
+			StatusCode: http.StatusCreated,
+			Header:     http.Header{"Content-Type": {"text/plain"}},
+			Body:       io.NopCloser(strings.NewReader("stubbed")),
+		}, nil
+	})
+	evaluated := testEvalWith(context.Background(), `httpPost("https://api.example.com/items", "x")`, func(ev *Evaluator) { ev.SetHTTPTransport(transport) })
+	if evaluated.Inspect() != "{status: 201, headers: {content-type: text/plain}, body: stubbed}" {
+		t.Errorf("wrong response. got=%q", evaluated.Inspect())
+	}
+	if got == nil || got.Method != "POST" || got.URL.String() != "https://api.example.com/items" {
+		t.Errorf("request was not sent through the transport. got=%v", got)
+	}
+
+	// 大きすぎるレスポンスボディは IOError
+	large := roundTripperFunc(func(*http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(strings.NewReader(strings.Repeat("x", maxResponseBodySize+1))),
+		}, nil
+	})
+	evaluated = testEvalWith(context.Background(), `httpGet("https://example.com/large")`, func(ev *Evaluator) { ev.SetHTTPTransport(large) })
+	expected := fmt.Sprintf("http response for GET https://example.com/large exceeds %d bytes", maxResponseBodySize)
+	if errObj, ok := evaluated.(*object.Error); !ok || errObj.Kind != object.IO_ERROR || errObj.Text() != expected {
+		t.Errorf("wrong error for large response. got=%s", evaluated.Inspect())
+	}
+
+	// nil の場合は通信を禁止する
+	evaluated = testEvalWith(context.Background(), `try { httpGet("https://example.com") } catch (e) { e["message"] }`, func(ev *Evaluator) { ev.SetHTTPTransport(nil) })
+	if evaluated.Inspect() != "network access is disabled" {
+		t.Errorf("network should be disabled. got=%q", evaluated.Inspect())
+	}
+}
+
+func TestHTTPContext(t *testing.T) {
+	server := newEchoServer(t)
+
+	// 実行の context が終わると、リクエストを中断して実行も中断する（catch できない）
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	input := fmt.Sprintf(`try { httpGet(%q) } catch (e) { "caught" }`, server.URL+"/slow")
+	evaluated := testEvalWith(ctx, input, func(ev *Evaluator) { ev.SetHTTPTransport(server.Client().Transport) })
+	errObj, ok := evaluated.(*object.Error)
+	if !ok {
+		t.Fatalf("expected limit error. got=%s", evaluated.Inspect())
+	}
+	if !strings.HasPrefix(errObj.Text(), LimitExceededMessage) {
+		t.Errorf("wrong error message. got=%q", errObj.Text())
+	}
+}
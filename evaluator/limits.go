@@ -0,0 +1,97 @@
+package evaluator
+
+import (
+	"context"
+	"fmt"
+	"sugu/ast"
+	"sugu/object"
+	"time"
+)
+
+// LimitExceededMessage は実行の上限に達したときのエラーメッセージの先頭
+const LimitExceededMessage = "execution limit exceeded"
+
+// DefaultMaxCallDepth は Limits.MaxCallDepth を指定しない場合の関数呼び出しの深さの上限
+// 深すぎる再帰で Go のスタックがあふれてプロセスが落ちる前にエラーにする
+const DefaultMaxCallDepth = 10000
+
+// contextCheckInterval は何ステップごとに context のキャンセルを確認するか
+const contextCheckInterval = 1024
+
+// Limits は 1 回の実行で使える資源の上限（0 は無制限）
+type Limits struct {
+	MaxSteps     int64         // 実行できるステップ数（文の実行とループの反復をそれぞれ 1 ステップと数える）
+	Timeout      time.Duration // 実行時間
+	MaxCallDepth int           // 関数呼び出しの深さ（0 の場合は DefaultMaxCallDepth）
+}
+
+// SetLimits は実行の上限を設定する
+// 上限は EvalContext（VM では vm.EvalContext）で評価を始めるたびに適用される
+func (e *Evaluator) SetLimits(limits Limits) {
+	e.limits = limits
+}
+
+// MaxCallDepth は関数呼び出しの深さの上限を返す
+// 上限を超える呼び出しは "maximum call depth exceeded" のエラーになる（try/catch でキャッチできる）
+func (e *Evaluator) MaxCallDepth() int {
+	if e.limits.MaxCallDepth > 0 {
+		return e.limits.MaxCallDepth
+	}
+	return DefaultMaxCallDepth
+}
+
+// EvalContext は ctx がキャンセルされるか上限に達するまで node を評価する
+// 上限に達した場合は "execution limit exceeded" で始まるエラーを返す
+func (e *Evaluator) EvalContext(ctx context.Context, node ast.Node, env *object.Environment) object.Object {
+	end := e.Begin(ctx)
+	defer end()
+	return e.Eval(node, env)
+}
+
+// Begin は ctx と設定された上限で新しい実行を開始し、終了するための関数を返す
+// ステップ数はここで 0 に戻る。VM バックエンドはこれを使って同じ上限を適用する
+func (e *Evaluator) Begin(ctx context.Context) (end func()) {
+	cancel := func() {}
+	if e.limits.Timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, e.limits.Timeout)
+	}
+
+	prevCtx, prevSteps := e.ctx, e.steps
+	e.ctx, e.steps, e.exceeded = ctx, 0, nil
+	return func() {
+		cancel()
+		e.ctx, e.steps = prevCtx, prevSteps
+	}
+}
+
+// Step は実行ステップを 1 つ進め、上限に達していればエラーを返す
+func (e *Evaluator) Step() *object.Error {
+	if e.exceeded != nil {
+		return e.exceeded
+	}
+
+	e.steps++
+	if e.limits.MaxSteps > 0 && e.steps > e.limits.MaxSteps {
+		return e.exceed(fmt.Sprintf("maximum steps (%d)", e.limits.MaxSteps))
+	}
+
+	// context の確認は最初のステップと一定間隔ごとに行う
+	if e.ctx != nil && e.steps%contextCheckInterval == 1 {
+		if err := e.ctx.Err(); err != nil {
+			return e.exceed(err.Error())
+		}
+	}
+	return nil
+}
+
+// LimitExceeded は上限に達して実行を中断しているかを返す
+// 中断中のエラーは try/catch でキャッチできない
+func (e *Evaluator) LimitExceeded() bool {
+	return e.exceeded != nil
+}
+
+// exceed は上限に達したことを記録してエラーを返す
+func (e *Evaluator) exceed(reason string) *object.Error {
+	e.exceeded = newError(object.LIMIT_ERROR, "%s: %s", LimitExceededMessage, reason)
+	return e.exceeded
+}
@@ -0,0 +1,170 @@
+package evaluator
+
+import (
+	"context"
+	"strings"
+	"sugu/lexer"
+	"sugu/object"
+	"sugu/parser"
+	"testing"
+	"time"
+)
+
+func TestMaxSteps(t *testing.T) {
+	tests := []struct {
+		input    string
+		maxSteps int64
+		expected interface{}
+	}{
+		{"1; 2; 3", 3, 3.0},
+		{"1; 2; 3", 2, "execution limit exceeded: maximum steps (2)"},
+		// 文とループの反復がそれぞれ 1 ステップ
+		{"mut i = 0; while (i < 3) { i++; } i", 10, 3.0},
+		{"mut i = 0; while (i < 3) { i++; } i", 9, "execution limit exceeded: maximum steps (9)"},
+		{"mut s = 0; for (x in [1, 2, 3]) { s += x; } s", 9, 6.0},
+		{"mut s = 0; for (mut i = 0; i < 3; i++) { s += i; } s", 10, 3.0},
+		{"while (true) {}", 100, "execution limit exceeded: maximum steps (100)"},
+		{"for (;;) {}", 100, "execution limit exceeded: maximum steps (100)"},
+		{"func f() => { f() }; f()", 1000, "execution limit exceeded: maximum steps (1000)"},
+		// 上限に達したエラーは catch できない
+		{"try { while (true) {} } catch (e) { 1 }", 50, "execution limit exceeded: maximum steps (50)"},
+		{"while (true) { try { while (true) {} } catch (e) {} }", 50, "execution limit exceeded: maximum steps (50)"},
+		{"try { while (true) {} } finally { 1 }", 50, "execution limit exceeded: maximum steps (50)"},
+		// 0 は無制限
+		{"mut i = 0; while (i < 10000) { i++; } i", 0, 10000.0},
+	}
+
+	for _, tt := range tests {
+		result := testEvalWith(context.Background(), tt.input, func(ev *Evaluator) { ev.SetLimits(Limits{MaxSteps: tt.maxSteps}) })
+		switch expected := tt.expected.(type) {
+		case float64:
+			testNumberObject(t, result, expected)
+		case string:
+			errObj, ok := result.(*object.Error)
+			if !ok {
+				t.Errorf("no error object returned for %q. got=%T(%+v)", tt.input, result, result)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message for %q. expected=%q, got=%q", tt.input, expected, errObj.Message)
+			}
+		}
+	}
+}
+
+func TestContextCancellation(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	result := testEvalWith(ctx, "while (true) {}", nil)
+	errObj, ok := result.(*object.Error)
+	if !ok {
+		t.Fatalf("no error object returned. got=%T(%+v)", result, result)
+	}
+	if errObj.Message != "execution limit exceeded: context canceled" {
+		t.Errorf("wrong error message. got=%q", errObj.Message)
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	tests := []struct {
+		name   string
+		ctx    func() (context.Context, context.CancelFunc)
+		limits Limits
+	}{
+		{
+			"limits",
+			func() (context.Context, context.CancelFunc) { return context.Background(), func() {} },
+			Limits{Timeout: 20 * time.Millisecond},
+		},
+		{
+			"context deadline",
+			func() (context.Context, context.CancelFunc) {
+				return context.WithTimeout(context.Background(), 20*time.Millisecond)
+			},
+			Limits{},
+		},
+	}
+
+	for _, tt := range tests {
+		ctx, cancel := tt.ctx()
+		start := time.Now()
+		result := testEvalWith(ctx, "mut i = 0; while (true) { i++; }", func(ev *Evaluator) { ev.SetLimits(tt.limits) })
+		cancel()
+
+		errObj, ok := result.(*object.Error)
+		if !ok {
+			t.Errorf("%s: no error object returned. got=%T(%+v)", tt.name, result, result)
+			continue
+		}
+		if errObj.Message != "execution limit exceeded: context deadline exceeded" {
+			t.Errorf("%s: wrong error message. got=%q", tt.name, errObj.Message)
+		}
+		if elapsed := time.Since(start); elapsed > 2*time.Second {
+			t.Errorf("%s: took too long to stop: %s", tt.name, elapsed)
+		}
+	}
+}
+
+func TestStepsAreCountedPerEvaluation(t *testing.T) {
+	ev := New("")
+	ev.SetLimits(Limits{MaxSteps: 5})
+	env := object.NewEnvironment()
+
+	// 同じ Evaluator で評価し直すとステップ数は 0 から数え直す
+	for i := 0; i < 3; i++ {
+		program := parser.New(lexer.New("1; 2; 3; 4; 5")).ParseProgram()
+		result := testEngine(context.Background(), ev, program, env)
+		if isError(result) {
+			t.Fatalf("evaluation %d failed: %s", i, result.Inspect())
+		}
+	}
+
+	program := parser.New(lexer.New("1; 2; 3; 4; 5; 6")).ParseProgram()
+	result := testEngine(context.Background(), ev, program, env)
+	if !isError(result) || !strings.HasPrefix(result.(*object.Error).Message, LimitExceededMessage) {
+		t.Errorf("expected limit error. got=%s", result.Inspect())
+	}
+}
+
+func TestMaxCallDepth(t *testing.T) {
+	recursive := "func f(n) => { if (n == 0) { return 0; } return 1 + f(n - 1); }\n"
+	tests := []struct {
+		input        string
+		maxCallDepth int
+		expected     interface{}
+	}{
+		{recursive + "f(100)", 101, 100.0},
+		{recursive + "f(100)", 100, "line 1, column 54: maximum call depth exceeded (100)"},
+		// 既定の上限でも Go のスタックがあふれる前にエラーになる
+		{recursive + "f(5000)", 0, 5000.0},
+		{recursive + "f(1000000)", 0, "line 1, column 54: maximum call depth exceeded (10000)"},
+		// 呼び出しの深さのエラーは catch できる
+		{recursive + `try { f(1000) } catch (e) { "caught" }`, 10, "caught"},
+		// 組み込み関数から呼び出した関数も深さに数える
+		{"func g(n) => { return map([n], g); }\ng(0)", 50, "line 1, column 26: maximum call depth exceeded (50)"},
+	}
+
+	for _, tt := range tests {
+		result := testEvalWith(context.Background(), tt.input, func(ev *Evaluator) { ev.SetLimits(Limits{MaxCallDepth: tt.maxCallDepth}) })
+		switch expected := tt.expected.(type) {
+		case float64:
+			testNumberObject(t, result, expected)
+		case string:
+			if str, ok := result.(*object.String); ok {
+				if str.Value != expected {
+					t.Errorf("wrong value for %q. expected=%q, got=%q", tt.input, expected, str.Value)
+				}
+				continue
+			}
+			errObj, ok := result.(*object.Error)
+			if !ok {
+				t.Errorf("no error object returned for %q. got=%T(%+v)", tt.input, result, result)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message for %q. expected=%q, got=%q", tt.input, expected, errObj.Message)
+			}
+		}
+	}
+}
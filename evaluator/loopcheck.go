@@ -0,0 +1,187 @@
+package evaluator
+
+import (
+	"sugu/ast"
+	"sugu/object"
+	"sugu/token"
+)
+
+// loopChecker はループの外にある break / continue を探す
+// VM はコンパイル時にこれらを "break outside loop" などのエラーにするため、
+// 木構造評価器でも実行前に同じエラーにして、関数の中の break が呼び出し元のループを止めないようにする
+type loopChecker struct {
+	inLoop   bool        // ループの中（continue を受け取れる）
+	inSwitch bool        // switch の case の中（break だけを受け取れる）
+	tok      token.Token // 見つかった break / continue のトークン
+	msg      string      // そのエラーメッセージ
+}
+
+// checkLoopControl は program のループの外の break / continue を VM のコンパイラと同じエラーにする（なければ nil）
+func (e *Evaluator) checkLoopControl(program *ast.Program) *object.Error {
+	c := &loopChecker{}
+	for _, stmt := range program.Statements {
+		if c.statement(stmt) {
+			return e.ErrorAt(e.file, c.tok.Line, c.tok.Column, object.SYNTAX_ERROR, "%s", c.msg)
+		}
+	}
+	return nil
+}
+
+// statement は文を調べ、ループの外の break / continue が見つかれば true を返す
+func (c *loopChecker) statement(stmt ast.Statement) bool {
+	switch stmt := stmt.(type) {
+	case *ast.BreakStatement:
+		if !c.inLoop && !c.inSwitch {
+			c.tok, c.msg = stmt.Token, "break outside loop"
+			return true
+		}
+	case *ast.ContinueStatement:
+		if !c.inLoop {
+			c.tok, c.msg = stmt.Token, "continue outside loop"
+			return true
+		}
+	case *ast.ExpressionStatement:
+		return c.expression(stmt.Expression)
+	case *ast.VariableStatement:
+		return c.pattern(stmt.Pattern) || c.expression(stmt.Value)
+	case *ast.ReturnStatement:
+		return c.expression(stmt.ReturnValue)
+	case *ast.ThrowStatement:
+		return c.expression(stmt.Value)
+	case *ast.ExportStatement:
+		return c.statement(stmt.Statement)
+	case *ast.BlockStatement:
+		return c.block(stmt)
+	case *ast.IfStatement:
+		return c.expression(stmt.Condition) || c.block(stmt.Consequence) || c.block(stmt.Alternative)
+	case *ast.WhileStatement:
+		return c.expression(stmt.Condition) || c.loopBody(stmt.Body)
+	case *ast.ForStatement:
+		return c.statement(stmt.Init) || c.expression(stmt.Condition) || c.expression(stmt.Update) || c.loopBody(stmt.Body)
+	case *ast.ForInStatement:
+		return c.pattern(stmt.KeyPattern) || c.pattern(stmt.ValuePattern) || c.expression(stmt.Iterable) || c.loopBody(stmt.Body)
+	case *ast.SwitchStatement:
+		if c.expression(stmt.Value) {
+			return true
+		}
+		for _, cc := range stmt.Cases {
+			if c.expression(cc.Value) || c.switchBody(cc.Body) {
+				return true
+			}
+		}
+		return c.switchBody(stmt.Default)
+	case *ast.TryStatement:
+		return c.block(stmt.TryBlock) || c.block(stmt.CatchBlock) || c.block(stmt.FinallyBlock)
+	}
+	return false
+}
+
+// block はブロックの文を順に調べる（nil のブロックは何もしない）
+func (c *loopChecker) block(block *ast.BlockStatement) bool {
+	if block == nil {
+		return false
+	}
+	for _, stmt := range block.Statements {
+		if c.statement(stmt) {
+			return true
+		}
+	}
+	return false
+}
+
+// loopBody はループの本体を調べる（break と continue を受け取れる）
+func (c *loopChecker) loopBody(body *ast.BlockStatement) bool {
+	inLoop, inSwitch := c.inLoop, c.inSwitch
+	c.inLoop, c.inSwitch = true, false
+	found := c.block(body)
+	c.inLoop, c.inSwitch = inLoop, inSwitch
+	return found
+}
+
+// switchBody は case の本体を調べる（break だけを受け取れる。continue は外側のループに任せる）
+func (c *loopChecker) switchBody(body *ast.BlockStatement) bool {
+	inSwitch := c.inSwitch
+	c.inSwitch = true
+	found := c.block(body)
+	c.inSwitch = inSwitch
+	return found
+}
+
+// expression は式の中の関数リテラルを探して調べる
+func (c *loopChecker) expression(exp ast.Expression) bool {
+	switch exp := exp.(type) {
+	case *ast.FunctionLiteral:
+		// 関数の本体は外側のループとは別に調べる
+		inLoop, inSwitch := c.inLoop, c.inSwitch
+		c.inLoop, c.inSwitch = false, false
+		found := false
+		for i := 0; i < len(exp.Defaults) && !found; i++ {
+			found = c.expression(exp.Defaults[i])
+		}
+		for i := 0; i < len(exp.Patterns) && !found; i++ {
+			found = c.pattern(exp.Patterns[i])
+		}
+		found = found || c.block(exp.Body)
+		c.inLoop, c.inSwitch = inLoop, inSwitch
+		return found
+	case *ast.TemplateLiteral:
+		return c.expressions(exp.Expressions)
+	case *ast.PrefixExpression:
+		return c.expression(exp.Right)
+	case *ast.InfixExpression:
+		return c.expression(exp.Left) || c.expression(exp.Right)
+	case *ast.CallExpression:
+		return c.expression(exp.Function) || c.expressions(exp.Arguments)
+	case *ast.AssignExpression:
+		return c.expression(exp.Value)
+	case *ast.ArrayLiteral:
+		return c.expressions(exp.Elements)
+	case *ast.MapLiteral:
+		for _, pair := range exp.Pairs {
+			if c.expression(pair.Key) || c.expression(pair.Value) {
+				return true
+			}
+		}
+	case *ast.IndexExpression:
+		return c.expression(exp.Left) || c.expression(exp.Index)
+	case *ast.IndexAssignExpression:
+		return c.expression(exp.Left) || c.expression(exp.Index) || c.expression(exp.Value)
+	case *ast.PostfixExpression:
+		return c.expression(exp.Operand)
+	case *ast.CompoundAssignExpression:
+		return c.expression(exp.Value)
+	case *ast.IndexCompoundAssignExpression:
+		return c.expression(exp.Left) || c.expression(exp.Index) || c.expression(exp.Value)
+	case *ast.SliceExpression:
+		return c.expression(exp.Left) || c.expression(exp.Low) || c.expression(exp.High)
+	}
+	return false
+}
+
+func (c *loopChecker) expressions(exps []ast.Expression) bool {
+	for _, exp := range exps {
+		if c.expression(exp) {
+			return true
+		}
+	}
+	return false
+}
+
+// pattern は分割代入のパターンのマップのキーの式を調べる
+func (c *loopChecker) pattern(p ast.Pattern) bool {
+	switch p := p.(type) {
+	case *ast.ArrayPattern:
+		for _, elem := range p.Elements {
+			if c.pattern(elem) {
+				return true
+			}
+		}
+	case *ast.MapPattern:
+		for _, pair := range p.Pairs {
+			if c.expression(pair.Key) || c.pattern(pair.Value) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,153 @@
+package evaluator
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sugu/ast"
+	"sugu/lexer"
+	"sugu/object"
+	"sugu/parser"
+)
+
+// module は読み込み済みのモジュールを表す
+type module struct {
+	path    string              // モジュールファイルのパス（REPL などでは空文字列）
+	name    string              // エラー表示用の名前（最初に評価されたファイルのディレクトリからの相対パス）
+	env     *object.Environment // モジュール専用のスコープ
+	exports []string            // export された識別子（宣言順）
+}
+
+// evalImportStatement はimport文を評価し、export された識別子を現在のスコープに束縛する
+func (e *Evaluator) evalImportStatement(node *ast.ImportStatement, env *object.Environment) object.Object {
+	path := e.resolveModulePath(node.Path.Value)
+
+	mod, errObj := e.loadModule(path, node)
+	if errObj != nil {
+		return errObj
+	}
+
+	for _, name := range mod.exports {
+		val, _ := mod.env.Get(name)
+		if mod.env.IsConst(name) {
+			env.SetConst(name, val)
+		} else {
+			env.Set(name, val)
+		}
+	}
+
+	return NULL
+}
+
+// evalExportStatement はexport文を評価し、識別子を現在のモジュールのエクスポートに追加する
+func (e *Evaluator) evalExportStatement(node *ast.ExportStatement, env *object.Environment) object.Object {
+	val := e.Eval(node.Statement, env)
+	if isError(val) {
+		return val
+	}
+
+	e.addExport(node.Name.Value)
+	return val
+}
+
+// addExport は識別子を現在のモジュールのエクスポートに追加する（重複は無視する）
+func (e *Evaluator) addExport(name string) {
+	for _, exported := range e.current.exports {
+		if exported == name {
+			return
+		}
+	}
+	e.current.exports = append(e.current.exports, name)
+}
+
+// resolveModulePath は import のパスを import を書いたファイルのディレクトリ基準で解決する
+func (e *Evaluator) resolveModulePath(path string) string {
+	if !filepath.IsAbs(path) {
+		dir := "."
+		if e.current.path != "" {
+			dir = filepath.Dir(e.current.path)
+		}
+		path = filepath.Join(dir, path)
+	}
+
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return filepath.Clean(path)
+}
+
+// loadModule はモジュールを読み込んで専用のスコープで評価する
+// 評価済みのモジュールはキャッシュを返すため、同じモジュールは一度しか評価されない
+func (e *Evaluator) loadModule(path string, node *ast.ImportStatement) (*module, *object.Error) {
+	if mod, ok := e.modules[path]; ok {
+		return mod, nil
+	}
+
+	// 評価中のモジュールを再度 import しようとした場合は循環 import
+	for _, loading := range e.loading {
+		if loading.path == path {
+			return nil, e.newErrorWithPos(node.Token.Line, node.Token.Column,
+				object.IMPORT_ERROR, "circular import: %s", e.importChain(path))
+		}
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, e.newErrorWithPos(node.Token.Line, node.Token.Column,
+			object.IMPORT_ERROR, "failed to import %q: %s", node.Path.Value, err.Error())
+	}
+
+	l := lexer.New(string(content))
+	p := parser.New(l)
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		return nil, e.newErrorWithPos(node.Token.Line, node.Token.Column,
+			object.IMPORT_ERROR, "failed to parse module %q: %s", node.Path.Value, p.Errors()[0])
+	}
+
+	mod := &module{path: path, name: e.moduleName(path), env: object.NewEnvironment()}
+	e.addSource(mod.name, string(content))
+
+	prev, prevFile := e.current, e.file
+	e.current, e.file = mod, mod.name
+	e.loading = append(e.loading, mod)
+	var result object.Object
+	if e.engine != nil {
+		result = e.engine(program, mod.env)
+	} else {
+		result = e.Eval(program, mod.env)
+	}
+	e.loading = e.loading[:len(e.loading)-1]
+	e.current, e.file = prev, prevFile
+
+	if errObj, ok := result.(*object.Error); ok {
+		return nil, errObj
+	}
+
+	e.modules[path] = mod
+	return mod, nil
+}
+
+// moduleName はエラー表示用に、最初に評価されたファイルのディレクトリからの相対パスを返す
+func (e *Evaluator) moduleName(path string) string {
+	base := "."
+	if e.root.path != "" {
+		base = filepath.Dir(e.root.path)
+	}
+	if absBase, err := filepath.Abs(base); err == nil {
+		if rel, err := filepath.Rel(absBase, path); err == nil {
+			return filepath.ToSlash(rel)
+		}
+	}
+	return path
+}
+
+// importChain は評価中の import の連鎖に path を加えて "a.sugu -> b.sugu" 形式で返す
+func (e *Evaluator) importChain(path string) string {
+	names := []string{}
+	for _, mod := range e.loading {
+		names = append(names, mod.name)
+	}
+	names = append(names, e.moduleName(path))
+	return strings.Join(names, " -> ")
+}
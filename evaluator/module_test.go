@@ -0,0 +1,281 @@
+package evaluator
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sugu/lexer"
+	"sugu/object"
+	"sugu/parser"
+	"testing"
+)
+
+// writeModules はテスト用のモジュールファイルを dir 以下に作成する
+func writeModules(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+}
+
+// testEvalFile はファイルを読み込み、そのファイルを基準に評価する
+func testEvalFile(t *testing.T, path string) object.Object {
+	t.Helper()
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read %s: %v", path, err)
+	}
+	l := lexer.New(string(content))
+	p := parser.New(l)
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors: %v", p.Errors())
+	}
+	return testEngine(context.Background(), New(path), program, object.NewEnvironment())
+}
+
+func TestImportExportedIdentifiers(t *testing.T) {
+	dir := t.TempDir()
+	writeModules(t, dir, map[string]string{
+		"lib.sugu": `
+export const PI = 3;
+export func add(a, b) => {
+	return a + b;
+}
+func helper() => {
+	return 100;
+}
+export mut scale = helper();
+`,
+		"main.sugu": `
+import "lib.sugu";
+add(PI, scale);
+`,
+	})
+
+	evaluated := testEvalFile(t, filepath.Join(dir, "main.sugu"))
+	testNumberObject(t, evaluated, 103)
+}
+
+func TestImportIsolatesModuleScope(t *testing.T) {
+	tests := []struct {
+		name     string
+		files    map[string]string
+		expected string
+	}{
+		{
+			"non-exported identifier is not visible",
+			map[string]string{
+				"lib.sugu":  `const secret = 1; export const open = 2;`,
+				"main.sugu": "import \"lib.sugu\";\nsecret;",
+			},
+			"main.sugu, line 2, column 1: identifier not found: secret",
+		},
+		{
+			"module cannot see importer variables",
+			map[string]string{
+				"lib.sugu":  `export const value = fromMain;`,
+				"main.sugu": "const fromMain = 1;\nimport \"lib.sugu\";",
+			},
+			"lib.sugu, line 1, column 22: identifier not found: fromMain",
+		},
+		{
+			"exported const stays const",
+			map[string]string{
+				"lib.sugu":  `export const PI = 3.14;`,
+				"main.sugu": "import \"lib.sugu\";\nPI = 3;",
+			},
+			"main.sugu, line 2, column 4: cannot reassign to const variable: PI",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeModules(t, dir, tt.files)
+
+			evaluated := testEvalFile(t, filepath.Join(dir, "main.sugu"))
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Fatalf("no error object returned. got=%T (%+v)", evaluated, evaluated)
+			}
+			if errObj.Message != tt.expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", tt.expected, errObj.Message)
+			}
+		})
+	}
+}
+
+func TestImportRelativeToImportingFile(t *testing.T) {
+	dir := t.TempDir()
+	writeModules(t, dir, map[string]string{
+		"app/main.sugu": `import "../lib/a.sugu"; twice(base);`,
+		"lib/a.sugu": `
+import "util/b.sugu";
+export func twice(x) => {
+	return double(x);
+}
+export const base = 21;
+`,
+		"lib/util/b.sugu": `export func double(x) => { return x * 2; }`,
+	})
+
+	evaluated := testEvalFile(t, filepath.Join(dir, "app", "main.sugu"))
+	testNumberObject(t, evaluated, 42)
+}
+
+func TestImportEvaluatesModuleOnce(t *testing.T) {
+	dir := t.TempDir()
+	logFile := filepath.Join(dir, "log.txt")
+	writeModules(t, dir, map[string]string{
+		"counter.sugu": `
+appendFile("` + filepath.ToSlash(logFile) + `", "loaded;");
+export const value = 1;
+`,
+		"a.sugu": `import "counter.sugu"; export const fromA = value;`,
+		"main.sugu": `
+import "counter.sugu";
+import "a.sugu";
+import "counter.sugu";
+value + fromA;
+`,
+	})
+
+	evaluated := testEvalFile(t, filepath.Join(dir, "main.sugu"))
+	testNumberObject(t, evaluated, 2)
+
+	content, err := os.ReadFile(logFile)
+	if err != nil {
+		t.Fatalf("failed to read log: %v", err)
+	}
+	if string(content) != "loaded;" {
+		t.Errorf("module should be evaluated once. log=%q", string(content))
+	}
+}
+
+func TestImportErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		files    map[string]string
+		expected string
+	}{
+		{
+			"missing file",
+			map[string]string{
+				"main.sugu": `import "missing.sugu";`,
+			},
+			`main.sugu, line 1, column 1: failed to import "missing.sugu"`,
+		},
+		{
+			"parse error in module",
+			map[string]string{
+				"broken.sugu": `mut x`,
+				"main.sugu":   `import "broken.sugu";`,
+			},
+			`main.sugu, line 1, column 1: failed to parse module "broken.sugu": line 1, column 6: expected next token to be =, got EOF instead`,
+		},
+		{
+			"runtime error in module",
+			map[string]string{
+				"bad.sugu":  `export const x = undefinedVar;`,
+				"main.sugu": `import "bad.sugu";`,
+			},
+			"bad.sugu, line 1, column 18: identifier not found: undefinedVar",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeModules(t, dir, tt.files)
+
+			evaluated := testEvalFile(t, filepath.Join(dir, "main.sugu"))
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Fatalf("no error object returned. got=%T (%+v)", evaluated, evaluated)
+			}
+			if !strings.HasPrefix(errObj.Message, tt.expected) {
+				t.Errorf("wrong error message. expected prefix=%q, got=%q", tt.expected, errObj.Message)
+			}
+		})
+	}
+}
+
+func TestCircularImport(t *testing.T) {
+	tests := []struct {
+		name     string
+		files    map[string]string
+		expected string
+	}{
+		{
+			"indirect cycle",
+			map[string]string{
+				"main.sugu": `import "a.sugu";`,
+				"a.sugu":    `import "b.sugu"; export const a = 1;`,
+				"b.sugu":    "const b = 2;\n  import \"a.sugu\";",
+			},
+			"b.sugu, line 2, column 3: circular import: main.sugu -> a.sugu -> b.sugu -> a.sugu",
+		},
+		{
+			"self import",
+			map[string]string{
+				"main.sugu":     `import "lib/self.sugu";`,
+				"lib/self.sugu": `import "self.sugu";`,
+			},
+			"lib/self.sugu, line 1, column 1: circular import: main.sugu -> lib/self.sugu -> lib/self.sugu",
+		},
+		{
+			"import of the main file",
+			map[string]string{
+				"main.sugu": `import "a.sugu";`,
+				"a.sugu":    `import "main.sugu";`,
+			},
+			"a.sugu, line 1, column 1: circular import: main.sugu -> a.sugu -> main.sugu",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeModules(t, dir, tt.files)
+
+			evaluated := testEvalFile(t, filepath.Join(dir, "main.sugu"))
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Fatalf("no error object returned. got=%T (%+v)", evaluated, evaluated)
+			}
+			if errObj.Message != tt.expected {
+				t.Errorf("wrong error message.\nexpected=%q\ngot=%q", tt.expected, errObj.Message)
+			}
+		})
+	}
+}
+
+func TestErrorPositionInImportedFunction(t *testing.T) {
+	dir := t.TempDir()
+	writeModules(t, dir, map[string]string{
+		"lib/util.sugu": "export func broken() => {\n  return missing;\n}",
+		"main.sugu":     "import \"lib/util.sugu\";\nbroken();",
+	})
+
+	evaluated := testEvalFile(t, filepath.Join(dir, "main.sugu"))
+	errObj, ok := evaluated.(*object.Error)
+	if !ok {
+		t.Fatalf("no error object returned. got=%T (%+v)", evaluated, evaluated)
+	}
+
+	expected := "lib/util.sugu, line 2, column 10: identifier not found: missing"
+	if errObj.Message != expected {
+		t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+	}
+	if errObj.File != "lib/util.sugu" || errObj.Line != 2 || errObj.Column != 10 {
+		t.Errorf("wrong error position. got=%s:%d:%d", errObj.File, errObj.Line, errObj.Column)
+	}
+}
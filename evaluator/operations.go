@@ -0,0 +1,118 @@
+package evaluator
+
+import (
+	"fmt"
+	"sugu/ast"
+	"sugu/object"
+)
+
+// このファイルは木構造評価器と VM バックエンド（vm パッケージ）で共有する処理をまとめる
+// 両方のバックエンドが同じ関数を使うことで、演算の結果とエラーメッセージが一致する
+
+// Engine はプログラムを評価するバックエンド
+type Engine func(program *ast.Program, env *object.Environment) object.Object
+
+// SetEngine は import したモジュールのトップレベルを評価するバックエンドを設定する
+// 設定しない場合は木構造評価器で評価する
+func (e *Evaluator) SetEngine(engine Engine) {
+	e.engine = engine
+}
+
+// File は現在評価中のコードのファイル名を返す（ファイルを持たない場合は空文字列）
+func (e *Evaluator) File() string {
+	return e.file
+}
+
+// Import はimport文を評価し、export された識別子を env に束縛する
+func (e *Evaluator) Import(node *ast.ImportStatement, env *object.Environment) object.Object {
+	return e.evalImportStatement(node, env)
+}
+
+// Export は識別子を現在のモジュールのエクスポートに追加する
+func (e *Evaluator) Export(name string) {
+	e.addExport(name)
+}
+
+// LookupBuiltin は組み込み関数を名前で探す
+func (e *Evaluator) LookupBuiltin(name string) (*object.Builtin, bool) {
+	builtin, ok := e.builtins[name]
+	return builtin, ok
+}
+
+// NewErrorAt は file の line 行 column 列の位置情報付きの kind の種類のエラーを作成する
+func NewErrorAt(file string, line, column int, kind, format string, a ...interface{}) *object.Error {
+	msg := fmt.Sprintf(format, a...)
+	pos := object.FormatPosition(file, line, column)
+	return &object.Error{Message: pos + ": " + msg, Kind: kind, File: file, Line: line, Column: column}
+}
+
+// InfixOperation は中置演算子を適用する（&& と || は除く）
+func InfixOperation(operator string, left, right object.Object) object.Object {
+	return evalInfixExpression(operator, left, right)
+}
+
+// PrefixOperation は前置演算子を適用する
+func PrefixOperation(operator string, right object.Object) object.Object {
+	return evalPrefixExpression(operator, right)
+}
+
+// IndexOperation はインデックスアクセスを行う
+func IndexOperation(left, index object.Object) object.Object {
+	return evalIndexExpression(left, index)
+}
+
+// IndexAssign は配列またはマップの要素に値を代入する
+func IndexAssign(left, index, val object.Object) object.Object {
+	switch obj := left.(type) {
+	case *object.Array:
+		return evalArrayIndexAssignment(obj, index, val)
+	case *object.Map:
+		return evalMapIndexAssignment(obj, index, val)
+	default:
+		return newError(object.TYPE_ERROR, "index assignment not supported: %s", left.Type())
+	}
+}
+
+// SliceIndex はスライスのインデックスが数値か確認して整数に変換する
+func SliceIndex(index object.Object) (int64, *object.Error) {
+	if index.Type() != object.NUMBER_OBJ {
+		return 0, newError(object.TYPE_ERROR, "slice index must be a number, got %s", index.Type())
+	}
+	return int64(index.(*object.Number).Value), nil
+}
+
+// SliceOperation はスライスを行う（hasLow / hasHigh が false のインデックスは省略扱い）
+func SliceOperation(left object.Object, low, high int64, hasLow, hasHigh bool) object.Object {
+	switch obj := left.(type) {
+	case *object.Array:
+		return evalArraySlice(obj, low, high, hasLow, hasHigh)
+	case *object.String:
+		return evalStringSlice(obj, low, high, hasLow, hasHigh)
+	default:
+		return newError(object.TYPE_ERROR, "slice operator not supported: %s", left.Type())
+	}
+}
+
+// HashKey はマップのキーに使える値か確認する
+func HashKey(key object.Object) (object.Hashable, *object.Error) {
+	hashKey, ok := key.(object.Hashable)
+	if !ok {
+		return nil, newError(object.TYPE_ERROR, "unusable as hash key: %s", key.Type())
+	}
+	return hashKey, nil
+}
+
+// IsTruthy は値が真とみなされるかを返す
+func IsTruthy(obj object.Object) bool {
+	return isTruthy(obj)
+}
+
+// IsEqual は == と switch の case の比較と同じ規則で値を比較する
+func IsEqual(a, b object.Object) bool {
+	return isEqual(a, b)
+}
+
+// NativeBool は Go の bool を TRUE / FALSE に変換する
+func NativeBool(input bool) *object.Boolean {
+	return nativeBoolToBooleanObject(input)
+}
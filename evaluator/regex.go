@@ -0,0 +1,168 @@
+package evaluator
+
+import (
+	"regexp"
+	"strings"
+	"sugu/object"
+	"unicode/utf8"
+)
+
+// regexCacheSize はコンパイル済みの正規表現を保持する最大の数（超えたらキャッシュを空にする）
+const regexCacheSize = 256
+
+// regexBuiltins は正規表現（Go の regexp の構文）を使う文字列の組み込み関数を作成する
+// パターンは Evaluator ごとにキャッシュされ、ループの中で同じパターンを使っても再コンパイルしない
+func (e *Evaluator) regexBuiltins() map[string]*object.Builtin {
+	return map[string]*object.Builtin{
+		"regexMatch": {
+			Fn: func(args ...object.Object) object.Object {
+				str, re, errObj := e.stringAndRegex("regexMatch", args)
+				if errObj != nil {
+					return errObj
+				}
+				return nativeBoolToBooleanObject(re.MatchString(str))
+			},
+		},
+		"regexFind": {
+			Fn: func(args ...object.Object) object.Object {
+				str, re, errObj := e.stringAndRegex("regexFind", args)
+				if errObj != nil {
+					return errObj
+				}
+				loc := re.FindStringSubmatchIndex(str)
+				if loc == nil {
+					return NULL
+				}
+				return regexMatchMap(re, str, loc)
+			},
+		},
+		"regexFindAll": {
+			Fn: func(args ...object.Object) object.Object {
+				str, re, errObj := e.stringAndRegex("regexFindAll", args)
+				if errObj != nil {
+					return errObj
+				}
+				locs := re.FindAllStringSubmatchIndex(str, -1)
+				elements := make([]object.Object, len(locs))
+				for i, loc := range locs {
+					elements[i] = regexMatchMap(re, str, loc)
+				}
+				return &object.Array{Elements: elements}
+			},
+		},
+		"regexReplace": {
+			Fn: func(args ...object.Object) object.Object {
+				if len(args) != 3 {
+					return newError(object.ARGUMENT_ERROR, "wrong number of arguments. got=%d, want=3", len(args))
+				}
+				str, re, errObj := e.stringAndRegex("regexReplace", args[:2])
+				if errObj != nil {
+					return errObj
+				}
+				switch repl := args[2].(type) {
+				case *object.String:
+					return &object.String{Value: re.ReplaceAllString(str, repl.Value)}
+				case *object.Function, *object.Builtin:
+					return e.regexReplaceFunc(re, str, repl)
+				}
+				return newError(object.TYPE_ERROR, "third argument to `regexReplace` must be STRING or FUNCTION, got %s", args[2].Type())
+			},
+		},
+		"regexSplit": {
+			Fn: func(args ...object.Object) object.Object {
+				str, re, errObj := e.stringAndRegex("regexSplit", args)
+				if errObj != nil {
+					return errObj
+				}
+				parts := re.Split(str, -1)
+				elements := make([]object.Object, len(parts))
+				for i, p := range parts {
+					elements[i] = &object.String{Value: p}
+				}
+				return &object.Array{Elements: elements}
+			},
+		},
+	}
+}
+
+// stringAndRegex は (文字列, パターン) の 2 つの引数を確認し、パターンをコンパイルする
+func (e *Evaluator) stringAndRegex(name string, args []object.Object) (string, *regexp.Regexp, *object.Error) {
+	if len(args) != 2 {
+		return "", nil, newError(object.ARGUMENT_ERROR, "wrong number of arguments. got=%d, want=2", len(args))
+	}
+	str, ok := args[0].(*object.String)
+	if !ok {
+		return "", nil, newError(object.TYPE_ERROR, "first argument to `%s` must be STRING, got %s", name, args[0].Type())
+	}
+	pattern, ok := args[1].(*object.String)
+	if !ok {
+		return "", nil, newError(object.TYPE_ERROR, "second argument to `%s` must be STRING, got %s", name, args[1].Type())
+	}
+	re, errObj := e.compileRegex(name, pattern.Value)
+	if errObj != nil {
+		return "", nil, errObj
+	}
+	return str.Value, re, nil
+}
+
+// compileRegex はパターンをコンパイルする（コンパイル済みであればキャッシュのものを返す）
+func (e *Evaluator) compileRegex(name, pattern string) (*regexp.Regexp, *object.Error) {
+	if re, ok := e.regexps[pattern]; ok {
+		return re, nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, newError(object.VALUE_ERROR, "invalid regular expression in `%s`: %s", name, strings.TrimPrefix(err.Error(), "error parsing regexp: "))
+	}
+	if e.regexps == nil || len(e.regexps) >= regexCacheSize {
+		e.regexps = make(map[string]*regexp.Regexp)
+	}
+	e.regexps[pattern] = re
+	return re, nil
+}
+
+// regexMatchMap は一致 loc（FindStringSubmatchIndex の結果）を次のマップにする
+//
+//	"match"  一致した文字列
+//	"index"  一致した位置（rune 単位）
+//	"groups" キャプチャグループの配列（一致しなかったグループは null）
+//	"named"  名前付きグループの名前と値のマップ
+func regexMatchMap(re *regexp.Regexp, str string, loc []int) *object.Map {
+	groups := make([]object.Object, 0, re.NumSubexp())
+	named := &object.Map{}
+	for i, name := range re.SubexpNames()[1:] {
+		var group object.Object = NULL
+		if start, end := loc[2*(i+1)], loc[2*(i+1)+1]; start >= 0 {
+			group = &object.String{Value: str[start:end]}
+		}
+		groups = append(groups, group)
+		if name != "" {
+			named.Set(&object.String{Value: name}, group)
+		}
+	}
+
+	m := &object.Map{}
+	m.Set(&object.String{Value: "match"}, &object.String{Value: str[loc[0]:loc[1]]})
+	m.Set(&object.String{Value: "index"}, &object.Number{Value: float64(utf8.RuneCountInString(str[:loc[0]]))})
+	m.Set(&object.String{Value: "groups"}, &object.Array{Elements: groups})
+	m.Set(&object.String{Value: "named"}, named)
+	return m
+}
+
+// regexReplaceFunc は re に一致した部分を、一致のマップを引数に fn を呼び出した結果で置換する
+// 結果が文字列でなければ Inspect の形式の文字列にする
+func (e *Evaluator) regexReplaceFunc(re *regexp.Regexp, str string, fn object.Object) object.Object {
+	var out strings.Builder
+	last := 0
+	for _, loc := range re.FindAllStringSubmatchIndex(str, -1) {
+		result := e.callback(fn, []object.Object{regexMatchMap(re, str, loc)})
+		if isFailure(result) {
+			return result
+		}
+		out.WriteString(str[last:loc[0]])
+		out.WriteString(result.Inspect())
+		last = loc[1]
+	}
+	out.WriteString(str[last:])
+	return &object.String{Value: out.String()}
+}
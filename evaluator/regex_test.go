@@ -0,0 +1,98 @@
+package evaluator
+
+import (
+	"fmt"
+	"sugu/object"
+	"testing"
+)
+
+func TestRegexBuiltins(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`regexMatch("abc123", "[0-9]+")`, "true"},
+		{`regexMatch("abc", "^[0-9]+$")`, "false"},
+		{`regexFind("abc", "[0-9]+")`, "null"},
+		{`regexFind("id: 42, 7", "[0-9]+")["match"]`, "42"},
+		// index は rune 単位
+		{`regexFind("あいう42", "[0-9]+")["index"]`, "3"},
+		{`regexFind("2024-05-06", "(\\d+)-(\\d+)-(\\d+)")["groups"]`, "[2024, 05, 06]"},
+		{`regexFind("key=val", "(?P<k>\\w+)=(?P<v>\\w+)")["named"]`, "{k: key, v: val}"},
+		// 一致しなかったグループは null
+		{`regexFind("a", "(a)|(b)")["groups"]`, "[a, null]"},
+		{`map(regexFindAll("a1 b22 c333", "[a-z](\\d+)"), func(m) => m["groups"][0])`, "[1, 22, 333]"},
+		{`regexFindAll("abc", "\\d")`, "[]"},
+		{`regexReplace("2024-05-06", "(\\d+)-(\\d+)-(\\d+)", "$3/$2/$1")`, "06/05/2024"},
+		{`regexReplace("key=val", "(?P<k>\\w+)=(?P<v>\\w+)", "${v}=${k}")`, "val=key"},
+		{`regexReplace("a1b22", "\\d+", func(m) => "<" + m["match"] + ">")`, "a<1>b<22>"},
+		// 関数の結果が文字列でなければ Inspect の形式
+		{`regexReplace("1 2 3", "\\d", func(m) => int(m["match"]) * 2)`, "2 4 6"},
+		{`regexReplace("abc", "x", func(m) => "y")`, "abc"},
+		{`regexSplit("a, b;c  d", "[,;\\s]+")`, "[a, b, c, d]"},
+		{`regexSplit("", ",")`, "[]"},
+		{`mut n = 0; for (w in ["a1", "b", "c2"]) { if (regexMatch(w, "\\d")) { n++; } } n`, "2"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("input %q: wrong result. expected=%q, got=%q", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestRegexErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`regexMatch("a")`, "wrong number of arguments. got=1, want=2"},
+		{`regexMatch(1, "a")`, "first argument to `regexMatch` must be STRING, got NUMBER"},
+		{`regexFind("a", 1)`, "second argument to `regexFind` must be STRING, got NUMBER"},
+		{`regexFindAll("a", "(")`, "invalid regular expression in `regexFindAll`: missing closing ): `(`"},
+		{`regexReplace("a", "a")`, "wrong number of arguments. got=2, want=3"},
+		{`regexReplace("a", "a", 1)`, "third argument to `regexReplace` must be STRING or FUNCTION, got NUMBER"},
+		{`regexReplace("a", "a", func(m) => m["match"] + 1)`, "type mismatch: STRING + NUMBER"},
+		{`regexSplit("a", "[")`, "invalid regular expression in `regexSplit`: missing closing ]: `[`"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("input %q: object is not Error. got=%T (%+v)", tt.input, evaluated, evaluated)
+			continue
+		}
+		if errObj.Text() != tt.expected {
+			t.Errorf("input %q: wrong error message. expected=%q, got=%q", tt.input, tt.expected, errObj.Text())
+		}
+	}
+
+	// 置換の関数の throw は catch できる
+	input := `try { regexReplace("a", "a", func(m) => { throw "no"; }) } catch (e) { "caught " + e }`
+	if evaluated := testEval(input); evaluated.Inspect() != "caught no" {
+		t.Errorf("input %q: wrong result. expected=%q, got=%q", input, "caught no", evaluated.Inspect())
+	}
+}
+
+func TestRegexCache(t *testing.T) {
+	e := New("")
+
+	re1, _ := e.compileRegex("regexMatch", "a+")
+	re2, _ := e.compileRegex("regexFind", "a+")
+	if re1 != re2 {
+		t.Errorf("the same pattern should be compiled only once")
+	}
+
+	// 上限を超えたらキャッシュを作り直す
+	for i := 0; i < regexCacheSize; i++ {
+		e.compileRegex("regexMatch", fmt.Sprintf("p%d", i))
+	}
+	if len(e.regexps) > regexCacheSize {
+		t.Errorf("cache should not exceed %d patterns. got=%d", regexCacheSize, len(e.regexps))
+	}
+	if _, errObj := e.compileRegex("regexMatch", "("); errObj == nil {
+		t.Errorf("invalid pattern should be an error")
+	}
+}
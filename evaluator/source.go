@@ -0,0 +1,84 @@
+package evaluator
+
+import (
+	"strings"
+	"sugu/object"
+	"sugu/token"
+)
+
+// このファイルは実行時エラーに位置とソースコードの抜粋を付ける処理をまとめる
+// 木構造評価器と VM バックエンドで共有する
+
+// SetSource は最初に評価するファイルのソースコードを登録する
+// 登録したソースコードは実行時エラーの抜粋の表示に使われる。import したモジュールは自動で登録される
+func (e *Evaluator) SetSource(source string) {
+	e.addSource(e.root.name, source)
+}
+
+// maxNamedSources は SetNamedSource で登録したソースコードを保持する数
+// 古いものから捨てるため、それより前に評価したコードのエラーは抜粋なしで表示される
+const maxNamedSources = 100
+
+// SetNamedSource は source を name という名前で登録し、以降に評価するコードの位置を name で記録する
+// REPL の入力や Interpreter.Eval のように同じ Evaluator で何度も評価する場合に入力ごとに別の名前を付けると、
+// 前の入力で定義した関数のエラーにもその入力の抜粋が表示される
+func (e *Evaluator) SetNamedSource(name, source string) {
+	if _, ok := e.sources[name]; !ok {
+		e.named = append(e.named, name)
+		if len(e.named) > maxNamedSources {
+			delete(e.sources, e.named[0])
+			e.named = e.named[1:]
+		}
+	}
+	e.addSource(name, source)
+	e.file = name
+}
+
+// addSource は file のソースコードを行に分けて登録する
+func (e *Evaluator) addSource(file, source string) {
+	if e.sources == nil {
+		e.sources = make(map[string][]string)
+	}
+	lines := strings.Split(source, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSuffix(line, "\r")
+	}
+	e.sources[file] = lines
+}
+
+// SourceLine は file の line 行目のソースコードを返す（登録されていない場合は空文字列）
+func (e *Evaluator) SourceLine(file string, line int) string {
+	lines := e.sources[file]
+	if line < 1 || line > len(lines) {
+		return ""
+	}
+	return lines[line-1]
+}
+
+// ErrorAt は file の line 行 column 列の位置情報とソースコードの抜粋を持つエラーを作成する
+func (e *Evaluator) ErrorAt(file string, line, column int, kind, format string, a ...interface{}) *object.Error {
+	errObj := NewErrorAt(file, line, column, kind, format, a...)
+	errObj.Source = e.SourceLine(file, line)
+	return errObj
+}
+
+// Locate は位置を持たない実行時エラーに file の line 行 column 列の位置を付ける
+// 内側の式で既に位置が付いたエラーはそのまま残すので、エラーを起こした最も内側の位置が記録される
+// 実行の上限のエラーはプログラムのどこで起きたかに意味がないため位置を付けない
+func (e *Evaluator) Locate(errObj *object.Error, file string, line, column int) {
+	if errObj.Line != 0 || line == 0 || errObj.Kind == object.LIMIT_ERROR {
+		return
+	}
+	errObj.Message = object.FormatPosition(file, line, column) + ": " + errObj.Message
+	errObj.File, errObj.Line, errObj.Column = file, line, column
+	errObj.Source = e.SourceLine(file, line)
+}
+
+// locate は result が位置を持たないエラーであれば tok の位置を付けて返す
+// 演算子や組み込み関数のエラーは、それを評価した式（組み込み関数では呼び出し）の位置になる
+func (e *Evaluator) locate(result object.Object, tok token.Token) object.Object {
+	if errObj, ok := result.(*object.Error); ok {
+		e.Locate(errObj, e.file, tok.Line, tok.Column)
+	}
+	return result
+}
@@ -0,0 +1,34 @@
+package evaluator
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestSetNamedSource(t *testing.T) {
+	e := New("")
+	e.SetSource("root")
+	for i := 1; i <= maxNamedSources+1; i++ {
+		e.SetNamedSource(fmt.Sprintf("<repl:%d>", i), fmt.Sprintf("line %d", i))
+	}
+
+	tests := []struct {
+		file     string
+		expected string
+	}{
+		{"", "root"},
+		// 保持する数を超えた古い名前は捨てる
+		{"<repl:1>", ""},
+		{"<repl:2>", "line 2"},
+		{fmt.Sprintf("<repl:%d>", maxNamedSources+1), fmt.Sprintf("line %d", maxNamedSources+1)},
+	}
+
+	for _, tt := range tests {
+		if got := e.SourceLine(tt.file, 1); got != tt.expected {
+			t.Errorf("file %q: wrong source line. expected=%q, got=%q", tt.file, tt.expected, got)
+		}
+	}
+	if e.File() != fmt.Sprintf("<repl:%d>", maxNamedSources+1) {
+		t.Errorf("wrong current file. got=%q", e.File())
+	}
+}
@@ -0,0 +1,67 @@
+package evaluator
+
+import (
+	"sugu/object"
+	"testing"
+)
+
+func TestTemplateLiteral(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"`plain`", "plain"},
+		{"const name = \"Sugu\"; `Hello, ${name}!`", "Hello, Sugu!"},
+		{"`${1 + 2} and ${10 / 4}`", "3 and 2.5"},
+		// 埋め込む値は Inspect の形式
+		{"`${[1, \"a\", null]} ${ {\"k\": true} }`", "[1, a, null] {k: true}"},
+		{"`${func(x) => x}`", "func(x) => { ... }"},
+		{"`${`in${1}`}-${\"s\"}`", "in1-s"},
+		{"mut n = 0; const s = `${n++}${n++}`; s + string(n)", "012"},
+		{"`line1\nline2 ${\"x\"}`", "line1\nline2 x"},
+		{"`\\${x} \\` \\n`", "${x} ` \n"},
+		{"func greet(n) => `hi ${n}`; join(map([\"a\", \"b\"], greet), \", \")", "hi a, hi b"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		str, ok := evaluated.(*object.String)
+		if !ok {
+			t.Errorf("input %q: object is not String. got=%T (%+v)", tt.input, evaluated, evaluated)
+			continue
+		}
+		if str.Value != tt.expected {
+			t.Errorf("input %q: wrong result. expected=%q, got=%q", tt.input, tt.expected, str.Value)
+		}
+	}
+}
+
+func TestTemplateLiteralErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		// ${} の中のエラーは元のソースの行と列で報告する
+		{"`a ${missing} b`", "line 1, column 6: identifier not found: missing"},
+		{"const x = 1;\nconst s = `first\nsecond ${x + \"a\"}`;", "line 3, column 12: type mismatch: NUMBER + STRING"},
+		{"`${`in ${-\"a\"}`}`", "line 1, column 10: unknown operator: -STRING"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("input %q: object is not Error. got=%T (%+v)", tt.input, evaluated, evaluated)
+			continue
+		}
+		if errObj.Message != tt.expected {
+			t.Errorf("input %q: wrong error message. expected=%q, got=%q", tt.input, tt.expected, errObj.Message)
+		}
+	}
+
+	// ${} の中の throw は catch できる
+	input := "func fail() => { throw \"no\"; }; try { `x${fail()}` } catch (e) { \"caught \" + e }"
+	if evaluated := testEval(input); evaluated.Inspect() != "caught no" {
+		t.Errorf("input %q: wrong result. expected=%q, got=%q", input, "caught no", evaluated.Inspect())
+	}
+}
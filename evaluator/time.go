@@ -0,0 +1,279 @@
+package evaluator
+
+import (
+	"math"
+	"sugu/object"
+	"time"
+	_ "time/tzdata" // タイムゾーンのデータベースがない環境でも inZone などで地域名を使えるようにする
+)
+
+// timeLayouts は parseTime と formatTime で名前で指定できるレイアウト
+// それ以外の文字列は Go の time パッケージのレイアウト（"2006-01-02 15:04" など）として扱う
+var timeLayouts = map[string]string{
+	"RFC3339":     time.RFC3339,
+	"RFC3339Nano": time.RFC3339Nano,
+	"RFC1123":     time.RFC1123,
+	"RFC1123Z":    time.RFC1123Z,
+	"RFC822":      time.RFC822,
+	"DateTime":    time.DateTime,
+	"DateOnly":    time.DateOnly,
+	"TimeOnly":    time.TimeOnly,
+	"Kitchen":     time.Kitchen,
+}
+
+// timeBuiltins は日時（object.Time）を扱う組み込み関数を作成する
+// 期間は秒数（小数を含む数値）で表す。now() は SetClock で設定した関数から現在時刻を得る
+func (e *Evaluator) timeBuiltins() map[string]*object.Builtin {
+	return map[string]*object.Builtin{
+		"now": {
+			Fn: func(args ...object.Object) object.Object {
+				if len(args) != 0 {
+					return newError(object.ARGUMENT_ERROR, "wrong number of arguments. got=%d, want=0", len(args))
+				}
+				return &object.Time{Value: e.clock()}
+			},
+		},
+		"parseTime": {
+			Fn: func(args ...object.Object) object.Object {
+				if len(args) < 1 || len(args) > 3 {
+					return newError(object.ARGUMENT_ERROR, "wrong number of arguments. got=%d, want=1 to 3", len(args))
+				}
+				str, ok := args[0].(*object.String)
+				if !ok {
+					return newError(object.TYPE_ERROR, "first argument to `parseTime` must be STRING, got %s", args[0].Type())
+				}
+				layout, errObj := timeLayout("parseTime", args)
+				if errObj != nil {
+					return errObj
+				}
+				loc := time.UTC
+				if len(args) == 3 {
+					if loc, errObj = timeZone("parseTime", "third", args[2]); errObj != nil {
+						return errObj
+					}
+				}
+				t, err := time.ParseInLocation(layout, str.Value, loc)
+				if err != nil {
+					layoutName := "RFC3339"
+					if len(args) >= 2 {
+						layoutName = args[1].Inspect()
+					}
+					return newError(object.VALUE_ERROR, "cannot parse %q as time with layout %q", str.Value, layoutName)
+				}
+				return &object.Time{Value: t}
+			},
+		},
+		"formatTime": {
+			Fn: func(args ...object.Object) object.Object {
+				if len(args) != 1 && len(args) != 2 {
+					return newError(object.ARGUMENT_ERROR, "wrong number of arguments. got=%d, want=1 or 2", len(args))
+				}
+				t, errObj := timeArg("formatTime", "first", args[0])
+				if errObj != nil {
+					return errObj
+				}
+				layout, errObj := timeLayout("formatTime", args)
+				if errObj != nil {
+					return errObj
+				}
+				return &object.String{Value: t.Format(layout)}
+			},
+		},
+		"addTime": {
+			Fn: func(args ...object.Object) object.Object {
+				if len(args) != 2 {
+					return newError(object.ARGUMENT_ERROR, "wrong number of arguments. got=%d, want=2", len(args))
+				}
+				t, errObj := timeArg("addTime", "first", args[0])
+				if errObj != nil {
+					return errObj
+				}
+				d, errObj := duration("addTime", args[1])
+				if errObj != nil {
+					return errObj
+				}
+				return &object.Time{Value: t.Add(d)}
+			},
+		},
+		"addDate": {
+			Fn: func(args ...object.Object) object.Object {
+				if len(args) != 4 {
+					return newError(object.ARGUMENT_ERROR, "wrong number of arguments. got=%d, want=4", len(args))
+				}
+				t, errObj := timeArg("addDate", "first", args[0])
+				if errObj != nil {
+					return errObj
+				}
+				var n [3]int
+				for i, arg := range args[1:] {
+					num, ok := arg.(*object.Number)
+					if !ok || math.Trunc(num.Value) != num.Value {
+						return newError(object.TYPE_ERROR, "years, months and days for `addDate` must be integers, got %s", arg.Inspect())
+					}
+					n[i] = int(num.Value)
+				}
+				return &object.Time{Value: t.AddDate(n[0], n[1], n[2])}
+			},
+		},
+		"diffTime": {
+			Fn: func(args ...object.Object) object.Object {
+				if len(args) != 2 {
+					return newError(object.ARGUMENT_ERROR, "wrong number of arguments. got=%d, want=2", len(args))
+				}
+				a, errObj := timeArg("diffTime", "first", args[0])
+				if errObj != nil {
+					return errObj
+				}
+				b, errObj := timeArg("diffTime", "second", args[1])
+				if errObj != nil {
+					return errObj
+				}
+				return &object.Number{Value: a.Sub(b).Seconds()}
+			},
+		},
+		"inZone": {
+			Fn: func(args ...object.Object) object.Object {
+				if len(args) != 2 {
+					return newError(object.ARGUMENT_ERROR, "wrong number of arguments. got=%d, want=2", len(args))
+				}
+				t, errObj := timeArg("inZone", "first", args[0])
+				if errObj != nil {
+					return errObj
+				}
+				loc, errObj := timeZone("inZone", "second", args[1])
+				if errObj != nil {
+					return errObj
+				}
+				return &object.Time{Value: t.In(loc)}
+			},
+		},
+		"toUnix": {
+			Fn: func(args ...object.Object) object.Object {
+				if len(args) != 1 {
+					return newError(object.ARGUMENT_ERROR, "wrong number of arguments. got=%d, want=1", len(args))
+				}
+				t, errObj := timeArg("toUnix", "", args[0])
+				if errObj != nil {
+					return errObj
+				}
+				return &object.Number{Value: float64(t.Unix()) + float64(t.Nanosecond())/1e9}
+			},
+		},
+		"fromUnix": {
+			Fn: func(args ...object.Object) object.Object {
+				if len(args) != 1 {
+					return newError(object.ARGUMENT_ERROR, "wrong number of arguments. got=%d, want=1", len(args))
+				}
+				num, ok := args[0].(*object.Number)
+				if !ok {
+					return newError(object.TYPE_ERROR, "argument to `fromUnix` must be NUMBER, got %s", args[0].Type())
+				}
+				if math.IsNaN(num.Value) || math.IsInf(num.Value, 0) {
+					return newError(object.VALUE_ERROR, "cannot convert %s to time", num.Inspect())
+				}
+				sec, frac := math.Modf(num.Value)
+				return &object.Time{Value: time.Unix(int64(sec), int64(math.Round(frac*1e9))).UTC()}
+			},
+		},
+		"timeParts": {
+			Fn: func(args ...object.Object) object.Object {
+				if len(args) != 1 {
+					return newError(object.ARGUMENT_ERROR, "wrong number of arguments. got=%d, want=1", len(args))
+				}
+				t, errObj := timeArg("timeParts", "", args[0])
+				if errObj != nil {
+					return errObj
+				}
+				return timePartsMap(t)
+			},
+		},
+	}
+}
+
+// timeArg は ordinal 番目（"first" など。引数が 1 つの関数は空文字列）の引数 arg を日時として取り出す
+func timeArg(name, ordinal string, arg object.Object) (time.Time, *object.Error) {
+	t, ok := arg.(*object.Time)
+	if !ok {
+		return time.Time{}, newError(object.TYPE_ERROR, "%s to `%s` must be TIME, got %s", argumentName(ordinal), name, arg.Type())
+	}
+	return t.Value, nil
+}
+
+// argumentName はエラーメッセージの "first argument" などを返す
+func argumentName(ordinal string) string {
+	if ordinal == "" {
+		return "argument"
+	}
+	return ordinal + " argument"
+}
+
+// timeLayout は 2 番目の引数のレイアウトを返す（省略した場合は RFC 3339）
+func timeLayout(name string, args []object.Object) (string, *object.Error) {
+	if len(args) < 2 {
+		return time.RFC3339Nano, nil
+	}
+	layout, ok := args[1].(*object.String)
+	if !ok {
+		return "", newError(object.TYPE_ERROR, "second argument to `%s` must be STRING, got %s", name, args[1].Type())
+	}
+	if named, ok := timeLayouts[layout.Value]; ok {
+		return named, nil
+	}
+	return layout.Value, nil
+}
+
+// timeZone は "UTC"、"Local"、"Asia/Tokyo" などのタイムゾーン名の引数を読み込む
+func timeZone(name, ordinal string, arg object.Object) (*time.Location, *object.Error) {
+	zone, ok := arg.(*object.String)
+	if !ok {
+		return nil, newError(object.TYPE_ERROR, "%s to `%s` must be STRING, got %s", argumentName(ordinal), name, arg.Type())
+	}
+	loc, err := time.LoadLocation(zone.Value)
+	if err != nil {
+		return nil, newError(object.VALUE_ERROR, "unknown time zone %q", zone.Value)
+	}
+	return loc, nil
+}
+
+// duration は秒数の数値、または Go の形式の文字列（"1h30m"、"-90s" など）の期間を読み込む
+func duration(name string, arg object.Object) (time.Duration, *object.Error) {
+	switch arg := arg.(type) {
+	case *object.Number:
+		if math.IsNaN(arg.Value) || math.IsInf(arg.Value, 0) {
+			return 0, newError(object.VALUE_ERROR, "invalid duration: %s", arg.Inspect())
+		}
+		return time.Duration(math.Round(arg.Value * float64(time.Second))), nil
+	case *object.String:
+		d, err := time.ParseDuration(arg.Value)
+		if err != nil {
+			return 0, newError(object.VALUE_ERROR, "invalid duration: %q", arg.Value)
+		}
+		return d, nil
+	}
+	return 0, newError(object.TYPE_ERROR, "second argument to `%s` must be NUMBER or STRING, got %s", name, arg.Type())
+}
+
+// timePartsMap は日時の各部分のマップを作る
+// weekday は日曜日を 0 とする曜日、offset は UTC からのずれの秒数
+func timePartsMap(t time.Time) *object.Map {
+	zone, offset := t.Zone()
+	m := &object.Map{}
+	for _, part := range []struct {
+		key   string
+		value int
+	}{
+		{"year", t.Year()},
+		{"month", int(t.Month())},
+		{"day", t.Day()},
+		{"hour", t.Hour()},
+		{"minute", t.Minute()},
+		{"second", t.Second()},
+		{"nanosecond", t.Nanosecond()},
+		{"weekday", int(t.Weekday())},
+		{"offset", offset},
+	} {
+		m.Set(&object.String{Value: part.key}, &object.Number{Value: float64(part.value)})
+	}
+	m.Set(&object.String{Value: "zone"}, &object.String{Value: zone})
+	return m
+}
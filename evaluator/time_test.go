@@ -0,0 +1,96 @@
+package evaluator
+
+import (
+	"context"
+	"sugu/object"
+	"testing"
+	"time"
+)
+
+// testNow は時刻のテストで now() が返す時刻
+var testNow = time.Date(2024, 5, 6, 7, 8, 9, 500000000, time.UTC)
+
+func TestTimeBuiltins(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`now()`, "2024-05-06T07:08:09.5Z"},
+		{`type(now())`, "TIME"},
+		{`parseTime("2024-01-02T03:04:05+09:00")`, "2024-01-02T03:04:05+09:00"},
+		{`parseTime("2024-01-02", "DateOnly")`, "2024-01-02T00:00:00Z"},
+		{`parseTime("02/01/2024 15:04", "02/01/2006 15:04")`, "2024-01-02T15:04:00Z"},
+		// レイアウトにタイムゾーンがなければ指定したタイムゾーンの時刻
+		{`parseTime("2024-01-02 09:00:00", "DateTime", "Asia/Tokyo")`, "2024-01-02T09:00:00+09:00"},
+		{`formatTime(now())`, "2024-05-06T07:08:09.5Z"},
+		{`formatTime(now(), "DateTime")`, "2024-05-06 07:08:09"},
+		{`formatTime(now(), "2006/01/02 Mon")`, "2024/05/06 Mon"},
+		{`formatTime(inZone(now(), "Asia/Tokyo"), "RFC3339")`, "2024-05-06T16:08:09+09:00"},
+		{`addTime(now(), 90)`, "2024-05-06T07:09:39.5Z"},
+		{`addTime(now(), -0.5)`, "2024-05-06T07:08:09Z"},
+		{`addTime(now(), "1h30m")`, "2024-05-06T08:38:09.5Z"},
+		{`addDate(parseTime("2024-01-31", "DateOnly"), 0, 1, 0)`, "2024-03-02T00:00:00Z"},
+		{`addDate(now(), -1, 0, 1)`, "2023-05-07T07:08:09.5Z"},
+		{`diffTime(addTime(now(), "2h"), now())`, "7200"},
+		{`diffTime(parseTime("2024-01-01", "DateOnly"), parseTime("2024-01-02", "DateOnly"))`, "-86400"},
+		{`toUnix(parseTime("1970-01-02T00:00:00Z"))`, "86400"},
+		{`toUnix(now()) == 1714979289.5`, "true"},
+		{`fromUnix(1714979289.5)`, "2024-05-06T07:08:09.5Z"},
+		{`fromUnix(0)`, "1970-01-01T00:00:00Z"},
+		{`timeParts(inZone(now(), "Asia/Tokyo"))`, "{year: 2024, month: 5, day: 6, hour: 16, minute: 8, second: 9, nanosecond: 500000000, weekday: 1, offset: 32400, zone: JST}"},
+		// 比較はタイムゾーンに関係なく時刻で行う
+		{`now() == inZone(now(), "Asia/Tokyo")`, "true"},
+		{`now() != addTime(now(), 1)`, "true"},
+		{`now() < addTime(now(), 1)`, "true"},
+		{`now() > addTime(now(), 1)`, "false"},
+		{`now() <= now() && now() >= now()`, "true"},
+		{`[now()] == [inZone(now(), "UTC")]`, "true"},
+		{`same(now(), inZone(now(), "UTC"))`, "true"},
+		{`now() == "2024-05-06T07:08:09.5Z"`, "false"},
+		{`jsonStringify({"at": inZone(now(), "Asia/Tokyo")})`, `{"at":"2024-05-06T16:08:09.5+09:00"}`},
+		{"`at ${now()}`", "at 2024-05-06T07:08:09.5Z"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEvalWith(context.Background(), tt.input, func(ev *Evaluator) { ev.SetClock(func() time.Time { return testNow }) })
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("input %q: wrong result. expected=%q, got=%q", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestTimeErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`now(1)`, "wrong number of arguments. got=1, want=0"},
+		{`parseTime(1)`, "first argument to `parseTime` must be STRING, got NUMBER"},
+		{`parseTime("2024-13-01", "DateOnly")`, "cannot parse \"2024-13-01\" as time with layout \"DateOnly\""},
+		{`parseTime("yesterday")`, "cannot parse \"yesterday\" as time with layout \"RFC3339\""},
+		{`parseTime("2024-01-02", "DateOnly", "Mars/Base")`, "unknown time zone \"Mars/Base\""},
+		{`formatTime("2024-01-02")`, "first argument to `formatTime` must be TIME, got STRING"},
+		{`formatTime(now(), 1)`, "second argument to `formatTime` must be STRING, got NUMBER"},
+		{`addTime(now(), "soon")`, "invalid duration: \"soon\""},
+		{`addTime(now(), [])`, "second argument to `addTime` must be NUMBER or STRING, got ARRAY"},
+		{`addDate(now(), 0, 1.5, 0)`, "years, months and days for `addDate` must be integers, got 1.5"},
+		{`diffTime(now(), 1)`, "second argument to `diffTime` must be TIME, got NUMBER"},
+		{`inZone(now(), 9)`, "second argument to `inZone` must be STRING, got NUMBER"},
+		{`toUnix(1)`, "argument to `toUnix` must be TIME, got NUMBER"},
+		{`fromUnix("1")`, "argument to `fromUnix` must be NUMBER, got STRING"},
+		{`now() + 1`, "type mismatch: TIME + NUMBER"},
+		{`now() - now()`, "unknown operator: TIME - TIME"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEvalWith(context.Background(), tt.input, func(ev *Evaluator) { ev.SetClock(func() time.Time { return testNow }) })
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("input %q: object is not Error. got=%T (%+v)", tt.input, evaluated, evaluated)
+			continue
+		}
+		if errObj.Text() != tt.expected {
+			t.Errorf("input %q: wrong error message. expected=%q, got=%q", tt.input, tt.expected, errObj.Text())
+		}
+	}
+}
@@ -2,4 +2,4 @@ module sugu
 
 go 1.25.5
 
-require github.com/aws/aws-lambda-go v1.51.1 // indirect
+require github.com/aws/aws-lambda-go v1.51.1
@@ -0,0 +1,12 @@
+// bootstrap は AWS Lambda のカスタムランタイム（provided.al2023）のエントリーポイント
+package main
+
+import (
+	sugulambda "sugu/lambda"
+
+	"github.com/aws/aws-lambda-go/lambda"
+)
+
+func main() {
+	lambda.Start(sugulambda.Handler)
+}
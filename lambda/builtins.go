@@ -1,4 +1,4 @@
-package main
+package lambda
 
 import (
 	"strings"
@@ -10,9 +10,9 @@ type OutputCapture struct {
 	builder strings.Builder
 }
 
-// Write は文字列を出力バッファに書き込む
-func (o *OutputCapture) Write(s string) {
-	o.builder.WriteString(s)
+// Write は p を出力バッファに書き込む（io.Writer として Interpreter の標準出力にする）
+func (o *OutputCapture) Write(p []byte) (int, error) {
+	return o.builder.Write(p)
 }
 
 // String はキャプチャした出力を返す
@@ -26,28 +26,12 @@ func (o *OutputCapture) Reset() {
 }
 
 // NewLambdaBuiltins は Lambda 用の組み込み関数を作成する
-func NewLambdaBuiltins(capture *OutputCapture) map[string]*object.Builtin {
+// 出力は Interpreter の標準出力を OutputCapture にしてキャプチャするため、ここでは上書きしない
+func NewLambdaBuiltins() map[string]*object.Builtin {
 	return map[string]*object.Builtin{
-		"out": {
-			Fn: func(args ...object.Object) object.Object {
-				for _, arg := range args {
-					capture.Write(arg.Inspect())
-				}
-				return &object.Null{}
-			},
-		},
-		"outln": {
-			Fn: func(args ...object.Object) object.Object {
-				for _, arg := range args {
-					capture.Write(arg.Inspect())
-					capture.Write("\n")
-				}
-				return &object.Null{}
-			},
-		},
 		"in": {
 			Fn: func(args ...object.Object) object.Object {
-				return &object.Error{Message: "in() is not available in Lambda environment"}
+				return &object.Error{Message: "in() is not available in Lambda environment", Kind: object.IO_ERROR}
 			},
 		},
 	}
@@ -1,146 +1,224 @@
-package main
+// Package lambda は AWS Lambda で Sugu のスクリプトを実行するハンドラーを提供する
+// エントリーポイントは lambda/bootstrap、ローカルでの動作確認は sugu lambda-invoke で行う
+package lambda
 
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"os"
-	"sugu/evaluator"
-	"sugu/lexer"
+	"strings"
+	"sugu/convert"
 	"sugu/object"
-	"sugu/parser"
+	"sugu/sugu"
+	"time"
 )
 
+// deadlineMargin は Lambda のタイムアウトより前に Sugu の実行を止めるための余裕
+// この時間内にエラーレスポンスを返すことで、Lambda にプロセスを強制終了されるのを避ける
+const deadlineMargin = 200 * time.Millisecond
+
+// ResponseModeEnv はレスポンスの形式を指定する環境変数の名前
+const ResponseModeEnv = "SUGU_RESPONSE_MODE"
+
+// ResponseMode は Lambda のレスポンスの形式
+type ResponseMode string
+
+const (
+	ResponseEnvelope ResponseMode = "envelope" // {output, result, error, durationMs} のマップ（既定）
+	ResponseRaw      ResponseMode = "raw"      // 値そのもの（API Gateway のプロキシ統合などで使う）
+)
+
+// ParseResponseMode は環境変数などで指定されたレスポンスの形式を返す（空文字列は ResponseEnvelope）
+func ParseResponseMode(s string) (ResponseMode, error) {
+	switch mode := ResponseMode(s); mode {
+	case "":
+		return ResponseEnvelope, nil
+	case ResponseEnvelope, ResponseRaw:
+		return mode, nil
+	}
+	return "", fmt.Errorf("unknown response mode %q (want %s or %s)", s, ResponseEnvelope, ResponseRaw)
+}
+
 // Handler は Lambda ハンドラー
 // event は任意の JSON で、Sugu の event 変数として渡される
-// main.sugu の最後に評価された式の値がそのまま Lambda のレスポンスになる
+// main.sugu の実行結果を、環境変数 SUGU_RESPONSE_MODE で指定した形式（既定は envelope）のレスポンスにする
 func Handler(ctx context.Context, event json.RawMessage) (interface{}, error) {
+	mode, err := ParseResponseMode(os.Getenv(ResponseModeEnv))
+	if err != nil {
+		return nil, err
+	}
+
 	// main.sugu を読み込む
 	code, err := os.ReadFile("main.sugu")
 	if err != nil {
-		return map[string]string{"error": "failed to read main.sugu: " + err.Error()}, nil
+		result := Result{Error: &object.Error{Message: "failed to read main.sugu: " + err.Error(), Kind: object.IO_ERROR}}
+		return result.Response(mode), nil
 	}
 
-	return Execute(string(code), event)
+	return Run(ctx, string(code), event).Response(mode), nil
 }
 
-// Execute は Sugu コードを実行し、結果を返す
-func Execute(code string, eventJSON json.RawMessage) (interface{}, error) {
-	// 出力キャプチャを作成
-	capture := &OutputCapture{}
-	_ = capture // outln の出力は Lambda では使用しないが、エラー防止のため保持
+// Execute は Sugu コードを実行し、結果を ResponseRaw の形式で返す
+// ctx がキャンセルされるか、ctx の期限の deadlineMargin 前になると実行を中断し、
+// "execution limit exceeded" のエラーレスポンスを返す
+func Execute(ctx context.Context, code string, eventJSON json.RawMessage) (interface{}, error) {
+	return Run(ctx, code, eventJSON).Response(ResponseRaw), nil
+}
 
-	// Lambda 用組み込み関数を取得
-	lambdaBuiltins := NewLambdaBuiltins(capture)
+// Result は Sugu コードを実行した結果
+type Result struct {
+	Value    interface{}   // 最後に評価された式の値（return 文の値）を Go の値に変換したもの
+	Output   string        // 標準出力への出力（out、outln、outf など）
+	Error    *object.Error // 構文エラー・実行時エラーなど（成功した場合は nil）
+	Duration time.Duration // 実行にかかった時間
+}
 
-	// 環境を作成し、Lambda 用組み込み関数を登録
-	env := object.NewEnvironment()
-	for name, builtin := range lambdaBuiltins {
-		env.Set(name, builtin)
+// Envelope は ResponseEnvelope の形式のレスポンス
+type Envelope struct {
+	Output     string       `json:"output"`
+	Result     interface{}  `json:"result"`
+	Error      *ErrorDetail `json:"error"`
+	DurationMs float64      `json:"durationMs"`
+}
+
+// ErrorDetail は Envelope のエラー（catch で受け取る例外と同じ情報）
+type ErrorDetail struct {
+	Message string       `json:"message"`
+	Kind    string       `json:"kind"`
+	File    string       `json:"file,omitempty"`
+	Line    int          `json:"line,omitempty"`
+	Column  int          `json:"column,omitempty"`
+	Stack   []StackFrame `json:"stack"`
+}
+
+// StackFrame は ErrorDetail の呼び出し履歴の 1 フレーム
+type StackFrame struct {
+	Function string `json:"function,omitempty"`
+	File     string `json:"file,omitempty"`
+	Line     int    `json:"line"`
+	Column   int    `json:"column"`
+}
+
+// Response は実行結果を mode の形式のレスポンスにする
+// ResponseRaw では値をそのまま返し、エラーの場合は {"error", "stack"} のマップを返す
+func (r Result) Response(mode ResponseMode) interface{} {
+	if mode == ResponseRaw {
+		if r.Error == nil {
+			return r.Value
+		}
+		resp := map[string]string{"error": r.Error.Message}
+		if trace := r.Error.StackTrace(); trace != "" {
+			resp["stack"] = trace
+		}
+		return resp
 	}
 
-	// event 変数を設定
-	eventObj, err := jsonToSuguObject(eventJSON)
-	if err != nil {
-		return map[string]string{"error": "failed to parse event: " + err.Error()}, nil
+	env := Envelope{
+		Output:     r.Output,
+		Result:     r.Value,
+		DurationMs: float64(r.Duration.Microseconds()) / 1000,
+	}
+	if r.Error != nil {
+		env.Error = errorDetail(r.Error)
+	}
+	return env
+}
+
+// errorDetail はエラーの種類・位置・呼び出し履歴を取り出す
+func errorDetail(err *object.Error) *ErrorDetail {
+	kind := err.Kind
+	if kind == "" {
+		kind = object.GENERIC_ERROR
+	}
+	stack := make([]StackFrame, len(err.Stack))
+	for i, frame := range err.Stack {
+		stack[i] = StackFrame{Function: frame.Function, File: frame.File, Line: frame.Line, Column: frame.Column}
 	}
-	env.Set("event", eventObj)
+	return &ErrorDetail{Message: err.Text(), Kind: kind, File: err.File, Line: err.Line, Column: err.Column, Stack: stack}
+}
 
-	// Lexer
-	l := lexer.New(code)
+// Run は Sugu コードを実行し、値・出力・エラー・実行時間を返す
+// ctx がキャンセルされるか、ctx の期限の deadlineMargin 前になると実行を中断する
+func Run(ctx context.Context, code string, eventJSON json.RawMessage) Result {
+	capture := &OutputCapture{}
+	start := time.Now()
+	value, errObj := run(ctx, code, eventJSON, capture)
+	return Result{Value: value, Output: capture.String(), Error: errObj, Duration: time.Since(start)}
+}
 
-	// Parser
-	p := parser.New(l)
-	program := p.ParseProgram()
+// run は標準出力（out、outln、outf など）を capture に書き込みながら Sugu コードを実行する
+func run(ctx context.Context, code string, eventJSON json.RawMessage, capture *OutputCapture) (interface{}, *object.Error) {
+	// Interpreter を作成し、Lambda 用組み込み関数を登録
+	interp, err := sugu.New(sugu.Options{Stdout: capture})
+	if err != nil {
+		return nil, objectError(err)
+	}
+	for name, builtin := range NewLambdaBuiltins() {
+		if err := interp.Register(name, builtin); err != nil {
+			return nil, objectError(err)
+		}
+	}
 
-	// パースエラーのチェック
-	if len(p.Errors()) != 0 {
-		return map[string]string{"error": p.Errors()[0]}, nil
+	// event 変数を設定
+	eventObj, err := jsonToSuguObject(eventJSON)
+	if err != nil {
+		return nil, &object.Error{Message: "failed to parse event: " + err.Error(), Kind: object.VALUE_ERROR}
+	}
+	if err := interp.SetGlobal("event", eventObj); err != nil {
+		return nil, objectError(err)
 	}
 
-	// Evaluator
-	result := evaluator.Eval(program, env)
+	// Lambda の期限より少し前に止める
+	if deadline, ok := ctx.Deadline(); ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, deadline.Add(-deadlineMargin))
+		defer cancel()
+	}
 
-	// エラーチェック
-	if errObj, ok := result.(*object.Error); ok {
-		return map[string]string{"error": errObj.Message}, nil
+	result, err := interp.Eval(ctx, code)
+	if err != nil {
+		errObj := objectError(err)
+		// パーサーのエラーは位置を持たず、メッセージを改行でつないだものになる（最初のエラーだけを返す）
+		if errObj.Kind == object.SYNTAX_ERROR && errObj.Line == 0 {
+			first, _, _ := strings.Cut(errObj.Message, "\n")
+			return nil, syntaxError(first)
+		}
+		return nil, errObj
 	}
 
 	// 結果を返す（return 文の値、または最後に評価された式の値）
-	return suguObjectToGoValue(result), nil
-}
-
-// jsonToSuguObject は JSON を Sugu の Object に変換する
-func jsonToSuguObject(data json.RawMessage) (object.Object, error) {
-	if len(data) == 0 {
-		return &object.Null{}, nil
+	var value interface{}
+	if err := convert.FromObject(result, &value); err != nil {
+		return nil, &object.Error{Message: "failed to convert result: " + err.Error(), Kind: object.TYPE_ERROR}
 	}
+	return value, nil
+}
 
-	var v interface{}
-	if err := json.Unmarshal(data, &v); err != nil {
-		return nil, err
+// objectError は Interpreter のエラーを *object.Error として返す
+func objectError(err error) *object.Error {
+	var errObj *object.Error
+	if errors.As(err, &errObj) {
+		return errObj
 	}
-
-	return goValueToSuguObject(v), nil
+	return &object.Error{Message: err.Error()}
 }
 
-// goValueToSuguObject は Go の値を Sugu の Object に変換する
-func goValueToSuguObject(v interface{}) object.Object {
-	switch val := v.(type) {
-	case nil:
-		return &object.Null{}
-	case bool:
-		return &object.Boolean{Value: val}
-	case float64:
-		return &object.Number{Value: val}
-	case string:
-		return &object.String{Value: val}
-	case []interface{}:
-		elements := make([]object.Object, len(val))
-		for i, elem := range val {
-			elements[i] = goValueToSuguObject(elem)
-		}
-		return &object.Array{Elements: elements}
-	case map[string]interface{}:
-		pairs := make(map[object.HashKey]object.HashPair)
-		for k, v := range val {
-			key := &object.String{Value: k}
-			pairs[key.HashKey()] = object.HashPair{
-				Key:   key,
-				Value: goValueToSuguObject(v),
-			}
-		}
-		return &object.Map{Pairs: pairs}
-	default:
-		return &object.Null{}
+// syntaxError はパーサーのエラーメッセージ（"line 1, column 4: ..."）から位置を取り出して SyntaxError にする
+func syntaxError(msg string) *object.Error {
+	errObj := &object.Error{Message: msg, Kind: object.SYNTAX_ERROR}
+	var line, column int
+	if n, _ := fmt.Sscanf(msg, "line %d, column %d:", &line, &column); n == 2 {
+		errObj.Line, errObj.Column = line, column
 	}
+	return errObj
 }
 
-// suguObjectToGoValue は Sugu の Object を Go の値に変換する
-func suguObjectToGoValue(obj object.Object) interface{} {
-	switch v := obj.(type) {
-	case *object.Null:
-		return nil
-	case *object.Boolean:
-		return v.Value
-	case *object.Number:
-		return v.Value
-	case *object.String:
-		return v.Value
-	case *object.Array:
-		result := make([]interface{}, len(v.Elements))
-		for i, elem := range v.Elements {
-			result[i] = suguObjectToGoValue(elem)
-		}
-		return result
-	case *object.Map:
-		result := make(map[string]interface{})
-		for _, pair := range v.Pairs {
-			if key, ok := pair.Key.(*object.String); ok {
-				result[key.Value] = suguObjectToGoValue(pair.Value)
-			}
-		}
-		return result
-	default:
-		return nil
+// jsonToSuguObject は JSON を Sugu の Object に変換する（jsonParse と同じ変換）
+func jsonToSuguObject(data json.RawMessage) (object.Object, error) {
+	if len(data) == 0 {
+		return object.NULL, nil
 	}
+	return convert.FromJSON(data)
 }
@@ -1,13 +1,17 @@
-package main
+package lambda
 
 import (
+	"context"
 	"encoding/json"
+	"os"
+	"reflect"
 	"testing"
+	"time"
 )
 
 func TestExecute_SimpleExpression(t *testing.T) {
 	code := "1 + 2"
-	resp, err := Execute(code, nil)
+	resp, err := Execute(context.Background(), code, nil)
 
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
@@ -20,7 +24,7 @@ func TestExecute_SimpleExpression(t *testing.T) {
 
 func TestExecute_ReturnString(t *testing.T) {
 	code := `"hello"`
-	resp, err := Execute(code, nil)
+	resp, err := Execute(context.Background(), code, nil)
 
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
@@ -33,7 +37,7 @@ func TestExecute_ReturnString(t *testing.T) {
 
 func TestExecute_ReturnMap(t *testing.T) {
 	code := `{"status": "ok", "count": 42}`
-	resp, err := Execute(code, nil)
+	resp, err := Execute(context.Background(), code, nil)
 
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
@@ -55,7 +59,7 @@ func TestExecute_ReturnMap(t *testing.T) {
 
 func TestExecute_ReturnArray(t *testing.T) {
 	code := `[1, 2, 3]`
-	resp, err := Execute(code, nil)
+	resp, err := Execute(context.Background(), code, nil)
 
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
@@ -81,7 +85,7 @@ func TestExecute_VariableAndExpression(t *testing.T) {
 		mut y = 20;
 		x + y;
 	`
-	resp, err := Execute(code, nil)
+	resp, err := Execute(context.Background(), code, nil)
 
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
@@ -94,7 +98,7 @@ func TestExecute_VariableAndExpression(t *testing.T) {
 
 func TestExecute_ParseError(t *testing.T) {
 	code := "1 +"
-	resp, err := Execute(code, nil)
+	resp, err := Execute(context.Background(), code, nil)
 
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
@@ -110,9 +114,57 @@ func TestExecute_ParseError(t *testing.T) {
 	}
 }
 
+func TestExecute_StopsBeforeDeadline(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), deadlineMargin+50*time.Millisecond)
+	defer cancel()
+
+	code := "while (true) {}"
+	resp, err := Execute(ctx, code, nil)
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	resultMap, ok := resp.(map[string]string)
+	if !ok {
+		t.Fatalf("expected error map, got %T", resp)
+	}
+
+	expected := "execution limit exceeded: context deadline exceeded"
+	if resultMap["error"] != expected {
+		t.Errorf("expected %q, got %q", expected, resultMap["error"])
+	}
+
+	// Lambda の期限より前に応答を返している
+	if ctx.Err() != nil {
+		t.Errorf("expected to stop before the deadline")
+	}
+}
+
+func TestExecute_RuntimeErrorStackTrace(t *testing.T) {
+	code := "func fail() => { missing; }\nfail();"
+	resp, err := Execute(context.Background(), code, nil)
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	resultMap, ok := resp.(map[string]string)
+	if !ok {
+		t.Fatalf("expected error map, got %T", resp)
+	}
+
+	if resultMap["error"] != "line 1, column 18: identifier not found: missing" {
+		t.Errorf("unexpected error message: %q", resultMap["error"])
+	}
+	if resultMap["stack"] != "  at fail (line 2, column 5)" {
+		t.Errorf("unexpected stack: %q", resultMap["stack"])
+	}
+}
+
 func TestExecute_RuntimeError(t *testing.T) {
 	code := "foo"
-	resp, err := Execute(code, nil)
+	resp, err := Execute(context.Background(), code, nil)
 
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
@@ -130,7 +182,7 @@ func TestExecute_RuntimeError(t *testing.T) {
 
 func TestExecute_InFunctionDisabled(t *testing.T) {
 	code := `in()`
-	resp, err := Execute(code, nil)
+	resp, err := Execute(context.Background(), code, nil)
 
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
@@ -141,7 +193,7 @@ func TestExecute_InFunctionDisabled(t *testing.T) {
 		t.Fatalf("expected error map, got %T", resp)
 	}
 
-	expectedError := "in() is not available in Lambda environment"
+	expectedError := "line 1, column 3: in() is not available in Lambda environment"
 	if resultMap["error"] != expectedError {
 		t.Errorf("expected error %q, got %q", expectedError, resultMap["error"])
 	}
@@ -154,7 +206,7 @@ func TestExecute_FunctionCall(t *testing.T) {
 		}
 		add(5, 7);
 	`
-	resp, err := Execute(code, nil)
+	resp, err := Execute(context.Background(), code, nil)
 
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
@@ -167,7 +219,7 @@ func TestExecute_FunctionCall(t *testing.T) {
 
 func TestOutputCapture_Reset(t *testing.T) {
 	capture := &OutputCapture{}
-	capture.Write("test")
+	capture.Write([]byte("test"))
 
 	if capture.String() != "test" {
 		t.Errorf("expected 'test', got '%s'", capture.String())
@@ -186,7 +238,7 @@ func TestExecute_EventVariable(t *testing.T) {
 	code := `event["name"]`
 	eventJSON := json.RawMessage(`{"name": "Taro", "age": 25}`)
 
-	resp, err := Execute(code, eventJSON)
+	resp, err := Execute(context.Background(), code, eventJSON)
 
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
@@ -201,7 +253,7 @@ func TestExecute_EventWithNumber(t *testing.T) {
 	code := `event["age"] + 5`
 	eventJSON := json.RawMessage(`{"name": "Taro", "age": 25}`)
 
-	resp, err := Execute(code, eventJSON)
+	resp, err := Execute(context.Background(), code, eventJSON)
 
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
@@ -216,7 +268,7 @@ func TestExecute_EventWithArray(t *testing.T) {
 	code := `event["items"][1]`
 	eventJSON := json.RawMessage(`{"items": ["apple", "banana", "cherry"]}`)
 
-	resp, err := Execute(code, eventJSON)
+	resp, err := Execute(context.Background(), code, eventJSON)
 
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
@@ -237,7 +289,7 @@ func TestExecute_EventWithBoolean(t *testing.T) {
 	`
 	eventJSON := json.RawMessage(`{"active": true}`)
 
-	resp, err := Execute(code, eventJSON)
+	resp, err := Execute(context.Background(), code, eventJSON)
 
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
@@ -250,7 +302,7 @@ func TestExecute_EventWithBoolean(t *testing.T) {
 
 func TestExecute_EventNull(t *testing.T) {
 	code := `event`
-	resp, err := Execute(code, nil)
+	resp, err := Execute(context.Background(), code, nil)
 
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
@@ -265,7 +317,7 @@ func TestExecute_EventNestedObject(t *testing.T) {
 	code := `event["user"]["name"]`
 	eventJSON := json.RawMessage(`{"user": {"name": "Hanako", "email": "hanako@example.com"}}`)
 
-	resp, err := Execute(code, eventJSON)
+	resp, err := Execute(context.Background(), code, eventJSON)
 
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
@@ -280,7 +332,7 @@ func TestExecute_ReturnEventAsIs(t *testing.T) {
 	code := `event`
 	eventJSON := json.RawMessage(`{"name": "Test", "count": 10}`)
 
-	resp, err := Execute(code, eventJSON)
+	resp, err := Execute(context.Background(), code, eventJSON)
 
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
@@ -299,3 +351,217 @@ func TestExecute_ReturnEventAsIs(t *testing.T) {
 		t.Errorf("expected count 10, got %v", resultMap["count"])
 	}
 }
+
+func TestExecute_EventWithFalse(t *testing.T) {
+	code := `if (event["active"]) { "yes" } else { "no" }`
+	eventJSON := json.RawMessage(`{"active": false}`)
+
+	resp, err := Execute(context.Background(), code, eventJSON)
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if resp != "no" {
+		t.Errorf("expected 'no', got %v", resp)
+	}
+}
+
+func TestExecute_ReturnNonStringKeys(t *testing.T) {
+	code := `{1: "one", true: "yes"}`
+
+	resp, err := Execute(context.Background(), code, nil)
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	resultMap, ok := resp.(map[string]interface{})
+	if !ok {
+		t.Fatalf("expected map, got %T", resp)
+	}
+
+	if resultMap["1"] != "one" || resultMap["true"] != "yes" {
+		t.Errorf("expected keys converted to strings, got %v", resultMap)
+	}
+}
+
+func TestExecute_ReturnUnconvertibleValue(t *testing.T) {
+	code := `{"handler": func(x) => { x }}`
+
+	resp, err := Execute(context.Background(), code, nil)
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	respMap, ok := resp.(map[string]string)
+	if !ok {
+		t.Fatalf("expected error map, got %T", resp)
+	}
+
+	if respMap["error"] != "failed to convert result: [handler]: cannot convert FUNCTION to a Go value" {
+		t.Errorf("unexpected error message: %s", respMap["error"])
+	}
+}
+
+// レスポンスの形式のテスト
+
+func TestRun_Envelope(t *testing.T) {
+	code := `outln("Hello, " + event["name"] + "!"); event["age"] + 10;`
+	resp := Run(context.Background(), code, json.RawMessage(`{"name": "Taro", "age": 25}`)).Response(ResponseEnvelope)
+
+	env, ok := resp.(Envelope)
+	if !ok {
+		t.Fatalf("expected Envelope, got %T", resp)
+	}
+
+	if env.Output != "Hello, Taro!\n" {
+		t.Errorf("unexpected output: %q", env.Output)
+	}
+	if env.Result != float64(35) {
+		t.Errorf("expected result 35, got %v", env.Result)
+	}
+	if env.Error != nil {
+		t.Errorf("expected no error, got %+v", env.Error)
+	}
+	if env.DurationMs <= 0 {
+		t.Errorf("expected positive duration, got %v", env.DurationMs)
+	}
+}
+
+func TestRun_EnvelopeOutput(t *testing.T) {
+	// 標準出力に書き込む組み込み関数はすべてキャプチャする
+	code := `outln("a"); outf("%s-%d\n", "b", 2); out("c"); "done";`
+	resp := Run(context.Background(), code, nil).Response(ResponseEnvelope)
+
+	env, ok := resp.(Envelope)
+	if !ok {
+		t.Fatalf("expected Envelope, got %T", resp)
+	}
+
+	if env.Output != "a\nb-2\nc" {
+		t.Errorf("unexpected output: %q", env.Output)
+	}
+	if env.Result != "done" {
+		t.Errorf("expected result \"done\", got %v", env.Result)
+	}
+}
+
+func TestRun_EnvelopeError(t *testing.T) {
+	code := "outln(\"start\");\nfunc fail() => { missing; }\nfail();"
+	resp := Run(context.Background(), code, nil).Response(ResponseEnvelope)
+
+	data, err := json.Marshal(resp)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var got map[string]interface{}
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	delete(got, "durationMs")
+	expected := map[string]interface{}{
+		"output": "start\n",
+		"result": nil,
+		"error": map[string]interface{}{
+			"message": "identifier not found: missing",
+			"kind":    "ReferenceError",
+			"line":    float64(2),
+			"column":  float64(18),
+			"stack": []interface{}{
+				map[string]interface{}{"function": "fail", "line": float64(3), "column": float64(5)},
+			},
+		},
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("unexpected envelope: %s", data)
+	}
+}
+
+func TestRun_EnvelopeErrorKinds(t *testing.T) {
+	tests := []struct {
+		code    string
+		event   json.RawMessage
+		message string
+		kind    string
+		line    int
+		column  int
+	}{
+		{"1 +", nil, "no prefix parse function for EOF found", "SyntaxError", 1, 4},
+		{`throw "boom"`, nil, "uncaught exception: boom", "Error", 0, 0},
+		{`in()`, nil, "in() is not available in Lambda environment", "IOError", 1, 3},
+		{`event`, json.RawMessage(`{`), "failed to parse event: invalid JSON at offset 1: unexpected end of JSON input", "ValueError", 0, 0},
+		{`func() => 1`, nil, "failed to convert result: cannot convert FUNCTION to a Go value", "TypeError", 0, 0},
+		{`mut m = {"a": 1}; m["self"] = m; m;`, nil, "failed to convert result: [self]: cannot convert cyclic MAP", "TypeError", 0, 0},
+	}
+
+	for _, tt := range tests {
+		env := Run(context.Background(), tt.code, tt.event).Response(ResponseEnvelope).(Envelope)
+		if env.Error == nil {
+			t.Errorf("code %q: expected error, got result %v", tt.code, env.Result)
+			continue
+		}
+		if env.Error.Message != tt.message || env.Error.Kind != tt.kind {
+			t.Errorf("code %q: expected %s %q, got %s %q", tt.code, tt.kind, tt.message, env.Error.Kind, env.Error.Message)
+		}
+		if env.Error.Line != tt.line || env.Error.Column != tt.column {
+			t.Errorf("code %q: expected position %d:%d, got %d:%d", tt.code, tt.line, tt.column, env.Error.Line, env.Error.Column)
+		}
+	}
+}
+
+func TestParseResponseMode(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected ResponseMode
+	}{
+		{"", ResponseEnvelope},
+		{"envelope", ResponseEnvelope},
+		{"raw", ResponseRaw},
+	}
+
+	for _, tt := range tests {
+		mode, err := ParseResponseMode(tt.input)
+		if err != nil || mode != tt.expected {
+			t.Errorf("input %q: expected %q, got %q (%v)", tt.input, tt.expected, mode, err)
+		}
+	}
+
+	if _, err := ParseResponseMode("json"); err == nil || err.Error() != `unknown response mode "json" (want envelope or raw)` {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestHandler_ResponseMode(t *testing.T) {
+	t.Chdir(t.TempDir())
+	if err := os.WriteFile("main.sugu", []byte(`outln("hi"); {"statusCode": 200, "body": event["name"]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	event := json.RawMessage(`{"name": "Taro"}`)
+
+	resp, err := Handler(context.Background(), event)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	env, ok := resp.(Envelope)
+	if !ok || env.Output != "hi\n" {
+		t.Errorf("expected envelope by default, got %#v", resp)
+	}
+
+	t.Setenv(ResponseModeEnv, "raw")
+	resp, err = Handler(context.Background(), event)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := map[string]interface{}{"statusCode": float64(200), "body": "Taro"}
+	if !reflect.DeepEqual(resp, expected) {
+		t.Errorf("expected raw value %v, got %#v", expected, resp)
+	}
+
+	t.Setenv(ResponseModeEnv, "text")
+	if _, err := Handler(context.Background(), event); err == nil {
+		t.Errorf("expected error for unknown response mode")
+	}
+}
@@ -0,0 +1,45 @@
+package lambda
+
+import (
+	"context"
+	"embed"
+	"encoding/json"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultTimeout は Invoke のタイムアウトの既定値（Lambda 関数の既定のタイムアウトと同じ）
+const DefaultTimeout = 3 * time.Second
+
+//go:embed events/*.json
+var sampleEvents embed.FS
+
+// Invoke は Lambda と同じ組み込み関数と期限で code を実行する（AWS にデプロイせずに動作を確認するため）
+// timeout は Lambda 関数のタイムアウトで、Lambda と同じくその deadlineMargin 前に実行を中断する
+func Invoke(code string, event json.RawMessage, timeout time.Duration) Result {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return Run(ctx, code, event)
+}
+
+// SampleEvent は名前が name のサンプルイベント（"apigateway"、"s3"、"sqs"）の JSON を返す
+func SampleEvent(name string) (json.RawMessage, bool) {
+	data, err := sampleEvents.ReadFile("events/" + name + ".json")
+	if err != nil {
+		return nil, false
+	}
+	return data, true
+}
+
+// SampleEventNames はサンプルイベントの名前を並べ替えて返す
+func SampleEventNames() []string {
+	entries, _ := sampleEvents.ReadDir("events")
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		names = append(names, strings.TrimSuffix(entry.Name(), path.Ext(entry.Name())))
+	}
+	sort.Strings(names)
+	return names
+}
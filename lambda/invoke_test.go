@@ -0,0 +1,76 @@
+package lambda
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestInvoke_CapturesOutput(t *testing.T) {
+	code := `outln("Hello, " + event["name"] + "!"); out("a", "b"); event["age"] + 10;`
+	inv := Invoke(code, json.RawMessage(`{"name": "Taro", "age": 25}`), DefaultTimeout)
+
+	if inv.Error != nil {
+		t.Fatalf("unexpected error: %v", inv.Error)
+	}
+
+	if inv.Value != float64(35) {
+		t.Errorf("expected 35, got %v", inv.Value)
+	}
+	if inv.Output != "Hello, Taro!\nab" {
+		t.Errorf("unexpected output: %q", inv.Output)
+	}
+	if inv.Duration <= 0 {
+		t.Errorf("expected positive duration, got %s", inv.Duration)
+	}
+}
+
+func TestInvoke_StopsBeforeTimeout(t *testing.T) {
+	timeout := deadlineMargin + 50*time.Millisecond
+	inv := Invoke("while (true) {}", nil, timeout)
+
+	if inv.Error == nil {
+		t.Fatalf("expected error, got %v", inv.Value)
+	}
+	expected := "execution limit exceeded: context deadline exceeded"
+	if inv.Error.Message != expected {
+		t.Errorf("expected %q, got %q", expected, inv.Error.Message)
+	}
+	if inv.Duration >= timeout {
+		t.Errorf("expected to stop before the timeout, took %s", inv.Duration)
+	}
+}
+
+func TestSampleEvents(t *testing.T) {
+	tests := []struct {
+		name     string
+		code     string
+		expected interface{}
+	}{
+		{"apigateway", `[event["httpMethod"], event["pathParameters"]["id"], jsonParse(event["body"])["quantity"]]`, []interface{}{"POST", "42", float64(3)}},
+		{"s3", `const s3 = event["Records"][0]["s3"]; s3["bucket"]["name"] + "/" + s3["object"]["key"]`, "example-bucket/uploads/report.csv"},
+		{"sqs", `map(event["Records"], func(r) => jsonParse(r["body"])["orderId"])`, []interface{}{float64(1001), float64(1002)}},
+	}
+
+	for _, tt := range tests {
+		event, ok := SampleEvent(tt.name)
+		if !ok {
+			t.Fatalf("sample event %q not found", tt.name)
+		}
+		inv := Invoke(tt.code, event, DefaultTimeout)
+		if inv.Error != nil {
+			t.Fatalf("%s: unexpected error: %v", tt.name, inv.Error)
+		}
+		if !reflect.DeepEqual(inv.Value, tt.expected) {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.expected, inv.Value)
+		}
+	}
+
+	if names := SampleEventNames(); !reflect.DeepEqual(names, []string{"apigateway", "s3", "sqs"}) {
+		t.Errorf("unexpected sample event names: %v", names)
+	}
+	if _, ok := SampleEvent("missing"); ok {
+		t.Errorf("expected missing sample event not to be found")
+	}
+}
@@ -1,9 +0,0 @@
-package main
-
-import (
-	"github.com/aws/aws-lambda-go/lambda"
-)
-
-func main() {
-	lambda.Start(Handler)
-}
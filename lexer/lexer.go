@@ -10,6 +10,7 @@ type Lexer struct {
 	readPosition int    // 次の位置（現在の文字の次を指す）
 	ch           byte   // 現在検査中の文字
 	stringError  string // 文字列パース中のエラー
+	templates    []int  // 読んでいるテンプレート文字列の ${} ごとの、閉じていない { の数（内側の ${} が最後）
 	line         int    // 現在の行番号（1から始まる）
 	column       int    // 現在の列番号（1から始まる）
 }
@@ -144,6 +145,14 @@ func (l *Lexer) NextToken() token.Token {
 		} else {
 			tok = l.newToken(token.ILLEGAL, l.ch)
 		}
+	case '.':
+		if l.peekChar() == '.' && l.readPosition+1 < len(l.input) && l.input[l.readPosition+1] == '.' {
+			l.readChar()
+			l.readChar()
+			tok = l.newTokenWithLiteral(token.ELLIPSIS, "...", startLine, startColumn)
+		} else {
+			tok = l.newToken(token.ILLEGAL, l.ch)
+		}
 	case ',':
 		tok = l.newToken(token.COMMA, l.ch)
 	case ';':
@@ -155,9 +164,21 @@ func (l *Lexer) NextToken() token.Token {
 	case ')':
 		tok = l.newToken(token.RPAREN, l.ch)
 	case '{':
+		if n := len(l.templates); n > 0 {
+			l.templates[n-1]++
+		}
 		tok = l.newToken(token.LBRACE, l.ch)
 	case '}':
-		tok = l.newToken(token.RBRACE, l.ch)
+		if n := len(l.templates); n > 0 && l.templates[n-1] == 0 {
+			// ${} の終わり: テンプレート文字列の続きを読む
+			l.templates = l.templates[:n-1]
+			tok = l.readTemplate(token.TEMPLATE_MIDDLE, token.TEMPLATE_TAIL, startLine, startColumn)
+		} else {
+			if n > 0 {
+				l.templates[n-1]--
+			}
+			tok = l.newToken(token.RBRACE, l.ch)
+		}
 	case '[':
 		tok = l.newToken(token.LBRACKET, l.ch)
 	case ']':
@@ -170,6 +191,8 @@ func (l *Lexer) NextToken() token.Token {
 		} else {
 			tok = l.newTokenWithLiteral(token.STRING, literal, startLine, startColumn)
 		}
+	case '`':
+		tok = l.readTemplate(token.TEMPLATE_HEAD, token.TEMPLATE, startLine, startColumn)
 	case 0:
 		tok = l.newTokenWithLiteral(token.EOF, "", startLine, startColumn)
 	default:
@@ -287,6 +310,45 @@ func (l *Lexer) readString() string {
 	return string(result)
 }
 
+// readTemplate は現在の文字（` または ${} を閉じる }）の次から、テンプレート文字列の次の ${ または終わりの ` までを読む
+// ${ で終わった場合は open、` で終わった場合は end の種類のトークンを返す
+// 文字列と同じエスケープシーケンスに加えて \` と \$ を使える。改行はそのまま文字列に含まれる
+func (l *Lexer) readTemplate(open, end token.TokenType, line, column int) token.Token {
+	var result []byte
+	l.readChar() // 開始の ` または } をスキップ
+
+	for l.ch != '`' && l.ch != 0 {
+		if l.ch == '$' && l.peekChar() == '{' {
+			l.readChar() // '{' は NextToken の最後に読み飛ばされる
+			l.templates = append(l.templates, 0)
+			return l.newTokenWithLiteral(open, string(result), line, column)
+		}
+		if l.ch == '\\' {
+			l.readChar()
+			switch l.ch {
+			case 'n':
+				result = append(result, '\n')
+			case 't':
+				result = append(result, '\t')
+			case 'r':
+				result = append(result, '\r')
+			case '"', '\\', '`', '$':
+				result = append(result, l.ch)
+			case 0:
+				return l.newTokenWithLiteral(token.ILLEGAL, "unexpected end of string after \\", line, column)
+			default:
+				return l.newTokenWithLiteral(token.ILLEGAL, "unknown escape sequence: \\"+string(l.ch), line, column)
+			}
+		} else {
+			result = append(result, l.ch)
+		}
+		l.readChar()
+	}
+
+	// 未終端のテンプレート文字列は文字列と同じく最後までを内容とする
+	return l.newTokenWithLiteral(end, string(result), line, column)
+}
+
 func isLetter(ch byte) bool {
 	return 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || ch == '_'
 }
@@ -629,3 +629,147 @@ func TestMultiCharTokenPositions(t *testing.T) {
 		}
 	}
 }
+
+func TestModuleKeywords(t *testing.T) {
+	input := `import "lib.sugu"; export const x = 1;`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.IMPORT, "import"},
+		{token.STRING, "lib.sugu"},
+		{token.SEMICOLON, ";"},
+		{token.EXPORT, "export"},
+		{token.CONST, "const"},
+		{token.IDENT, "x"},
+		{token.ASSIGN, "="},
+		{token.NUMBER, "1"},
+		{token.SEMICOLON, ";"},
+		{token.EOF, ""},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q",
+				i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestEllipsis(t *testing.T) {
+	input := `func(a, ...rest) .. .`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.FUNC, "func"},
+		{token.LPAREN, "("},
+		{token.IDENT, "a"},
+		{token.COMMA, ","},
+		{token.ELLIPSIS, "..."},
+		{token.IDENT, "rest"},
+		{token.RPAREN, ")"},
+		// "..." 以外の '.' は不正な文字
+		{token.ILLEGAL, "."},
+		{token.ILLEGAL, "."},
+		{token.ILLEGAL, "."},
+		{token.EOF, ""},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q",
+				i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestTemplateString(t *testing.T) {
+	input := "`plain` `a${x}b${ {k: 1} }c` `${`in${y}`}`\n`line1\nline2 \\` \\${z}`"
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+		expectedLine    int
+		expectedColumn  int
+	}{
+		{token.TEMPLATE, "plain", 1, 1},
+		{token.TEMPLATE_HEAD, "a", 1, 9},
+		{token.IDENT, "x", 1, 13},
+		{token.TEMPLATE_MIDDLE, "b", 1, 14},
+		// ${} の中の {} はテンプレート文字列を閉じない
+		{token.LBRACE, "{", 1, 19},
+		{token.IDENT, "k", 1, 20},
+		{token.COLON, ":", 1, 21},
+		{token.NUMBER, "1", 1, 23},
+		{token.RBRACE, "}", 1, 24},
+		{token.TEMPLATE_TAIL, "c", 1, 26},
+		// 入れ子のテンプレート文字列
+		{token.TEMPLATE_HEAD, "", 1, 30},
+		{token.TEMPLATE_HEAD, "in", 1, 33},
+		{token.IDENT, "y", 1, 38},
+		{token.TEMPLATE_TAIL, "", 1, 39},
+		{token.TEMPLATE_TAIL, "", 1, 41},
+		// 複数行とエスケープ
+		{token.TEMPLATE, "line1\nline2 ` ${z}", 2, 1},
+		{token.EOF, "", 3, 16},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q",
+				i, tt.expectedLiteral, tok.Literal)
+		}
+
+		if tok.Line != tt.expectedLine || tok.Column != tt.expectedColumn {
+			t.Fatalf("tests[%d] - position wrong. expected=%d:%d, got=%d:%d (token type=%q)",
+				i, tt.expectedLine, tt.expectedColumn, tok.Line, tok.Column, tok.Type)
+		}
+	}
+}
+
+func TestTemplateStringErrors(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedLiteral string
+	}{
+		{"`a\\qb`", "unknown escape sequence: \\q"},
+		{"`a\\", "unexpected end of string after \\"},
+	}
+
+	for _, tt := range tests {
+		tok := New(tt.input).NextToken()
+		if tok.Type != token.ILLEGAL || tok.Literal != tt.expectedLiteral {
+			t.Errorf("input %q: expected ILLEGAL %q, got %s %q", tt.input, tt.expectedLiteral, tok.Type, tok.Literal)
+		}
+	}
+}
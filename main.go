@@ -1,26 +1,211 @@
 package main
 
 import (
+	"encoding/json"
+	"flag"
 	"fmt"
+	"log"
+	"net/http"
 	"os"
+	"strings"
+	"sugu/evaluator"
+	"sugu/lambda"
 	"sugu/repl"
+	"sugu/server"
+	"sugu/sugu"
+	"time"
 )
 
+// runFlags は実行エンジンと実行の上限のフラグ（ファイルの実行とサブコマンドで共通）
+type runFlags struct {
+	engine       *string
+	maxSteps     *int64
+	timeout      *time.Duration
+	maxCallDepth *int
+	strictArity  *bool
+}
+
+// addRunFlags は fs に実行エンジンと実行の上限のフラグを追加する
+func addRunFlags(fs *flag.FlagSet) *runFlags {
+	return &runFlags{
+		engine:       fs.String("engine", string(sugu.EngineEval), "実行エンジン（eval: 木構造評価器, vm: バイトコード VM）"),
+		maxSteps:     fs.Int64("max-steps", 0, "実行できるステップ数の上限（0 は無制限）"),
+		timeout:      fs.Duration("timeout", 0, "実行時間の上限（例: 5s。0 は無制限）"),
+		maxCallDepth: fs.Int("max-call-depth", evaluator.DefaultMaxCallDepth, "関数呼び出しの深さの上限"),
+		strictArity:  fs.Bool("strict-arity", false, "関数の引数の数が合わない呼び出しをエラーにする"),
+	}
+}
+
+// limits はフラグで指定された実行の上限を返す
+func (f *runFlags) limits() evaluator.Limits {
+	return evaluator.Limits{MaxSteps: *f.maxSteps, Timeout: *f.timeout, MaxCallDepth: *f.maxCallDepth}
+}
+
+// checkEngine は --engine の値を確認し、不明なエンジンであれば usage を表示して終了する
+func (f *runFlags) checkEngine(usage func()) {
+	if *f.engine != string(sugu.EngineEval) && *f.engine != string(sugu.EngineVM) {
+		fmt.Fprintf(os.Stderr, "unknown engine: %s\n", *f.engine)
+		usage()
+		os.Exit(1)
+	}
+}
+
+// parseArgs は fs で arguments を解析し、フラグ以外の引数を返す（sugu serve handler.sugu --addr=:3000 のようにファイル名の後のフラグも解析する）
+func parseArgs(fs *flag.FlagSet, arguments []string) []string {
+	var args []string
+	for {
+		fs.Parse(arguments)
+		if fs.NArg() == 0 {
+			return args
+		}
+		args = append(args, fs.Arg(0))
+		arguments = fs.Args()[1:]
+	}
+}
+
 func main() {
-	args := os.Args[1:]
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "serve":
+			serve(os.Args[2:])
+			return
+		case "lambda-invoke":
+			lambdaInvoke(os.Args[2:])
+			return
+		}
+	}
+
+	f := addRunFlags(flag.CommandLine)
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: sugu [--engine=eval|vm] [--max-steps=N] [--timeout=DURATION] [--max-call-depth=N] [--strict-arity] [filename]")
+		fmt.Fprintln(os.Stderr, "       sugu serve [--addr=ADDR] [options] handler.sugu")
+		fmt.Fprintln(os.Stderr, "       sugu lambda-invoke [--event=FILE|NAME] [--timeout=DURATION] [--response=envelope|raw] main.sugu")
+	}
+	flag.Parse()
+	args := flag.Args()
+	f.checkEngine(flag.Usage)
 
 	if len(args) == 0 {
+		if *f.engine != string(sugu.EngineEval) {
+			fmt.Fprintln(os.Stderr, "--engine=vm requires a script file")
+			os.Exit(1)
+		}
+		if *f.strictArity {
+			fmt.Fprintln(os.Stderr, "--strict-arity requires a script file")
+			os.Exit(1)
+		}
 		// 引数なしの場合はREPLを起動
-		repl.Start(os.Stdin, os.Stdout)
+		repl.StartWithLimits(os.Stdin, os.Stdout, f.limits())
 	} else if len(args) == 1 {
 		// 引数がある場合はファイルを実行
 		filename := args[0]
-		if err := repl.RunFile(filename, os.Stdout); err != nil {
+		opts := repl.Options{Engine: sugu.Engine(*f.engine), Limits: f.limits(), StrictArity: *f.strictArity}
+		if err := repl.RunFileWithOptions(filename, opts, os.Stdout); err != nil {
 			fmt.Fprintf(os.Stderr, "%s\n", err)
 			os.Exit(1)
 		}
 	} else {
-		fmt.Fprintln(os.Stderr, "Usage: sugu [filename]")
+		flag.Usage()
+		os.Exit(1)
+	}
+}
+
+// serve は sugu serve サブコマンド: リクエストごとにハンドラーのスクリプトを実行する HTTP サーバーを起動する
+func serve(arguments []string) {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	addr := fs.String("addr", ":8080", "待ち受けるアドレス")
+	f := addRunFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: sugu serve [--addr=ADDR] [--engine=eval|vm] [--max-steps=N] [--timeout=DURATION] [--max-call-depth=N] [--strict-arity] handler.sugu")
+	}
+	args := parseArgs(fs, arguments)
+	f.checkEngine(fs.Usage)
+	if len(args) != 1 {
+		fs.Usage()
 		os.Exit(1)
 	}
+
+	filename := args[0]
+	if _, err := os.Stat(filename); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to read file: %s\n", err)
+		os.Exit(1)
+	}
+	handler := server.NewHandler(filename, sugu.Options{
+		Engine:      sugu.Engine(*f.engine),
+		Limits:      f.limits(),
+		StrictArity: *f.strictArity,
+	})
+
+	log.Printf("serving %s on %s", filename, *addr)
+	log.Fatal(http.ListenAndServe(*addr, handler))
+}
+
+// lambdaInvoke は sugu lambda-invoke サブコマンド: Lambda と同じ環境でスクリプトを 1 回実行する
+// レスポンスの JSON を標準出力に、実行時間（raw の場合はスクリプトの標準出力への出力も）を標準エラー出力に書き込む
+func lambdaInvoke(arguments []string) {
+	fs := flag.NewFlagSet("lambda-invoke", flag.ExitOnError)
+	eventFlag := fs.String("event", "", "event 変数に渡す JSON のファイル、またはサンプルイベントの名前（"+strings.Join(lambda.SampleEventNames(), ", ")+"）")
+	timeout := fs.Duration("timeout", lambda.DefaultTimeout, "Lambda 関数のタイムアウト")
+	response := fs.String("response", os.Getenv(lambda.ResponseModeEnv), "レスポンスの形式（envelope または raw。既定は環境変数 "+lambda.ResponseModeEnv+" の値か envelope）")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: sugu lambda-invoke [--event=FILE|NAME] [--timeout=DURATION] [--response=envelope|raw] main.sugu")
+		fs.PrintDefaults()
+	}
+	args := parseArgs(fs, arguments)
+	if len(args) != 1 {
+		fs.Usage()
+		os.Exit(1)
+	}
+	mode, err := lambda.ParseResponseMode(*response)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		os.Exit(1)
+	}
+
+	code, err := os.ReadFile(args[0])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to read file: %s\n", err)
+		os.Exit(1)
+	}
+	event, err := readEvent(*eventFlag)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to read event: %s\n", err)
+		os.Exit(1)
+	}
+
+	result := lambda.Invoke(string(code), event, *timeout)
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(result.Response(mode)); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to encode response: %s\n", err)
+		os.Exit(1)
+	}
+	// envelope ではレスポンスの output に含まれる
+	if mode == lambda.ResponseRaw && result.Output != "" {
+		fmt.Fprintf(os.Stderr, "Output:\n%s", result.Output)
+		if !strings.HasSuffix(result.Output, "\n") {
+			fmt.Fprintln(os.Stderr)
+		}
+	}
+	fmt.Fprintf(os.Stderr, "Duration: %s\n", result.Duration.Round(time.Microsecond))
+}
+
+// readEvent は --event の値のイベントの JSON を返す
+// サンプルイベントの名前であればその JSON、それ以外はファイル名として読み込む（空ならイベントなし）
+func readEvent(name string) (json.RawMessage, error) {
+	if name == "" {
+		return nil, nil
+	}
+	if event, ok := lambda.SampleEvent(name); ok {
+		return event, nil
+	}
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	if !json.Valid(data) {
+		return nil, fmt.Errorf("%s is not valid JSON", name)
+	}
+	return data, nil
 }
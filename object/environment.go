@@ -71,3 +71,20 @@ func (e *Environment) Update(name string, val Object) (Object, bool) {
 	}
 	return nil, false
 }
+
+// Outer は外側の環境を返す
+func (e *Environment) Outer() *Environment {
+	return e.outer
+}
+
+// Len は現在のスコープに定義されている変数の数を返す
+func (e *Environment) Len() int {
+	return len(e.store)
+}
+
+// Clear は現在のスコープの変数をすべて削除する
+// ループの反復ごとに同じ環境を使い回す場合に使用する
+func (e *Environment) Clear() {
+	clear(e.store)
+	clear(e.consts)
+}
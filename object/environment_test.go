@@ -118,3 +118,32 @@ func TestEnvironmentShadowing(t *testing.T) {
 		t.Errorf("expected 10 in outer scope, got %f", num.Value)
 	}
 }
+
+func TestEnvironmentClear(t *testing.T) {
+	outer := NewEnvironment()
+	outer.Set("x", &Number{Value: 10})
+
+	inner := NewEnclosedEnvironment(outer)
+	inner.SetConst("y", &Number{Value: 20})
+	inner.Set("z", &Number{Value: 30})
+
+	if inner.Outer() != outer {
+		t.Error("expected Outer to return the enclosing environment")
+	}
+	if inner.Len() != 2 {
+		t.Errorf("expected 2 variables, got %d", inner.Len())
+	}
+
+	inner.Clear()
+
+	if inner.Len() != 0 {
+		t.Errorf("expected 0 variables after Clear, got %d", inner.Len())
+	}
+	if inner.IsConst("y") {
+		t.Error("expected 'y' to not be const after Clear")
+	}
+	// 外側のスコープには影響しない
+	if _, ok := inner.Get("x"); !ok {
+		t.Error("expected 'x' to be visible from the outer scope")
+	}
+}
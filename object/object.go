@@ -3,9 +3,9 @@ package object
 import (
 	"fmt"
 	"math"
-	"sort"
 	"strings"
 	"sugu/ast"
+	"time"
 )
 
 // ObjectType はオブジェクトの型を表す
@@ -22,6 +22,25 @@ const (
 	BUILTIN_OBJ  ObjectType = "BUILTIN"
 	ARRAY_OBJ    ObjectType = "ARRAY"
 	MAP_OBJ      ObjectType = "MAP"
+	TIME_OBJ     ObjectType = "TIME"
+
+	EXCEPTION_OBJ ObjectType = "EXCEPTION"
+)
+
+// エラーの種類（Error.Kind と、catch で受け取る Exception の kind）
+const (
+	GENERIC_ERROR    = "Error"          // その他のエラー、error() の既定値
+	TYPE_ERROR       = "TypeError"      // 型が合わない演算や引数、const への代入など
+	ARGUMENT_ERROR   = "ArgumentError"  // 引数の数が合わない
+	VALUE_ERROR      = "ValueError"     // 型は正しいが値が不正（変換できない文字列など）
+	INDEX_ERROR      = "IndexError"     // インデックスが範囲外
+	DIVISION_BY_ZERO = "DivisionByZero" // 0 による除算・剰余
+	REFERENCE_ERROR  = "ReferenceError" // 未定義の識別子
+	IO_ERROR         = "IOError"        // ファイルや標準入力の読み書きの失敗
+	IMPORT_ERROR     = "ImportError"    // モジュールの読み込みの失敗
+	RANGE_ERROR      = "RangeError"     // 関数呼び出しが深すぎる
+	LIMIT_ERROR      = "LimitError"     // 実行の上限に達した（catch できない）
+	SYNTAX_ERROR     = "SyntaxError"    // 実行前に検出した構文のエラー
 )
 
 // Object はすべてのオブジェクトの基底インターフェース
@@ -38,9 +57,9 @@ type Number struct {
 func (n *Number) Type() ObjectType { return NUMBER_OBJ }
 func (n *Number) Inspect() string {
 	// 整数の場合は小数点以下を表示しない
-	// math.Truncを使用して正確に判定し、int64の範囲内かもチェック
+	// math.Truncを使用して正確に判定し、int64の範囲内かもチェック（float64 では math.MaxInt64 が 2^63 に丸められるため未満で比較する）
 	if math.Trunc(n.Value) == n.Value &&
-		n.Value >= math.MinInt64 && n.Value <= math.MaxInt64 {
+		n.Value >= math.MinInt64 && n.Value < math.MaxInt64 {
 		return fmt.Sprintf("%d", int64(n.Value))
 	}
 	return fmt.Sprintf("%g", n.Value)
@@ -73,6 +92,23 @@ type Null struct{}
 func (n *Null) Type() ObjectType { return NULL_OBJ }
 func (n *Null) Inspect() string  { return "null" }
 
+// null と真偽値のシングルトン
+// 評価器は null と真偽値を同一性で比較するため、新しく作らずにこれらを使う
+var (
+	NULL  = &Null{}
+	TRUE  = &Boolean{Value: true}
+	FALSE = &Boolean{Value: false}
+)
+
+// Time は日時を表す（タイムゾーンを含む）
+// Inspect は RFC 3339 形式の文字列を返す
+type Time struct {
+	Value time.Time
+}
+
+func (t *Time) Type() ObjectType { return TIME_OBJ }
+func (t *Time) Inspect() string  { return t.Value.Format(time.RFC3339Nano) }
+
 // ReturnValue はreturn文の戻り値をラップする
 type ReturnValue struct {
 	Value Object
@@ -82,27 +118,173 @@ func (rv *ReturnValue) Type() ObjectType { return RETURN_OBJ }
 func (rv *ReturnValue) Inspect() string  { return rv.Value.Inspect() }
 
 // Error はエラーを表す
+// 位置情報を持つ場合、Message には "line 1, column 1: ..." 形式の位置情報も含まれる
 type Error struct {
 	Message string
+	Kind    string // エラーの種類（TYPE_ERROR など。空文字列は GENERIC_ERROR として扱う）
+	File    string // エラーが発生したファイル（不明な場合は空文字列）
+	Line    int    // エラーが発生した行（不明な場合は 0）
+	Column  int    // エラーが発生した列（不明な場合は 0）
+	Source  string // エラーが発生した行のソースコード（不明な場合は空文字列）
+
+	Stack []StackFrame // エラーが発生した時点の呼び出し履歴（内側の呼び出しから順。トップレベルでは空）
 }
 
 func (e *Error) Type() ObjectType { return ERROR_OBJ }
 func (e *Error) Inspect() string  { return "ERROR: " + e.Message }
 
+// Error は Go の error としてのメッセージを返す（Go のアプリケーションにエラーを返すために使う）
+func (e *Error) Error() string { return e.Message }
+
+// Text は位置情報を除いたエラーメッセージを返す
+func (e *Error) Text() string {
+	if e.Line == 0 {
+		return e.Message
+	}
+	return strings.TrimPrefix(e.Message, FormatPosition(e.File, e.Line, e.Column)+": ")
+}
+
+// Excerpt はエラーが発生した行のソースコードと、その下の列を指す ^ の 2 行を返す
+// ソースコードが不明な場合は空文字列を返す
+func (e *Error) Excerpt() string {
+	return SourceExcerpt(e.Source, e.Column)
+}
+
+// SourceExcerpt は source の行と、column 列（バイト単位、1 から始まる）を指す ^ の 2 行を返す
+// ^ の前はタブをそのまま残し、それ以外の文字は 1 文字を空白 1 つに置き換えて位置を揃える
+func SourceExcerpt(source string, column int) string {
+	if source == "" || column <= 0 {
+		return ""
+	}
+
+	prefix := source
+	if column-1 < len(source) {
+		prefix = source[:column-1]
+	}
+	var caret strings.Builder
+	for _, r := range prefix {
+		if r == '\t' {
+			caret.WriteRune('\t')
+		} else {
+			caret.WriteRune(' ')
+		}
+	}
+	return "  " + source + "\n  " + caret.String() + "^"
+}
+
+// maxStackTraceFrames は StackTrace で先頭と末尾それぞれに表示するフレーム数
+const maxStackTraceFrames = 10
+
+// StackTrace は呼び出し履歴を 1 フレーム 1 行の文字列で返す（呼び出し履歴がなければ空文字列）
+// 深い再帰などでフレームが多い場合は、先頭と末尾のフレームだけを表示する
+func (e *Error) StackTrace() string {
+	lines := []string{}
+	for i, frame := range e.Stack {
+		if len(e.Stack) > 2*maxStackTraceFrames && i == maxStackTraceFrames {
+			lines = append(lines, fmt.Sprintf("  ... %d more frames", len(e.Stack)-2*maxStackTraceFrames))
+		}
+		if len(e.Stack) > 2*maxStackTraceFrames && i >= maxStackTraceFrames && i < len(e.Stack)-maxStackTraceFrames {
+			continue
+		}
+		lines = append(lines, "  at "+frame.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// StackFrame は呼び出し履歴の 1 フレーム（呼び出された関数と、呼び出した位置）
+type StackFrame struct {
+	Function string // 関数名（無名関数は空文字列）
+	File     string // 呼び出し位置のファイル（不明な場合は空文字列）
+	Line     int    // 呼び出し位置の行
+	Column   int    // 呼び出し位置の列
+}
+
+// String は "name (file, line 1, column 1)" 形式の文字列を返す
+func (f StackFrame) String() string {
+	name := f.Function
+	if name == "" {
+		name = "<anonymous>"
+	}
+	return fmt.Sprintf("%s (%s)", name, FormatPosition(f.File, f.Line, f.Column))
+}
+
+// FormatPosition は "file, line 1, column 1" 形式の位置を返す（file が空ならファイル名を省略）
+func FormatPosition(file string, line, column int) string {
+	pos := fmt.Sprintf("line %d, column %d", line, column)
+	if file != "" {
+		pos = file + ", " + pos
+	}
+	return pos
+}
+
+// Exception は catch で受け取るエラーを表す
+// 実行時エラーをキャッチすると、エラーの情報を持つ Exception が catch の変数に束縛される
+// e["message"], e["kind"], e["file"], e["line"], e["column"], e["stack"] で各情報を取り出せる
+type Exception struct {
+	Message string       // 位置情報を含まないメッセージ
+	Kind    string       // エラーの種類（TYPE_ERROR など）
+	File    string       // エラーが発生したファイル（不明な場合は空文字列）
+	Line    int          // エラーが発生した行（不明な場合は 0）
+	Column  int          // エラーが発生した列（不明な場合は 0）
+	Source  string       // エラーが発生した行のソースコード（不明な場合は空文字列）
+	Stack   []StackFrame // エラーが発生した時点の呼び出し履歴（内側の呼び出しから順）
+}
+
+func (e *Exception) Type() ObjectType { return EXCEPTION_OBJ }
+
+// Inspect は位置情報付きのメッセージを返す（キャッチしなかった場合のエラーメッセージと同じ）
+func (e *Exception) Inspect() string {
+	if e.Line == 0 {
+		return e.Message
+	}
+	return FormatPosition(e.File, e.Line, e.Column) + ": " + e.Message
+}
+
+// NewException は実行時エラーから catch で受け取る Exception を作成する
+func NewException(err *Error) *Exception {
+	kind := err.Kind
+	if kind == "" {
+		kind = GENERIC_ERROR
+	}
+	return &Exception{
+		Message: err.Text(),
+		Kind:    kind,
+		File:    err.File,
+		Line:    err.Line,
+		Column:  err.Column,
+		Source:  err.Source,
+		Stack:   err.Stack,
+	}
+}
+
+// ToError は Exception を実行時エラーに戻す（キャッチされずにプログラムが終了する場合に使う）
+func (e *Exception) ToError() *Error {
+	return &Error{
+		Message: e.Inspect(),
+		Kind:    e.Kind,
+		File:    e.File,
+		Line:    e.Line,
+		Column:  e.Column,
+		Source:  e.Source,
+		Stack:   e.Stack,
+	}
+}
+
 // Function は関数を表す
 type Function struct {
 	Parameters []*ast.Identifier
+	Defaults   []ast.Expression // パラメータのデフォルト値（ast.FunctionLiteral.Defaults と同じ）
+	Patterns   []ast.Pattern    // パラメータの分割代入のパターン（ast.FunctionLiteral.Patterns と同じ）
+	Rest       *ast.Identifier  // 残りの引数を受け取るパラメータ（なければ nil）
 	Body       *ast.BlockStatement
 	Env        *Environment
 	Name       string
+	File       string // 関数が定義されたファイル（エラー位置の表示に使用）
 }
 
 func (f *Function) Type() ObjectType { return FUNCTION_OBJ }
 func (f *Function) Inspect() string {
-	params := []string{}
-	for _, p := range f.Parameters {
-		params = append(params, p.String())
-	}
+	params := ast.ParameterStrings(f.Parameters, f.Defaults, f.Rest)
 
 	if f.Name != "" {
 		return fmt.Sprintf("func %s(%s) => { ... }", f.Name, strings.Join(params, ", "))
@@ -110,6 +292,22 @@ func (f *Function) Inspect() string {
 	return fmt.Sprintf("func(%s) => { ... }", strings.Join(params, ", "))
 }
 
+// Default は i 番目のパラメータのデフォルト値を返す（なければ nil）
+func (f *Function) Default(i int) ast.Expression {
+	if f.Defaults == nil {
+		return nil
+	}
+	return f.Defaults[i]
+}
+
+// Pattern は i 番目のパラメータの分割代入のパターンを返す（なければ nil）
+func (f *Function) Pattern(i int) ast.Pattern {
+	if f.Patterns == nil {
+		return nil
+	}
+	return f.Patterns[i]
+}
+
 // BuiltinFunction は組み込み関数の型
 type BuiltinFunction func(args ...Object) Object
 
@@ -140,7 +338,8 @@ type Hashable interface {
 	HashKey() HashKey
 }
 
-// HashKey はマップのキーを表す
+// HashKey はマップのキーのハッシュ値を表す
+// 等しいキーは同じ HashKey になるが、同じ HashKey のキーが等しいとは限らない
 type HashKey struct {
 	Type  ObjectType
 	Value uint64
@@ -153,27 +352,167 @@ type HashPair struct {
 }
 
 // Map はマップを表す
+// ペアは挿入した順に保持し、キーの検索と削除は O(1) で行う
+// HashKey が衝突したキーは同じチェーンにつなぎ、キーの値を比較して区別する
+// ゼロ値は空のマップとしてそのまま使える
 type Map struct {
-	Pairs map[HashKey]HashPair
+	entries []mapEntry      // 挿入順のペア（削除したペアは Key が nil）
+	index   map[HashKey]int // HashKey から、その HashKey を持つチェーンの先頭の位置
+	deleted int             // entries に残っている削除済みのペアの数
+}
+
+// mapEntry は Map のペアと衝突チェーンのリンク
+type mapEntry struct {
+	pair HashPair
+	next int // 同じ HashKey を持つ次のエントリの位置（-1 なら末尾）
 }
 
 func (m *Map) Type() ObjectType { return MAP_OBJ }
 func (m *Map) Inspect() string {
 	pairs := []string{}
-	for _, pair := range m.Pairs {
+	for _, pair := range m.Pairs() {
 		pairs = append(pairs, fmt.Sprintf("%s: %s", pair.Key.Inspect(), pair.Value.Inspect()))
 	}
-	// 順序を安定化するためソート
-	sort.Strings(pairs)
 	return "{" + strings.Join(pairs, ", ") + "}"
 }
 
+// Len はペアの数を返す
+func (m *Map) Len() int {
+	return len(m.entries) - m.deleted
+}
+
+// Get は key に対応するペアを返す
+func (m *Map) Get(key Hashable) (HashPair, bool) {
+	i, _ := m.find(key, key.HashKey())
+	if i < 0 {
+		return HashPair{}, false
+	}
+	return m.entries[i].pair, true
+}
+
+// Set は key に value を設定する
+// 既存のキーの場合は値だけを置き換え、順序と最初に設定したキーは変わらない
+func (m *Map) Set(key Hashable, value Object) {
+	hashKey := key.HashKey()
+	if i, _ := m.find(key, hashKey); i >= 0 {
+		m.entries[i].pair.Value = value
+		return
+	}
+	if m.index == nil {
+		m.index = make(map[HashKey]int)
+	}
+	m.add(hashKey, HashPair{Key: key.(Object), Value: value})
+}
+
+// Delete は key のペアを削除し、削除したかどうかを返す
+func (m *Map) Delete(key Hashable) bool {
+	hashKey := key.HashKey()
+	i, prev := m.find(key, hashKey)
+	if i < 0 {
+		return false
+	}
+	next := m.entries[i].next
+	switch {
+	case prev >= 0:
+		m.entries[prev].next = next
+	case next >= 0:
+		m.index[hashKey] = next
+	default:
+		delete(m.index, hashKey)
+	}
+	m.entries[i] = mapEntry{next: -1}
+	m.deleted++
+	// 削除済みが半分を超えたら詰め直す（削除は償却 O(1)）
+	if m.deleted > len(m.entries)/2 {
+		m.compact()
+	}
+	return true
+}
+
+// Pairs は挿入順のペアを返す
+// 返すスライスはコピーなので、反復中にマップを変更してもよい
+func (m *Map) Pairs() []HashPair {
+	pairs := make([]HashPair, 0, m.Len())
+	for _, entry := range m.entries {
+		if entry.pair.Key != nil {
+			pairs = append(pairs, entry.pair)
+		}
+	}
+	return pairs
+}
+
+// find は key のエントリの位置と、チェーン上でその直前のエントリの位置を返す（ない場合は -1）
+func (m *Map) find(key Hashable, hashKey HashKey) (i, prev int) {
+	i, ok := m.index[hashKey]
+	if !ok {
+		return -1, -1
+	}
+	prev = -1
+	for i >= 0 {
+		if keyEqual(m.entries[i].pair.Key, key.(Object)) {
+			return i, prev
+		}
+		prev, i = i, m.entries[i].next
+	}
+	return -1, -1
+}
+
+// compact は削除済みのエントリを取り除いて索引を作り直す
+func (m *Map) compact() {
+	entries := m.entries
+	m.entries = make([]mapEntry, 0, m.Len())
+	m.deleted = 0
+	clear(m.index)
+	for _, entry := range entries {
+		if entry.pair.Key == nil {
+			continue
+		}
+		m.add(entry.pair.Key.(Hashable).HashKey(), entry.pair)
+	}
+}
+
+// add は pair を末尾に追加し、hashKey のチェーンの先頭につなぐ
+func (m *Map) add(hashKey HashKey, pair HashPair) {
+	next, ok := m.index[hashKey]
+	if !ok {
+		next = -1
+	}
+	m.index[hashKey] = len(m.entries)
+	m.entries = append(m.entries, mapEntry{pair: pair, next: next})
+}
+
+// keyEqual はマップのキーとして a と b が等しいかどうかを返す
+// 数値は 0 と -0、NaN 同士を等しいとみなす
+func keyEqual(a, b Object) bool {
+	switch a := a.(type) {
+	case *String:
+		b, ok := b.(*String)
+		return ok && a.Value == b.Value
+	case *Number:
+		b, ok := b.(*Number)
+		return ok && (a.Value == b.Value || math.IsNaN(a.Value) && math.IsNaN(b.Value))
+	case *Boolean:
+		b, ok := b.(*Boolean)
+		return ok && a.Value == b.Value
+	}
+	return a == b
+}
+
 // Number の HashKey メソッド
+// -0 は 0 と、NaN はすべて同じ HashKey になる
 func (n *Number) HashKey() HashKey {
-	return HashKey{Type: n.Type(), Value: math.Float64bits(n.Value)}
+	value := n.Value
+	switch {
+	case value == 0:
+		value = 0
+	case math.IsNaN(value):
+		value = math.NaN()
+	}
+	return HashKey{Type: n.Type(), Value: math.Float64bits(value)}
 }
 
 // String の HashKey メソッド
+// 異なる文字列が同じ HashKey になることがある（Map はキーの値も比較して区別する）
 func (s *String) HashKey() HashKey {
 	h := uint64(0)
 	for i, c := range s.Value {
@@ -2,6 +2,7 @@ package object
 
 import (
 	"math"
+	"strings"
 	"sugu/ast"
 	"sugu/token"
 	"testing"
@@ -17,6 +18,9 @@ func TestNumberInspect(t *testing.T) {
 		{0, "0"},
 		{-10, "-10"},
 		{100.5, "100.5"},
+		{-9223372036854775808, "-9223372036854775808"},
+		// 2^63 は int64 に収まらない
+		{9223372036854775808, "9.223372036854776e+18"},
 	}
 
 	for _, tt := range tests {
@@ -178,3 +182,189 @@ func TestNumberInspectLargeValues(t *testing.T) {
 		t.Errorf("Number.Inspect() for NaN wrong. got=%s, want=NaN", nanNum.Inspect())
 	}
 }
+
+func TestSourceExcerpt(t *testing.T) {
+	tests := []struct {
+		source   string
+		column   int
+		expected string
+	}{
+		{"mut x = 10 / 0;", 12, "  mut x = 10 / 0;\n             ^"},
+		{"x", 1, "  x\n  ^"},
+		// タブはそのまま残して位置を揃える
+		{"\t\treturn a + b;", 12, "  \t\treturn a + b;\n  \t\t         ^"},
+		// 列はバイト単位だが、^ の位置は文字単位で揃える
+		{`outln("あい" + 1);`, 16, "  outln(\"あい\" + 1);\n             ^"},
+		// 行末より後ろの列（EOF など）は行末の次を指す
+		{"f(", 3, "  f(\n    ^"},
+		// ソースコードが不明な場合は空
+		{"", 1, ""},
+	}
+
+	for _, tt := range tests {
+		got := SourceExcerpt(tt.source, tt.column)
+		if got != tt.expected {
+			t.Errorf("SourceExcerpt(%q, %d) wrong.\nwant=%q\ngot=%q", tt.source, tt.column, tt.expected, got)
+		}
+	}
+
+	err := &Error{Message: "line 1, column 1: oops", Line: 1, Column: 1, Source: "oops"}
+	if err.Excerpt() != "  oops\n  ^" {
+		t.Errorf("Excerpt() wrong. got=%q", err.Excerpt())
+	}
+}
+
+func TestErrorStackTrace(t *testing.T) {
+	err := &Error{Message: "division by zero", Stack: []StackFrame{
+		{Function: "divide", File: "main.sugu", Line: 5, Column: 10},
+		{Function: "", Line: 8, Column: 2},
+	}}
+	expected := "  at divide (main.sugu, line 5, column 10)\n  at <anonymous> (line 8, column 2)"
+	if err.StackTrace() != expected {
+		t.Errorf("StackTrace() wrong.\nwant=%q\ngot=%q", expected, err.StackTrace())
+	}
+
+	if (&Error{Message: "top level"}).StackTrace() != "" {
+		t.Errorf("StackTrace() should be empty without frames")
+	}
+
+	// フレームが多い場合は先頭と末尾だけを表示する
+	deep := &Error{}
+	for i := 0; i < 100; i++ {
+		deep.Stack = append(deep.Stack, StackFrame{Function: "f", Line: i + 1, Column: 1})
+	}
+	lines := strings.Split(deep.StackTrace(), "\n")
+	if len(lines) != 21 {
+		t.Fatalf("wrong number of lines. want=21, got=%d", len(lines))
+	}
+	if lines[10] != "  ... 80 more frames" {
+		t.Errorf("wrong omission line. got=%q", lines[10])
+	}
+	if lines[20] != "  at f (line 100, column 1)" {
+		t.Errorf("wrong last line. got=%q", lines[20])
+	}
+}
+
+func TestException(t *testing.T) {
+	err := &Error{
+		Message: "main.sugu, line 2, column 5: identifier not found: x",
+		Kind:    REFERENCE_ERROR,
+		File:    "main.sugu",
+		Line:    2,
+		Column:  5,
+		Stack:   []StackFrame{{Function: "f", File: "main.sugu", Line: 4, Column: 2}},
+	}
+
+	exc := NewException(err)
+	if exc.Message != "identifier not found: x" {
+		t.Errorf("Message wrong. got=%q", exc.Message)
+	}
+	if exc.Kind != REFERENCE_ERROR || exc.File != "main.sugu" || exc.Line != 2 || exc.Column != 5 || len(exc.Stack) != 1 {
+		t.Errorf("fields wrong. got=%+v", exc)
+	}
+	if exc.Inspect() != err.Message {
+		t.Errorf("Inspect() wrong. got=%q, want=%q", exc.Inspect(), err.Message)
+	}
+
+	back := exc.ToError()
+	if back.Message != err.Message || back.Kind != err.Kind || len(back.Stack) != 1 {
+		t.Errorf("ToError() wrong. got=%+v", back)
+	}
+
+	// 種類を持たないエラーは GENERIC_ERROR になる
+	if kind := NewException(&Error{Message: "oops"}).Kind; kind != GENERIC_ERROR {
+		t.Errorf("Kind wrong. got=%q", kind)
+	}
+}
+
+func TestMap(t *testing.T) {
+	m := &Map{}
+	if m.Len() != 0 || m.Inspect() != "{}" {
+		t.Fatalf("zero Map should be empty. got=%s", m.Inspect())
+	}
+
+	for i := 0; i < 10; i++ {
+		m.Set(&Number{Value: float64(i)}, &String{Value: "v"})
+	}
+	m.Set(&Number{Value: 0}, &String{Value: "first"})
+	if pair, ok := m.Get(&Number{Value: 0}); !ok || pair.Value.Inspect() != "first" {
+		t.Errorf("Get(0) wrong. got=%v, %t", pair.Value, ok)
+	}
+
+	// 半分以上を削除して詰め直しが起きても順序と索引が保たれる
+	for i := 1; i < 9; i++ {
+		if !m.Delete(&Number{Value: float64(i)}) {
+			t.Errorf("Delete(%d) should return true", i)
+		}
+	}
+	if m.Delete(&Number{Value: 1}) {
+		t.Errorf("Delete of missing key should return false")
+	}
+	m.Set(&String{Value: "x"}, TRUE)
+
+	expected := "{0: first, 9: v, x: true}"
+	if m.Inspect() != expected {
+		t.Errorf("Inspect() wrong. got=%q, want=%q", m.Inspect(), expected)
+	}
+	if m.Len() != 3 || len(m.Pairs()) != 3 {
+		t.Errorf("wrong length. Len()=%d, len(Pairs())=%d", m.Len(), len(m.Pairs()))
+	}
+	if pair, ok := m.Get(&Number{Value: 9}); !ok || pair.Key.Inspect() != "9" {
+		t.Errorf("Get(9) after compaction failed. got=%v, %t", pair.Key, ok)
+	}
+}
+
+func TestMapHashCollisions(t *testing.T) {
+	// 実際に HashKey が衝突する文字列
+	colliding := []string{"AaAa", "AaBB", "BBAa", "BBBB"}
+	for _, s := range colliding[1:] {
+		if (&String{Value: s}).HashKey() != (&String{Value: colliding[0]}).HashKey() {
+			t.Fatalf("%q and %q should have the same HashKey", s, colliding[0])
+		}
+	}
+
+	m := &Map{}
+	for i, s := range colliding {
+		m.Set(&String{Value: s}, &Number{Value: float64(i)})
+	}
+	expected := "{AaAa: 0, AaBB: 1, BBAa: 2, BBBB: 3}"
+	if m.Inspect() != expected {
+		t.Fatalf("Inspect() wrong. got=%q, want=%q", m.Inspect(), expected)
+	}
+
+	// チェーンの途中・先頭・末尾を削除しても残りのキーを取り出せる
+	for _, s := range []string{"BBAa", "BBBB", "AaAa"} {
+		if !m.Delete(&String{Value: s}) {
+			t.Errorf("Delete(%q) should return true", s)
+		}
+		if _, ok := m.Get(&String{Value: s}); ok {
+			t.Errorf("%q should be deleted", s)
+		}
+	}
+	if pair, ok := m.Get(&String{Value: "AaBB"}); !ok || pair.Value.Inspect() != "1" {
+		t.Errorf("Get(AaBB) wrong. got=%v, %t", pair.Value, ok)
+	}
+	if _, ok := m.Get(&String{Value: "BBAa"}); ok || m.Len() != 1 {
+		t.Errorf("wrong map after deletion. got=%s", m.Inspect())
+	}
+}
+
+func TestMapNumberKeys(t *testing.T) {
+	m := &Map{}
+	m.Set(&Number{Value: 0}, &String{Value: "zero"})
+	m.Set(&Number{Value: math.Copysign(0, -1)}, &String{Value: "negative zero"})
+	m.Set(&Number{Value: math.NaN()}, &String{Value: "nan"})
+	m.Set(&Number{Value: -math.NaN()}, &String{Value: "nan again"})
+	m.Set(&String{Value: "0"}, &String{Value: "string"})
+
+	expected := "{0: negative zero, NaN: nan again, 0: string}"
+	if m.Inspect() != expected {
+		t.Errorf("Inspect() wrong. got=%q, want=%q", m.Inspect(), expected)
+	}
+	if pair, ok := m.Get(&Number{Value: math.Copysign(0, -1)}); !ok || pair.Key.Inspect() != "0" {
+		t.Errorf("-0 should find the key 0. got=%v, %t", pair.Key, ok)
+	}
+	if !m.Delete(&Number{Value: math.NaN()}) || m.Len() != 2 {
+		t.Errorf("NaN key should be deleted. got=%s", m.Inspect())
+	}
+}
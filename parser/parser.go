@@ -105,6 +105,10 @@ func (p *Parser) parseStatement() ast.Statement {
 		return p.parseTryStatement()
 	case token.THROW:
 		return p.parseThrowStatement()
+	case token.IMPORT:
+		return p.parseImportStatement()
+	case token.EXPORT:
+		return p.parseExportStatement()
 	default:
 		return p.parseExpressionStatement()
 	}
@@ -114,12 +118,20 @@ func (p *Parser) parseStatement() ast.Statement {
 func (p *Parser) parseVariableStatement() *ast.VariableStatement {
 	stmt := &ast.VariableStatement{Token: p.curToken}
 
-	if !p.expectPeek(token.IDENT) {
-		return nil
+	// 分割代入（const [a, b] = arr; / mut {"name": n} = person;）
+	if p.peekTokenIs(token.LBRACKET) || p.peekTokenIs(token.LBRACE) {
+		p.nextToken()
+		stmt.Pattern = p.parsePattern()
+		if stmt.Pattern == nil {
+			return nil
+		}
+	} else {
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+		stmt.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
 	}
 
-	stmt.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
-
 	if !p.expectPeek(token.ASSIGN) {
 		return nil
 	}
@@ -135,6 +147,140 @@ func (p *Parser) parseVariableStatement() *ast.VariableStatement {
 	return stmt
 }
 
+// parsePattern は現在のトークンから始まる分割代入のパターン（変数名、[...]、{...}）をパース
+// 1 つのパターンの中で同じ変数名を 2 回宣言することはできない
+func (p *Parser) parsePattern() ast.Pattern {
+	pattern := p.parsePatternElement()
+	if pattern == nil {
+		return nil
+	}
+
+	seen := map[string]bool{}
+	for _, ident := range ast.PatternNames(pattern) {
+		if seen[ident.Value] {
+			msg := fmt.Sprintf("line %d, column %d: duplicate variable %s in pattern",
+				ident.Token.Line, ident.Token.Column, ident.Value)
+			p.errors = append(p.errors, msg)
+			return nil
+		}
+		seen[ident.Value] = true
+	}
+	return pattern
+}
+
+// parsePatternElement はパターンの要素を 1 つパース（入れ子のパターンを含む）
+func (p *Parser) parsePatternElement() ast.Pattern {
+	switch p.curToken.Type {
+	case token.IDENT:
+		return &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	case token.LBRACKET:
+		return p.parseArrayPattern()
+	case token.LBRACE:
+		return p.parseMapPattern()
+	}
+	msg := fmt.Sprintf("line %d, column %d: expected variable name or pattern, got %s instead",
+		p.curToken.Line, p.curToken.Column, p.curToken.Type)
+	p.errors = append(p.errors, msg)
+	return nil
+}
+
+// parseArrayPattern は配列のパターン（[a, b, ...rest]）をパース
+func (p *Parser) parseArrayPattern() ast.Pattern {
+	pattern := &ast.ArrayPattern{Token: p.curToken, Elements: []ast.Pattern{}}
+
+	for !p.peekTokenIs(token.RBRACKET) {
+		p.nextToken()
+
+		if p.curTokenIs(token.ELLIPSIS) {
+			if !p.expectPeek(token.IDENT) {
+				return nil
+			}
+			pattern.Rest = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+			if !p.peekTokenIs(token.RBRACKET) {
+				msg := fmt.Sprintf("line %d, column %d: rest element %s must be the last element",
+					p.peekToken.Line, p.peekToken.Column, pattern.Rest.Value)
+				p.errors = append(p.errors, msg)
+				return nil
+			}
+			break
+		}
+
+		elem := p.parsePatternElement()
+		if elem == nil {
+			return nil
+		}
+		pattern.Elements = append(pattern.Elements, elem)
+
+		if !p.peekTokenIs(token.RBRACKET) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+
+	if !p.expectPeek(token.RBRACKET) {
+		return nil
+	}
+	return pattern
+}
+
+// parseMapPattern はマップのパターン（{"name": n, "age": a}）をパース
+// キーは文字列・数値・真偽値のリテラルに限る
+func (p *Parser) parseMapPattern() ast.Pattern {
+	pattern := &ast.MapPattern{Token: p.curToken}
+
+	for !p.peekTokenIs(token.RBRACE) {
+		p.nextToken()
+
+		var key ast.Expression
+		switch p.curToken.Type {
+		case token.STRING:
+			key = &ast.StringLiteral{Token: p.curToken, Value: p.curToken.Literal}
+		case token.NUMBER:
+			key = &ast.NumberLiteral{Token: p.curToken, Value: p.curToken.Literal}
+		case token.TRUE, token.FALSE:
+			key = &ast.BooleanLiteral{Token: p.curToken, Value: p.curTokenIs(token.TRUE)}
+		default:
+			msg := fmt.Sprintf("line %d, column %d: map pattern key must be a STRING, NUMBER or BOOLEAN literal, got %s instead",
+				p.curToken.Line, p.curToken.Column, p.curToken.Type)
+			p.errors = append(p.errors, msg)
+			return nil
+		}
+
+		if !p.expectPeek(token.COLON) {
+			return nil
+		}
+		p.nextToken()
+		value := p.parsePatternElement()
+		if value == nil {
+			return nil
+		}
+		pattern.Pairs = append(pattern.Pairs, ast.PatternPair{Key: key, Value: value})
+
+		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+
+	if !p.expectPeek(token.RBRACE) {
+		return nil
+	}
+	return pattern
+}
+
+// patternIdentifier はパターンを表示する仮の識別子を作る（for-in の変数と関数のパラメータに使う）
+func patternIdentifier(pattern ast.Pattern) *ast.Identifier {
+	if ident, ok := pattern.(*ast.Identifier); ok {
+		return ident
+	}
+	tok := token.Token{Type: token.IDENT, Literal: pattern.String()}
+	switch pattern := pattern.(type) {
+	case *ast.ArrayPattern:
+		tok.Line, tok.Column = pattern.Token.Line, pattern.Token.Column
+	case *ast.MapPattern:
+		tok.Line, tok.Column = pattern.Token.Line, pattern.Token.Column
+	}
+	return &ast.Identifier{Token: tok, Value: tok.Literal}
+}
+
 // parseReturnStatement はreturn文をパース
 func (p *Parser) parseReturnStatement() *ast.ReturnStatement {
 	stmt := &ast.ReturnStatement{Token: p.curToken}
@@ -235,6 +381,8 @@ func (p *Parser) parseExpression(precedence int) ast.Expression {
 		leftExp = &ast.NumberLiteral{Token: p.curToken, Value: p.curToken.Literal}
 	case token.STRING:
 		leftExp = &ast.StringLiteral{Token: p.curToken, Value: p.curToken.Literal}
+	case token.TEMPLATE, token.TEMPLATE_HEAD:
+		leftExp = p.parseTemplateLiteral()
 	case token.TRUE, token.FALSE:
 		leftExp = &ast.BooleanLiteral{Token: p.curToken, Value: p.curTokenIs(token.TRUE)}
 	case token.NULL:
@@ -464,6 +612,12 @@ func (p *Parser) parseBlockStatement() *ast.BlockStatement {
 	p.nextToken()
 
 	for !p.curTokenIs(token.RBRACE) && !p.curTokenIs(token.EOF) {
+		// import/export はトップレベルでのみ使用できる
+		if p.curTokenIs(token.IMPORT) || p.curTokenIs(token.EXPORT) {
+			msg := fmt.Sprintf("line %d, column %d: %s is only allowed at the top level",
+				p.curToken.Line, p.curToken.Column, p.curToken.Literal)
+			p.errors = append(p.errors, msg)
+		}
 		stmt := p.parseStatement()
 		if stmt != nil {
 			block.Statements = append(block.Statements, stmt)
@@ -512,36 +666,53 @@ func (p *Parser) parseForStatement() ast.Statement {
 		firstIdent := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
 
 		// for (item in expr) { ... } — 単一変数 for-in
-		if p.peekTokenIs(token.IDENT) && p.peekToken.Literal == "in" {
-			return p.parseForInRest(forToken, firstIdent, nil)
-		}
-
 		// for (key, value in expr) { ... } — 2変数 for-in
-		if p.peekTokenIs(token.COMMA) {
-			p.nextToken() // consume ','
-			if !p.expectPeek(token.IDENT) {
-				return nil
-			}
-			secondIdent := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
-			if p.peekTokenIs(token.IDENT) && p.peekToken.Literal == "in" {
-				return p.parseForInRest(forToken, firstIdent, secondIdent)
-			}
-			msg := fmt.Sprintf("line %d, column %d: expected 'in' in for-in statement, got %s",
-				p.peekToken.Line, p.peekToken.Column, p.peekToken.Literal)
-			p.errors = append(p.errors, msg)
-			return nil
+		if (p.peekTokenIs(token.IDENT) && p.peekToken.Literal == "in") || p.peekTokenIs(token.COMMA) {
+			return p.parseForInVariables(forToken, firstIdent)
 		}
 
 		// for-in ではない: 通常の for 文（IDENT で始まる初期化式）
 		return p.parseRegularForWithIdent(forToken, firstIdent)
 	}
 
+	// for ([a, b] in expr) { ... } — 分割代入の for-in
+	if p.peekTokenIs(token.LBRACKET) || p.peekTokenIs(token.LBRACE) {
+		p.nextToken()
+		key := p.parsePattern()
+		if key == nil {
+			return nil
+		}
+		return p.parseForInVariables(forToken, key)
+	}
+
 	// 通常の for 文（mut/const/セミコロン始まり）
 	return p.parseRegularFor(forToken)
 }
 
+// parseForInVariables は for-in 文の最初の変数（key）より後をパースする
+// 2 つ目の変数も分割代入のパターンにできる
+func (p *Parser) parseForInVariables(forToken token.Token, key ast.Pattern) ast.Statement {
+	var value ast.Pattern
+	if p.peekTokenIs(token.COMMA) {
+		p.nextToken() // consume ','
+		p.nextToken()
+		value = p.parsePattern()
+		if value == nil {
+			return nil
+		}
+	}
+
+	if !p.peekTokenIs(token.IDENT) || p.peekToken.Literal != "in" {
+		msg := fmt.Sprintf("line %d, column %d: expected 'in' in for-in statement, got %s",
+			p.peekToken.Line, p.peekToken.Column, p.peekToken.Literal)
+		p.errors = append(p.errors, msg)
+		return nil
+	}
+	return p.parseForInRest(forToken, key, value)
+}
+
 // parseForInRest は for-in 文の "in" 以降をパースする
-func (p *Parser) parseForInRest(forToken token.Token, key *ast.Identifier, value *ast.Identifier) *ast.ForInStatement {
+func (p *Parser) parseForInRest(forToken token.Token, key ast.Pattern, value ast.Pattern) *ast.ForInStatement {
 	p.nextToken() // consume "in"
 	p.nextToken() // move to iterable expression
 	iterable := p.parseExpression(LOWEST)
@@ -554,13 +725,22 @@ func (p *Parser) parseForInRest(forToken token.Token, key *ast.Identifier, value
 	}
 	body := p.parseBlockStatement()
 
-	return &ast.ForInStatement{
+	stmt := &ast.ForInStatement{
 		Token:    forToken,
-		Key:      key,
-		Value:    value,
+		Key:      patternIdentifier(key),
 		Iterable: iterable,
 		Body:     body,
 	}
+	if _, ok := key.(*ast.Identifier); !ok {
+		stmt.KeyPattern = key
+	}
+	if value != nil {
+		stmt.Value = patternIdentifier(value)
+		if _, ok := value.(*ast.Identifier); !ok {
+			stmt.ValuePattern = value
+		}
+	}
+	return stmt
 }
 
 // parseRegularForWithIdent は最初の IDENT を消費済みの通常の for 文をパースする
@@ -759,47 +939,147 @@ func (p *Parser) parseFunctionLiteral() ast.Expression {
 		return nil
 	}
 
-	lit.Parameters = p.parseFunctionParameters()
+	if !p.parseFunctionParameters(lit) {
+		return nil
+	}
 
 	if !p.expectPeek(token.ARROW) {
 		return nil
 	}
 
-	if !p.expectPeek(token.LBRACE) {
-		return nil
+	// => の後が { でなければ式 1 つの本体（func(x) => x * 2）
+	if !p.peekTokenIs(token.LBRACE) {
+		p.nextToken()
+		stmt := &ast.ExpressionStatement{Token: p.curToken}
+		stmt.Expression = p.parseExpression(LOWEST)
+		lit.Body = &ast.BlockStatement{Token: stmt.Token, Statements: []ast.Statement{stmt}}
+		return lit
 	}
 
+	p.nextToken()
 	lit.Body = p.parseBlockStatement()
 
 	return lit
 }
 
-// parseFunctionParameters は関数のパラメータリストをパース
-func (p *Parser) parseFunctionParameters() []*ast.Identifier {
-	identifiers := []*ast.Identifier{}
+// parseFunctionParameters は関数のパラメータリストをパースして lit に設定する
+// デフォルト値（b = 10）を持つパラメータの後には、デフォルト値を持つパラメータか ...rest しか書けない
+// ...rest は最後に 1 つだけ書ける。パラメータには分割代入のパターン（[a, b]、{"k": v}）も書ける
+func (p *Parser) parseFunctionParameters(lit *ast.FunctionLiteral) bool {
+	lit.Parameters = []*ast.Identifier{}
 
 	if p.peekTokenIs(token.RPAREN) {
 		p.nextToken()
-		return identifiers
+		return true
 	}
 
-	p.nextToken()
+	for {
+		p.nextToken()
 
-	ident := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
-	identifiers = append(identifiers, ident)
+		if p.curTokenIs(token.ELLIPSIS) {
+			if !p.expectPeek(token.IDENT) {
+				return false
+			}
+			lit.Rest = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+			if p.peekTokenIs(token.ASSIGN) {
+				p.parameterError(p.peekToken, "rest parameter %s cannot have a default value", lit.Rest.Value)
+				return false
+			}
+			if !p.peekTokenIs(token.RPAREN) {
+				p.parameterError(p.peekToken, "rest parameter %s must be the last parameter", lit.Rest.Value)
+				return false
+			}
+			break
+		}
 
-	for p.peekTokenIs(token.COMMA) {
-		p.nextToken()
+		var ident *ast.Identifier
+		switch p.curToken.Type {
+		case token.IDENT:
+			ident = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+		case token.LBRACKET, token.LBRACE:
+			// 分割代入のパラメータ（func([a, b]) => ...）
+			pattern := p.parsePattern()
+			if pattern == nil {
+				return false
+			}
+			ident = patternIdentifier(pattern)
+			if lit.Patterns == nil {
+				lit.Patterns = make([]ast.Pattern, len(lit.Parameters), len(lit.Parameters)+1)
+			}
+			lit.Patterns = append(lit.Patterns, pattern)
+		default:
+			p.parameterError(p.curToken, "expected parameter name, got %s instead", p.curToken.Type)
+			return false
+		}
+		lit.Parameters = append(lit.Parameters, ident)
+		if lit.Patterns != nil && len(lit.Patterns) < len(lit.Parameters) {
+			lit.Patterns = append(lit.Patterns, nil)
+		}
+
+		var def ast.Expression
+		if p.peekTokenIs(token.ASSIGN) {
+			p.nextToken()
+			p.nextToken()
+			def = p.parseExpression(ASSIGN)
+			if def == nil {
+				return false
+			}
+		} else if lit.Defaults != nil {
+			p.parameterError(ident.Token, "parameter %s without a default value cannot follow a parameter with one", ident.Value)
+			return false
+		}
+		if def != nil && lit.Defaults == nil {
+			lit.Defaults = make([]ast.Expression, len(lit.Parameters)-1, len(lit.Parameters))
+		}
+		if lit.Defaults != nil {
+			lit.Defaults = append(lit.Defaults, def)
+		}
+
+		if !p.peekTokenIs(token.COMMA) {
+			break
+		}
 		p.nextToken()
-		ident := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
-		identifiers = append(identifiers, ident)
 	}
 
-	if !p.expectPeek(token.RPAREN) {
-		return nil
+	return p.expectPeek(token.RPAREN)
+}
+
+// parameterError は tok の位置にパラメータリストの構文エラーを記録する
+func (p *Parser) parameterError(tok token.Token, format string, args ...any) {
+	msg := fmt.Sprintf("line %d, column %d: ", tok.Line, tok.Column) + fmt.Sprintf(format, args...)
+	p.errors = append(p.errors, msg)
+}
+
+// parseTemplateLiteral はテンプレート文字列をパース
+// ${} の中の式はテンプレート文字列の外と同じトークン列として読まれるので、位置は元のソースの位置になる
+func (p *Parser) parseTemplateLiteral() ast.Expression {
+	lit := &ast.TemplateLiteral{Token: p.curToken, Strings: []string{p.curToken.Literal}}
+
+	for p.curTokenIs(token.TEMPLATE_HEAD) || p.curTokenIs(token.TEMPLATE_MIDDLE) {
+		p.nextToken()
+		if p.curTokenIs(token.TEMPLATE_MIDDLE) || p.curTokenIs(token.TEMPLATE_TAIL) {
+			msg := fmt.Sprintf("line %d, column %d: empty expression in template string",
+				p.curToken.Line, p.curToken.Column)
+			p.errors = append(p.errors, msg)
+			return nil
+		}
+		exp := p.parseExpression(LOWEST)
+		if exp == nil {
+			return nil
+		}
+		lit.Expressions = append(lit.Expressions, exp)
+
+		if !p.peekTokenIs(token.TEMPLATE_MIDDLE) && !p.peekTokenIs(token.TEMPLATE_TAIL) {
+			msg := fmt.Sprintf("line %d, column %d: expected } after template expression, got %s instead",
+				p.peekToken.Line, p.peekToken.Column, p.peekToken.Type)
+			p.errors = append(p.errors, msg)
+			return nil
+		}
+		p.nextToken()
+		lit.Strings = append(lit.Strings, p.curToken.Literal)
 	}
 
-	return identifiers
+	return lit
 }
 
 // parseArrayLiteral は配列リテラルをパース
@@ -921,7 +1201,6 @@ func (p *Parser) parseSliceExpressionRest(bracketToken token.Token, left ast.Exp
 // parseMapLiteral はマップリテラルをパース
 func (p *Parser) parseMapLiteral() ast.Expression {
 	mapLit := &ast.MapLiteral{Token: p.curToken}
-	mapLit.Pairs = make(map[ast.Expression]ast.Expression)
 
 	for !p.peekTokenIs(token.RBRACE) {
 		p.nextToken()
@@ -934,7 +1213,7 @@ func (p *Parser) parseMapLiteral() ast.Expression {
 		p.nextToken()
 		value := p.parseExpression(LOWEST)
 
-		mapLit.Pairs[key] = value
+		mapLit.Pairs = append(mapLit.Pairs, ast.MapPair{Key: key, Value: value})
 
 		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
 			return nil
@@ -948,7 +1227,7 @@ func (p *Parser) parseMapLiteral() ast.Expression {
 	return mapLit
 }
 
-// parseTryStatement はtry/catch文をパース
+// parseTryStatement はtry/catch/finally文をパース
 func (p *Parser) parseTryStatement() *ast.TryStatement {
 	stmt := &ast.TryStatement{Token: p.curToken}
 
@@ -959,32 +1238,50 @@ func (p *Parser) parseTryStatement() *ast.TryStatement {
 
 	stmt.TryBlock = p.parseBlockStatement()
 
-	// catch キーワード
-	if !p.expectPeek(token.CATCH) {
+	// catch と finally の少なくとも一方が必要
+	if !p.peekTokenIs(token.CATCH) && !p.peekTokenIs(token.FINALLY) {
+		msg := fmt.Sprintf("line %d, column %d: expected catch or finally after try block, got %s instead",
+			p.peekToken.Line, p.peekToken.Column, p.peekToken.Type)
+		p.errors = append(p.errors, msg)
 		return nil
 	}
 
-	// catch パラメータ
-	if !p.expectPeek(token.LPAREN) {
-		return nil
-	}
+	if p.peekTokenIs(token.CATCH) {
+		p.nextToken()
 
-	if !p.expectPeek(token.IDENT) {
-		return nil
-	}
+		// catch パラメータ
+		if !p.expectPeek(token.LPAREN) {
+			return nil
+		}
 
-	stmt.CatchParam = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
 
-	if !p.expectPeek(token.RPAREN) {
-		return nil
-	}
+		stmt.CatchParam = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
 
-	// catch ブロック
-	if !p.expectPeek(token.LBRACE) {
-		return nil
+		if !p.expectPeek(token.RPAREN) {
+			return nil
+		}
+
+		// catch ブロック
+		if !p.expectPeek(token.LBRACE) {
+			return nil
+		}
+
+		stmt.CatchBlock = p.parseBlockStatement()
 	}
 
-	stmt.CatchBlock = p.parseBlockStatement()
+	// finally ブロック
+	if p.peekTokenIs(token.FINALLY) {
+		p.nextToken()
+
+		if !p.expectPeek(token.LBRACE) {
+			return nil
+		}
+
+		stmt.FinallyBlock = p.parseBlockStatement()
+	}
 
 	return stmt
 }
@@ -1003,3 +1300,61 @@ func (p *Parser) parseThrowStatement() *ast.ThrowStatement {
 
 	return stmt
 }
+
+// parseImportStatement はimport文をパース（import "lib.sugu";）
+func (p *Parser) parseImportStatement() *ast.ImportStatement {
+	stmt := &ast.ImportStatement{Token: p.curToken}
+
+	if !p.expectPeek(token.STRING) {
+		return nil
+	}
+
+	stmt.Path = &ast.StringLiteral{Token: p.curToken, Value: p.curToken.Literal}
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+// parseExportStatement はexport文をパース（export const x = 1; / export func f() => { ... }）
+func (p *Parser) parseExportStatement() *ast.ExportStatement {
+	stmt := &ast.ExportStatement{Token: p.curToken}
+
+	switch p.peekToken.Type {
+	case token.MUT, token.CONST:
+		p.nextToken()
+		varStmt := p.parseVariableStatement()
+		if varStmt == nil {
+			return nil
+		}
+		if varStmt.Pattern != nil {
+			msg := fmt.Sprintf("line %d, column %d: export requires a variable name, got a destructuring pattern",
+				varStmt.Token.Line, varStmt.Token.Column)
+			p.errors = append(p.errors, msg)
+			return nil
+		}
+		stmt.Name = varStmt.Name
+		stmt.Statement = varStmt
+	case token.FUNC:
+		p.nextToken()
+		exprStmt := p.parseExpressionStatement()
+		fn, ok := exprStmt.Expression.(*ast.FunctionLiteral)
+		if !ok || fn.Name == nil {
+			msg := fmt.Sprintf("line %d, column %d: export requires a named function",
+				stmt.Token.Line, stmt.Token.Column)
+			p.errors = append(p.errors, msg)
+			return nil
+		}
+		stmt.Name = fn.Name
+		stmt.Statement = exprStmt
+	default:
+		msg := fmt.Sprintf("line %d, column %d: expected mut, const or func after export, got %s instead",
+			p.peekToken.Line, p.peekToken.Column, p.peekToken.Type)
+		p.errors = append(p.errors, msg)
+		return nil
+	}
+
+	return stmt
+}
@@ -1038,6 +1038,357 @@ func TestParserErrorsWithPosition(t *testing.T) {
 			"mut x = 10;\n@",
 			"line 2, column 1: no prefix parse function for ILLEGAL found",
 		},
+		{
+			"try { x; }",
+			"line 1, column 11: expected catch or finally after try block, got EOF instead",
+		},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) == 0 {
+			t.Errorf("expected parser errors for input %q", tt.input)
+			continue
+		}
+
+		if errors[0] != tt.expected {
+			t.Errorf("wrong error message.\nexpected=%q\ngot=%q", tt.expected, errors[0])
+		}
+	}
+}
+
+func TestTryStatementParsing(t *testing.T) {
+	tests := []struct {
+		input      string
+		hasCatch   bool
+		hasFinally bool
+		expected   string
+	}{
+		{"try { x; } catch (e) { e; }", true, false, "try { x } catch (e) { e }"},
+		{"try { x; } finally { y; }", false, true, "try { x } finally { y }"},
+		{"try { x; } catch (e) { e; } finally { y; }", true, true, "try { x } catch (e) { e } finally { y }"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("program.Statements does not contain 1 statement. got=%d", len(program.Statements))
+		}
+
+		stmt, ok := program.Statements[0].(*ast.TryStatement)
+		if !ok {
+			t.Fatalf("stmt is not *ast.TryStatement. got=%T", program.Statements[0])
+		}
+
+		if (stmt.CatchBlock != nil) != tt.hasCatch {
+			t.Errorf("%q: catch block presence wrong. got=%v", tt.input, stmt.CatchBlock != nil)
+		}
+		if (stmt.FinallyBlock != nil) != tt.hasFinally {
+			t.Errorf("%q: finally block presence wrong. got=%v", tt.input, stmt.FinallyBlock != nil)
+		}
+		if stmt.String() != tt.expected {
+			t.Errorf("%q: String() wrong. expected=%q, got=%q", tt.input, tt.expected, stmt.String())
+		}
+	}
+}
+
+func TestImportStatementParsing(t *testing.T) {
+	input := `import "lib/math.sugu";`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Statements does not contain 1 statement. got=%d", len(program.Statements))
+	}
+
+	stmt, ok := program.Statements[0].(*ast.ImportStatement)
+	if !ok {
+		t.Fatalf("stmt is not *ast.ImportStatement. got=%T", program.Statements[0])
+	}
+
+	if stmt.Path.Value != "lib/math.sugu" {
+		t.Errorf("stmt.Path.Value wrong. got=%q", stmt.Path.Value)
+	}
+}
+
+func TestExportStatementParsing(t *testing.T) {
+	tests := []struct {
+		input        string
+		expectedName string
+	}{
+		{"export const PI = 3.14;", "PI"},
+		{"export mut count = 0;", "count"},
+		{"export func add(a, b) => { return a + b; }", "add"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("input %q: program.Statements does not contain 1 statement. got=%d",
+				tt.input, len(program.Statements))
+		}
+
+		stmt, ok := program.Statements[0].(*ast.ExportStatement)
+		if !ok {
+			t.Fatalf("input %q: stmt is not *ast.ExportStatement. got=%T", tt.input, program.Statements[0])
+		}
+
+		if stmt.Name.Value != tt.expectedName {
+			t.Errorf("input %q: stmt.Name.Value wrong. expected=%q, got=%q",
+				tt.input, tt.expectedName, stmt.Name.Value)
+		}
+	}
+}
+
+func TestModuleStatementErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{
+			"import lib;",
+			"line 1, column 8: expected next token to be STRING, got IDENT instead",
+		},
+		{
+			"export 1;",
+			"line 1, column 8: expected mut, const or func after export, got NUMBER instead",
+		},
+		{
+			"export func(x) => { return x; }",
+			"line 1, column 1: export requires a named function",
+		},
+		{
+			"func f() => {\n  import \"lib.sugu\";\n}",
+			"line 2, column 3: import is only allowed at the top level",
+		},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) == 0 {
+			t.Errorf("expected parser errors for input %q", tt.input)
+			continue
+		}
+
+		if errors[0] != tt.expected {
+			t.Errorf("wrong error message.\nexpected=%q\ngot=%q", tt.expected, errors[0])
+		}
+	}
+}
+
+func TestFunctionParameterParsing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"func(x) => x * 2", "func(x) => { (x * 2) }"},
+		{"func(a, b = 10) => { a + b }", "func(a, b = 10) => { (a + b) }"},
+		{"func(a = 1, b = a + 1) => b", "func(a = 1, b = (a + 1)) => { b }"},
+		{"func f(first, ...rest) => rest", "func f(first, ...rest) => { rest }"},
+		{"func(a, b = [], ...rest) => a", "func(a, b = [], ...rest) => { a }"},
+		{"func(...all) => all", "func(...all) => { all }"},
+		// 式の本体は 1 つの式文のブロックになり、, や ) の前で終わる
+		{"map(xs, func(x) => x + 1, 2)", "map(xs, func(x) => { (x + 1) }, 2)"},
+		{"func(x) => func(y) => x + y", "func(x) => { func(y) => { (x + y) } }"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("input %q: wrong program. expected=%q, got=%q", tt.input, tt.expected, program.String())
+		}
+	}
+}
+
+func TestFunctionParameterErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{
+			"func(a = 1, b) => a",
+			"line 1, column 13: parameter b without a default value cannot follow a parameter with one",
+		},
+		{
+			"func(...rest, a) => a",
+			"line 1, column 13: rest parameter rest must be the last parameter",
+		},
+		{
+			"func(...rest = []) => rest",
+			"line 1, column 14: rest parameter rest cannot have a default value",
+		},
+		{
+			"func(1) => 1",
+			"line 1, column 6: expected parameter name, got NUMBER instead",
+		},
+		{
+			"func(...) => 1",
+			"line 1, column 9: expected next token to be IDENT, got ) instead",
+		},
+		{
+			"func(x) =>",
+			"line 1, column 11: no prefix parse function for EOF found",
+		},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) == 0 {
+			t.Errorf("expected parser errors for input %q", tt.input)
+			continue
+		}
+
+		if errors[0] != tt.expected {
+			t.Errorf("wrong error message.\nexpected=%q\ngot=%q", tt.expected, errors[0])
+		}
+	}
+}
+
+func TestDestructuringParsing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"const [a, b] = arr;", "const [a, b] = arr;"},
+		{"mut [first, ...rest] = arr;", "mut [first, ...rest] = arr;"},
+		{`const {"name": n, "age": a} = person;`, `const {"name": n, "age": a} = person;`},
+		{`const {1: one, true: t} = m;`, `const {1: one, true: t} = m;`},
+		{`const [x, {"pos": [y, z]}] = v;`, `const [x, {"pos": [y, z]}] = v;`},
+		{"const [] = arr;", "const [] = arr;"},
+		{"for ([a, b] in pairs) { a }", "for ([a, b] in pairs) { a }"},
+		{`for (k, {"x": x} in m) { x }`, `for (k, {"x": x} in m) { x }`},
+		{"for (i, [a] in arr) { a }", "for (i, [a] in arr) { a }"},
+		{`func f([a, b], {"k": v}) => a`, `func f([a, b], {"k": v}) => { a }`},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("input %q: wrong program. expected=%q, got=%q", tt.input, tt.expected, program.String())
+		}
+	}
+}
+
+func TestDestructuringParseErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{
+			"const [a, a] = arr;",
+			"line 1, column 11: duplicate variable a in pattern",
+		},
+		{
+			"const [...rest, a] = arr;",
+			"line 1, column 15: rest element rest must be the last element",
+		},
+		{
+			"const [1] = arr;",
+			"line 1, column 8: expected variable name or pattern, got NUMBER instead",
+		},
+		{
+			"const {name: n} = m;",
+			"line 1, column 8: map pattern key must be a STRING, NUMBER or BOOLEAN literal, got IDENT instead",
+		},
+		{
+			`const {"a" n} = m;`,
+			"line 1, column 12: expected next token to be :, got IDENT instead",
+		},
+		{
+			"const [a b] = arr;",
+			"line 1, column 10: expected next token to be ,, got IDENT instead",
+		},
+		{
+			"export const [a] = arr;",
+			"line 1, column 8: export requires a variable name, got a destructuring pattern",
+		},
+		{
+			"for ([a] of arr) { }",
+			"line 1, column 10: expected 'in' in for-in statement, got of",
+		},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) == 0 {
+			t.Errorf("expected parser errors for input %q", tt.input)
+			continue
+		}
+
+		if errors[0] != tt.expected {
+			t.Errorf("wrong error message.\nexpected=%q\ngot=%q", tt.expected, errors[0])
+		}
+	}
+}
+
+func TestTemplateLiteralParsing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"`plain`", "`plain`"},
+		{"`a${x}b`", "`a${x}b`"},
+		{"`${a + b * c}${f(1)}`", "`${(a + (b * c))}${f(1)}`"},
+		{"`${ {\"k\": 1}[\"k\"] }`", "`${({\"k\": 1}[\"k\"])}`"},
+		{"`out${`in${x}`}`", "`out${`in${x}`}`"},
+		{"`a${x}` + \"b\"", "(`a${x}` + \"b\")"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("input %q: wrong program. expected=%q, got=%q", tt.input, tt.expected, program.String())
+		}
+	}
+}
+
+func TestTemplateLiteralErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"`a${}b`", "line 1, column 5: empty expression in template string"},
+		{"`a${x y}b`", "line 1, column 7: expected } after template expression, got IDENT instead"},
+		{"x;\n`a${\n  1 +\n}`", "line 4, column 1: no prefix parse function for TEMPLATE_TAIL found"},
 	}
 
 	for _, tt := range tests {
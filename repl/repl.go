@@ -2,8 +2,11 @@ package repl
 
 import (
 	"bufio"
+	"context"
 	"fmt"
 	"io"
+	"os"
+	"os/signal"
 	"sugu/evaluator"
 	"sugu/lexer"
 	"sugu/object"
@@ -14,13 +17,23 @@ const PROMPT = ">> "
 
 // Start はREPLを開始する
 func Start(in io.Reader, out io.Writer) {
+	StartWithLimits(in, out, evaluator.Limits{})
+}
+
+// StartWithLimits は 1 行ごとの評価に limits の上限を設定してREPLを開始する
+// 評価中に Ctrl-C を押すと、REPL を終了せずにその評価だけを中断する
+func StartWithLimits(in io.Reader, out io.Writer, limits evaluator.Limits) {
 	scanner := bufio.NewScanner(in)
 	env := object.NewEnvironment()
+	ev := evaluator.New("")
+	ev.SetLimits(limits)
+	ev.SetStdout(out)
 
 	fmt.Fprintln(out, "Sugu Language REPL")
 	fmt.Fprintln(out, "Type 'exit' or 'quit' to exit")
 	fmt.Fprintln(out)
 
+	entries := 0
 	for {
 		fmt.Fprint(out, PROMPT)
 		scanned := scanner.Scan()
@@ -41,6 +54,7 @@ func Start(in io.Reader, out io.Writer) {
 			continue
 		}
 
+		entries++
 		l := lexer.New(line)
 		p := parser.New(l)
 
@@ -50,10 +64,14 @@ func Start(in io.Reader, out io.Writer) {
 			continue
 		}
 
-		evaluated := evaluator.Eval(program, env)
+		// 入力ごとに別の名前で登録し、前の入力で定義した関数のエラーもその入力の位置と抜粋で表示する
+		ev.SetNamedSource(fmt.Sprintf("<repl:%d>", entries), line)
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		evaluated := ev.EvalContext(ctx, program, env)
+		stop()
 		if evaluated != nil {
 			if errObj, ok := evaluated.(*object.Error); ok {
-				fmt.Fprintf(out, "Error: %s\n", errObj.Message)
+				printError(out, errObj)
 			} else if evaluated.Type() != object.NULL_OBJ {
 				fmt.Fprintln(out, evaluated.Inspect())
 			}
@@ -61,6 +79,17 @@ func Start(in io.Reader, out io.Writer) {
 	}
 }
 
+// printError は実行時エラーをソースコードの抜粋と呼び出し履歴とともに表示する
+func printError(out io.Writer, errObj *object.Error) {
+	fmt.Fprintf(out, "Error: %s\n", errObj.Message)
+	if excerpt := errObj.Excerpt(); excerpt != "" {
+		fmt.Fprintln(out, excerpt)
+	}
+	if trace := errObj.StackTrace(); trace != "" {
+		fmt.Fprintln(out, trace)
+	}
+}
+
 // printParserErrors はパーサーエラーを表示する
 func printParserErrors(out io.Writer, errors []string) {
 	fmt.Fprintln(out, "Parser errors:")
@@ -2,8 +2,13 @@ package repl
 
 import (
 	"bytes"
+	"os"
+	"path/filepath"
 	"strings"
+	"sugu/evaluator"
+	"sugu/sugu"
 	"testing"
+	"time"
 )
 
 func TestREPLBasicExpression(t *testing.T) {
@@ -77,6 +82,36 @@ func TestREPLRuntimeError(t *testing.T) {
 	}
 }
 
+func TestREPLErrorExcerpt(t *testing.T) {
+	in := strings.NewReader("const x = 1;\nx + missing\nexit\n")
+	out := &bytes.Buffer{}
+
+	Start(in, out)
+
+	expected := "Error: <repl:2>, line 1, column 5: identifier not found: missing\n" +
+		"  x + missing\n" +
+		"      ^\n"
+	if !strings.Contains(out.String(), expected) {
+		t.Errorf("expected output to contain %q, got=%q", expected, out.String())
+	}
+}
+
+func TestREPLErrorInEarlierEntry(t *testing.T) {
+	// 前の入力で定義した関数のエラーは、その入力の位置と抜粋で表示する
+	in := strings.NewReader("const f = func() => 1/0;\nf()\nexit\n")
+	out := &bytes.Buffer{}
+
+	Start(in, out)
+
+	expected := "Error: <repl:1>, line 1, column 22: division by zero\n" +
+		"  const f = func() => 1/0;\n" +
+		"                       ^\n" +
+		"  at <anonymous> (<repl:2>, line 1, column 2)\n"
+	if !strings.Contains(out.String(), expected) {
+		t.Errorf("expected output to contain %q, got=%q", expected, out.String())
+	}
+}
+
 func TestREPLQuit(t *testing.T) {
 	input := "quit\n"
 	in := strings.NewReader(input)
@@ -160,3 +195,156 @@ func TestRunSource(t *testing.T) {
 		})
 	}
 }
+
+func TestRunFileWithImport(t *testing.T) {
+	dir := t.TempDir()
+	libPath := filepath.Join(dir, "lib", "greet.sugu")
+	mainPath := filepath.Join(dir, "main.sugu")
+	if err := os.MkdirAll(filepath.Dir(libPath), 0755); err != nil {
+		t.Fatalf("failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(libPath, []byte(`export func greet(name) => { return "Hello, " + name; }`), 0644); err != nil {
+		t.Fatalf("failed to write module: %v", err)
+	}
+	if err := os.WriteFile(mainPath, []byte(`import "lib/greet.sugu"; greet("Sugu");`), 0644); err != nil {
+		t.Fatalf("failed to write main: %v", err)
+	}
+
+	out := &bytes.Buffer{}
+	if err := RunFile(mainPath, out); err != nil {
+		t.Errorf("unexpected error: %v (output=%q)", err, out.String())
+	}
+}
+
+func TestRunFileWithOptions(t *testing.T) {
+	dir := t.TempDir()
+	libPath := filepath.Join(dir, "lib.sugu")
+	mainPath := filepath.Join(dir, "main.sugu")
+	if err := os.WriteFile(libPath, []byte(`export func square(x) => { return x * x; }`), 0644); err != nil {
+		t.Fatalf("failed to write module: %v", err)
+	}
+	if err := os.WriteFile(mainPath, []byte("import \"lib.sugu\";\nmut total = 0;\nfor (x in [1, 2, 3]) { total += square(x); }\nundefinedName;"), 0644); err != nil {
+		t.Fatalf("failed to write main: %v", err)
+	}
+
+	for _, engine := range []sugu.Engine{sugu.EngineEval, sugu.EngineVM} {
+		t.Run(string(engine), func(t *testing.T) {
+			out := &bytes.Buffer{}
+			err := RunFileWithOptions(mainPath, Options{Engine: engine}, out)
+			if err == nil {
+				t.Fatalf("expected error, got nil")
+			}
+			expected := "Error: main.sugu, line 4, column 1: identifier not found: undefinedName\n" +
+				"  undefinedName;\n" +
+				"  ^\n"
+			if out.String() != expected {
+				t.Errorf("wrong output. expected=%q, got=%q", expected, out.String())
+			}
+		})
+	}
+
+	if err := RunFileWithOptions(mainPath, Options{Engine: sugu.Engine("jit")}, &bytes.Buffer{}); err == nil {
+		t.Errorf("expected error for unknown engine, got nil")
+	}
+}
+
+func TestRunFileWithLimits(t *testing.T) {
+	dir := t.TempDir()
+	mainPath := filepath.Join(dir, "main.sugu")
+	if err := os.WriteFile(mainPath, []byte("while (true) {}"), 0644); err != nil {
+		t.Fatalf("failed to write main: %v", err)
+	}
+
+	for _, engine := range []sugu.Engine{sugu.EngineEval, sugu.EngineVM} {
+		t.Run(string(engine), func(t *testing.T) {
+			out := &bytes.Buffer{}
+			opts := Options{Engine: engine, Limits: evaluator.Limits{MaxSteps: 1000}}
+			if err := RunFileWithOptions(mainPath, opts, out); err == nil {
+				t.Fatalf("expected error, got nil")
+			}
+			expected := "Error: execution limit exceeded: maximum steps (1000)\n"
+			if out.String() != expected {
+				t.Errorf("wrong output. expected=%q, got=%q", expected, out.String())
+			}
+		})
+	}
+}
+
+func TestREPLLimits(t *testing.T) {
+	in := strings.NewReader("while (true) {}\n1 + 2\nexit\n")
+	out := &bytes.Buffer{}
+
+	StartWithLimits(in, out, evaluator.Limits{Timeout: 20 * time.Millisecond})
+
+	output := out.String()
+	if !strings.Contains(output, "Error: execution limit exceeded: context deadline exceeded") {
+		t.Errorf("expected limit error, got=%q", output)
+	}
+	// 中断した後も REPL は続く
+	if !strings.Contains(output, "3") {
+		t.Errorf("expected output to contain '3', got=%q", output)
+	}
+}
+
+func TestRunSourceStackTrace(t *testing.T) {
+	source := "func inner() => {\n  return missing;\n}\nfunc outer() => {\n  return inner();\n}\nouter();"
+	out := &bytes.Buffer{}
+	if err := RunSource(source, out); err == nil {
+		t.Fatalf("expected error, got nil")
+	}
+
+	expected := "Error: line 2, column 10: identifier not found: missing\n" +
+		"    return missing;\n" +
+		"           ^\n" +
+		"  at inner (line 5, column 15)\n" +
+		"  at outer (line 7, column 6)\n"
+	if out.String() != expected {
+		t.Errorf("wrong output.\nexpected=%q\ngot=%q", expected, out.String())
+	}
+}
+
+func TestRunFileErrorExcerpt(t *testing.T) {
+	dir := t.TempDir()
+	libPath := filepath.Join(dir, "lib.sugu")
+	mainPath := filepath.Join(dir, "main.sugu")
+	if err := os.WriteFile(libPath, []byte("export func half(n) => {\n\treturn n / \"2\";\n}\n"), 0644); err != nil {
+		t.Fatalf("failed to write module: %v", err)
+	}
+
+	tests := []struct {
+		source   string
+		expected string
+	}{
+		// 組み込み関数のエラーは呼び出した位置を指す
+		{
+			"const items = [1, 2];\noutln(len(items, 1));\n",
+			"Error: main.sugu, line 2, column 10: wrong number of arguments. got=2, want=1\n" +
+				"  outln(len(items, 1));\n" +
+				"           ^\n",
+		},
+		// import したモジュールのエラーはモジュールのソースコードを表示する（タブは揃えたまま残す）
+		{
+			"import \"lib.sugu\";\nhalf(4);\n",
+			"Error: lib.sugu, line 2, column 11: type mismatch: NUMBER / STRING\n" +
+				"  \treturn n / \"2\";\n" +
+				"  \t         ^\n" +
+				"  at half (main.sugu, line 2, column 5)\n",
+		},
+	}
+
+	for _, tt := range tests {
+		if err := os.WriteFile(mainPath, []byte(tt.source), 0644); err != nil {
+			t.Fatalf("failed to write main: %v", err)
+		}
+		for _, engine := range []sugu.Engine{sugu.EngineEval, sugu.EngineVM} {
+			out := &bytes.Buffer{}
+			if err := RunFileWithOptions(mainPath, Options{Engine: engine}, out); err == nil {
+				t.Errorf("%s: expected error for %q, got nil", engine, tt.source)
+				continue
+			}
+			if out.String() != tt.expected {
+				t.Errorf("%s: wrong output for %q.\nexpected=%q\ngot=%q", engine, tt.source, tt.expected, out.String())
+			}
+		}
+	}
+}
@@ -1,47 +1,70 @@
 package repl
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
+	"strings"
 	"sugu/evaluator"
-	"sugu/lexer"
 	"sugu/object"
-	"sugu/parser"
+	"sugu/sugu"
 )
 
+// Options はファイルの実行方法の設定
+type Options struct {
+	Engine      sugu.Engine      // 実行エンジン（空文字列なら sugu.EngineEval）
+	Limits      evaluator.Limits // 実行の上限（ステップ数・実行時間）
+	StrictArity bool             // 関数の引数の数が合わない呼び出しをエラーにする
+}
+
 // RunFile はファイルを読み込んで実行する
 func RunFile(filename string, out io.Writer) error {
+	return RunFileWithOptions(filename, Options{}, out)
+}
+
+// RunFileWithOptions は opts の設定でファイルを実行する
+// out() と outln() の出力とエラーメッセージは out に書き込まれる
+func RunFileWithOptions(filename string, opts Options, out io.Writer) error {
 	content, err := os.ReadFile(filename)
 	if err != nil {
 		return fmt.Errorf("failed to read file: %w", err)
 	}
 
-	return RunSource(string(content), out)
+	return runSource(filename, string(content), opts, out)
 }
 
 // RunSource はソースコードを実行する
 func RunSource(source string, out io.Writer) error {
-	l := lexer.New(source)
-	p := parser.New(l)
-
-	program := p.ParseProgram()
-	if len(p.Errors()) != 0 {
-		fmt.Fprintln(out, "Parser errors:")
-		for _, msg := range p.Errors() {
-			fmt.Fprintf(out, "  %s\n", msg)
-		}
-		return fmt.Errorf("parse error")
-	}
+	return runSource("", source, Options{}, out)
+}
 
-	env := object.NewEnvironment()
-	result := evaluator.Eval(program, env)
+// runSource は filename のソースコードとして実行する（import の相対パスは filename 基準で解決される）
+func runSource(filename, source string, opts Options, out io.Writer) error {
+	interp, err := sugu.New(sugu.Options{
+		Filename:    filename,
+		Engine:      opts.Engine,
+		Limits:      opts.Limits,
+		StrictArity: opts.StrictArity,
+		Stdout:      out,
+	})
+	if err != nil {
+		return err
+	}
 
-	if result != nil {
-		if errObj, ok := result.(*object.Error); ok {
-			fmt.Fprintf(out, "Error: %s\n", errObj.Message)
-			return fmt.Errorf("runtime error: %s", errObj.Message)
+	if _, err := interp.Eval(context.Background(), source); err != nil {
+		var errObj *object.Error
+		if !errors.As(err, &errObj) {
+			return err
+		}
+		// パーサーのエラーは位置を持たず、メッセージを改行でつないだものになる
+		if errObj.Kind == object.SYNTAX_ERROR && errObj.Line == 0 {
+			printParserErrors(out, strings.Split(errObj.Message, "\n"))
+			return fmt.Errorf("parse error")
 		}
+		printError(out, errObj)
+		return fmt.Errorf("runtime error: %s", errObj.Message)
 	}
 
 	return nil
@@ -21,7 +21,7 @@ try {
     $env:GOARCH = "amd64"
     $env:CGO_ENABLED = "0"
 
-    go build -o "$OutputDir/bootstrap" ./lambda
+    go build -o "$OutputDir/bootstrap" ./lambda/bootstrap
 
     Write-Host "Created: $OutputDir/bootstrap"
 
@@ -16,7 +16,7 @@ mkdir -p "$OUTPUT_DIR"
 
 # Linux x86_64 向けにビルド
 cd "$PROJECT_ROOT"
-GOOS=linux GOARCH=amd64 CGO_ENABLED=0 go build -o "$OUTPUT_DIR/bootstrap" ./lambda
+GOOS=linux GOARCH=amd64 CGO_ENABLED=0 go build -o "$OUTPUT_DIR/bootstrap" ./lambda/bootstrap
 
 echo "Created: $OUTPUT_DIR/bootstrap"
 
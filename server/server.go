@@ -0,0 +1,236 @@
+// Package server は Sugu のスクリプトを HTTP のハンドラーとして実行する（sugu serve）
+//
+// リクエストごとにスクリプトを読み込み直して新しい Interpreter で実行するため、
+// サーバーを起動したままスクリプトを編集できる
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sugu/convert"
+	"sugu/object"
+	"sugu/sugu"
+)
+
+// maxBodySize はリクエストボディの最大のバイト数
+const maxBodySize = 10 << 20
+
+// Handler はスクリプトを実行してレスポンスを返す http.Handler
+type Handler struct {
+	filename string
+	opts     sugu.Options
+}
+
+// NewHandler は filename のスクリプトを opts の設定で実行する Handler を作成する
+// opts.Limits はリクエストごとに適用される。opts.Stdin が nil の場合、in() は入力がないためエラーになる
+func NewHandler(filename string, opts sugu.Options) *Handler {
+	opts.Filename = filename
+	if opts.Stdin == nil {
+		opts.Stdin = strings.NewReader("")
+	}
+	return &Handler{filename: filename, opts: opts}
+}
+
+// ServeHTTP はリクエストを request 変数に設定してスクリプトを実行し、その値をレスポンスにする
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBodySize))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeError(w, http.StatusRequestEntityTooLarge, "request body too large")
+			return
+		}
+		writeError(w, http.StatusBadRequest, "failed to read request body: "+err.Error())
+		return
+	}
+
+	result, err := h.run(r.Context(), requestMap(r, body))
+	if err != nil {
+		var errObj *object.Error
+		if errors.As(err, &errObj) {
+			writeObjectError(w, errObj)
+			return
+		}
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeResult(w, result)
+}
+
+// run はスクリプトを新しい Interpreter で実行し、最後に評価した文の値を返す
+func (h *Handler) run(ctx context.Context, request *object.Map) (object.Object, error) {
+	code, err := os.ReadFile(h.filename)
+	if err != nil {
+		return nil, errors.New("failed to read " + h.filename + ": " + err.Error())
+	}
+	interp, err := sugu.New(h.opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := interp.SetGlobal("request", request); err != nil {
+		return nil, err
+	}
+	return interp.Eval(ctx, string(code))
+}
+
+// requestMap はリクエストを次のマップにする
+//
+//	"method"  メソッド
+//	"path"    パス
+//	"query"   クエリパラメータの名前と値のマップ（同じ名前が複数ある場合は最初の値）
+//	"headers" ヘッダー名（小文字）と値のマップ（同じ名前が複数ある場合は ", " でつなぐ）
+//	"body"    ボディの文字列
+//	"json"    ボディを JSON として読み込んだ値（JSON でなければ null）
+func requestMap(r *http.Request, body []byte) *object.Map {
+	query := &object.Map{}
+	values := r.URL.Query()
+	for _, name := range sortedKeys(values) {
+		query.Set(&object.String{Value: name}, &object.String{Value: values.Get(name)})
+	}
+
+	headers := &object.Map{}
+	for _, name := range sortedKeys(r.Header) {
+		headers.Set(&object.String{Value: strings.ToLower(name)}, &object.String{Value: strings.Join(r.Header[name], ", ")})
+	}
+
+	var parsed object.Object = object.NULL
+	if len(body) > 0 {
+		if obj, err := convert.FromJSON(body); err == nil {
+			parsed = obj
+		}
+	}
+
+	m := &object.Map{}
+	m.Set(&object.String{Value: "method"}, &object.String{Value: r.Method})
+	m.Set(&object.String{Value: "path"}, &object.String{Value: r.URL.Path})
+	m.Set(&object.String{Value: "query"}, query)
+	m.Set(&object.String{Value: "headers"}, headers)
+	m.Set(&object.String{Value: "body"}, &object.String{Value: string(body)})
+	m.Set(&object.String{Value: "json"}, parsed)
+	return m
+}
+
+// sortedKeys は url.Values や http.Header の名前を並べ替えて返す
+func sortedKeys(m map[string][]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// writeResult はスクリプトの値をレスポンスにする
+// {"status": ..., "headers": ..., "body": ...} の形のマップはそのとおりのレスポンスにし、
+// それ以外の値は Lambda と同じく JSON にしてステータス 200 で返す
+func writeResult(w http.ResponseWriter, result object.Object) {
+	if m, ok := responseMap(result); ok {
+		writeResponseMap(w, m)
+		return
+	}
+	data, err := convert.ToJSON(result, "")
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to convert result: "+err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
+
+// responseMap は result が数値の "status" を持ち、"status"・"headers"・"body" 以外のキーを持たないマップかどうかを返す
+func responseMap(result object.Object) (*object.Map, bool) {
+	m, ok := result.(*object.Map)
+	if !ok {
+		return nil, false
+	}
+	if pair, ok := m.Get(&object.String{Value: "status"}); !ok || pair.Value.Type() != object.NUMBER_OBJ {
+		return nil, false
+	}
+	for _, pair := range m.Pairs() {
+		key, ok := pair.Key.(*object.String)
+		if !ok || (key.Value != "status" && key.Value != "headers" && key.Value != "body") {
+			return nil, false
+		}
+	}
+	return m, true
+}
+
+// writeResponseMap は {"status", "headers", "body"} のマップのとおりにレスポンスを書き込む
+// body が文字列ならそのまま（既定の Content-Type は text/plain）、null なら空、それ以外は JSON にする
+func writeResponseMap(w http.ResponseWriter, m *object.Map) {
+	pair, _ := m.Get(&object.String{Value: "status"})
+	status := pair.Value.(*object.Number).Value
+	if status < 100 || status > 999 || status != math.Trunc(status) {
+		writeError(w, http.StatusInternalServerError, "invalid response status: "+pair.Value.Inspect())
+		return
+	}
+
+	header := http.Header{}
+	if pair, ok := m.Get(&object.String{Value: "headers"}); ok && pair.Value != object.NULL {
+		headers, ok := pair.Value.(*object.Map)
+		if !ok {
+			writeError(w, http.StatusInternalServerError, "response headers must be MAP, got "+string(pair.Value.Type()))
+			return
+		}
+		for _, h := range headers.Pairs() {
+			header.Set(h.Key.Inspect(), h.Value.Inspect())
+		}
+	}
+
+	var data []byte
+	if pair, ok := m.Get(&object.String{Value: "body"}); ok {
+		switch body := pair.Value.(type) {
+		case *object.String:
+			data = []byte(body.Value)
+			if header.Get("Content-Type") == "" {
+				header.Set("Content-Type", "text/plain; charset=utf-8")
+			}
+		case *object.Null:
+		default:
+			var err error
+			if data, err = convert.ToJSON(body, ""); err != nil {
+				writeError(w, http.StatusInternalServerError, "failed to convert response body: "+err.Error())
+				return
+			}
+			if header.Get("Content-Type") == "" {
+				header.Set("Content-Type", "application/json")
+			}
+		}
+	}
+
+	for name, values := range header {
+		w.Header()[name] = values
+	}
+	w.WriteHeader(int(status))
+	w.Write(data)
+}
+
+// writeObjectError は構文エラーと実行時エラーを Lambda と同じ {"error", "stack"} の JSON にしてステータス 500 で返す
+func writeObjectError(w http.ResponseWriter, errObj *object.Error) {
+	resp := map[string]string{"error": errObj.Message}
+	if trace := errObj.StackTrace(); trace != "" {
+		resp["stack"] = trace
+	}
+	writeJSON(w, http.StatusInternalServerError, resp)
+}
+
+// writeError は {"error": msg} の JSON をステータス status で返す
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"error": msg})
+}
+
+// writeJSON は v を JSON にしてステータス status で返す
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.Encode(v)
+}
@@ -0,0 +1,160 @@
+package server
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sugu/evaluator"
+	"sugu/sugu"
+	"testing"
+	"time"
+)
+
+// testServe は handler のスクリプトを書いたファイルを作り、リクエストを 1 つ処理したレスポンスを返す
+func testServe(t *testing.T, script string, opts sugu.Options, req *http.Request) *http.Response {
+	t.Helper()
+	filename := filepath.Join(t.TempDir(), "handler.sugu")
+	if err := os.WriteFile(filename, []byte(script), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	rec := httptest.NewRecorder()
+	NewHandler(filename, opts).ServeHTTP(rec, req)
+	return rec.Result()
+}
+
+func readBody(t *testing.T, resp *http.Response) string {
+	t.Helper()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestRequestMap(t *testing.T) {
+	tests := []struct {
+		script   string
+		expected string
+	}{
+		{`request["method"]`, `"POST"`},
+		{`request["path"]`, `"/hooks/github"`},
+		{`request["query"]`, `{"a":"1","b":"x"}`},
+		{`request["headers"]["x-event"]`, `"push, ping"`},
+		{`request["body"]`, `"{\"ref\": \"main\", \"commits\": [1, 2]}"`},
+		{`request["json"]["ref"] + ":" + string(len(request["json"]["commits"]))`, `"main:2"`},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest("POST", "/hooks/github?b=x&a=1&a=2", strings.NewReader(`{"ref": "main", "commits": [1, 2]}`))
+		req.Header.Add("X-Event", "push")
+		req.Header.Add("X-Event", "ping")
+		resp := testServe(t, tt.script, sugu.Options{}, req)
+		body := readBody(t, resp)
+		if resp.StatusCode != 200 || body != tt.expected {
+			t.Errorf("script %q: wrong response. expected=200 %s, got=%d %s", tt.script, tt.expected, resp.StatusCode, body)
+		}
+		if ct := resp.Header.Get("Content-Type"); ct != "application/json" {
+			t.Errorf("script %q: wrong Content-Type. got=%q", tt.script, ct)
+		}
+	}
+
+	// JSON でないボディの json は null
+	req := httptest.NewRequest("POST", "/", strings.NewReader("name=sugu"))
+	resp := testServe(t, `[request["body"], request["json"]]`, sugu.Options{}, req)
+	if body := readBody(t, resp); body != `["name=sugu",null]` {
+		t.Errorf("wrong response for form body. got=%s", body)
+	}
+}
+
+func TestResponseMap(t *testing.T) {
+	tests := []struct {
+		script      string
+		status      int
+		contentType string
+		header      string
+		body        string
+	}{
+		{`{"status": 201, "headers": {"Location": "/items/1"}, "body": {"id": 1}}`, 201, "application/json", "/items/1", `{"id":1}`},
+		{`{"status": 404, "body": "not found"}`, 404, "text/plain; charset=utf-8", "", "not found"},
+		{`{"status": 200, "headers": {"Content-Type": "text/html"}, "body": "<p>hi</p>"}`, 200, "text/html", "", "<p>hi</p>"},
+		{`{"status": 204}`, 204, "", "", ""},
+		// 文字列以外のヘッダーの値は文字列にする
+		{`{"status": 302, "headers": {"Location": 42}}`, 302, "", "42", ""},
+		// status 以外のキーを含むマップはそのまま JSON になる
+		{`{"status": "ok", "count": 1}`, 200, "application/json", "", `{"status":"ok","count":1}`},
+		{`{"status": 200, "data": 1}`, 200, "application/json", "", `{"status":200,"data":1}`},
+		// 不正なステータス
+		{`{"status": 42}`, 500, "application/json", "", `{"error":"invalid response status: 42"}` + "\n"},
+		{`{"status": 200, "headers": []}`, 500, "application/json", "", `{"error":"response headers must be MAP, got ARRAY"}` + "\n"},
+	}
+
+	for _, tt := range tests {
+		resp := testServe(t, tt.script, sugu.Options{}, httptest.NewRequest("GET", "/", nil))
+		body := readBody(t, resp)
+		if resp.StatusCode != tt.status {
+			t.Errorf("script %q: wrong status. expected=%d, got=%d", tt.script, tt.status, resp.StatusCode)
+		}
+		if ct := resp.Header.Get("Content-Type"); ct != tt.contentType {
+			t.Errorf("script %q: wrong Content-Type. expected=%q, got=%q", tt.script, tt.contentType, ct)
+		}
+		if loc := resp.Header.Get("Location"); loc != tt.header {
+			t.Errorf("script %q: wrong Location. expected=%q, got=%q", tt.script, tt.header, loc)
+		}
+		if body != tt.body {
+			t.Errorf("script %q: wrong body. expected=%q, got=%q", tt.script, tt.body, body)
+		}
+	}
+}
+
+func TestServeErrors(t *testing.T) {
+	tests := []struct {
+		script string
+		opts   sugu.Options
+		body   string
+	}{
+		{`missing`, sugu.Options{}, `{"error":"handler.sugu, line 1, column 1: identifier not found: missing"}`},
+		{"func fail() => {\n  missing\n}\nfail()", sugu.Options{}, `{"error":"handler.sugu, line 2, column 3: identifier not found: missing","stack":"  at fail (handler.sugu, line 4, column 5)"}`},
+		{`mut x = ;`, sugu.Options{Engine: sugu.EngineVM}, `{"error":"line 1, column 9: no prefix parse function for ; found"}`},
+		{`while (true) {}`, sugu.Options{Limits: evaluator.Limits{Timeout: 10 * time.Millisecond}}, `{"error":"execution limit exceeded: context deadline exceeded"}`},
+		{`func() => 1`, sugu.Options{}, `{"error":"failed to convert result: cannot convert FUNCTION to JSON"}`},
+	}
+
+	for _, tt := range tests {
+		resp := testServe(t, tt.script, tt.opts, httptest.NewRequest("GET", "/", nil))
+		body := strings.TrimSuffix(readBody(t, resp), "\n")
+		if resp.StatusCode != 500 || body != tt.body {
+			t.Errorf("script %q: wrong response. expected=500 %s, got=%d %s", tt.script, tt.body, resp.StatusCode, body)
+		}
+	}
+}
+
+func TestServeRereadsScript(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "handler.sugu")
+	handler := NewHandler(filename, sugu.Options{})
+
+	for _, script := range []string{`"first"`, `"second"`} {
+		if err := os.WriteFile(filename, []byte(script), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+		if body := rec.Body.String(); body != script {
+			t.Errorf("wrong response after editing script. expected=%s, got=%s", script, body)
+		}
+	}
+
+	// グローバル変数はリクエストをまたいで残らない
+	if err := os.WriteFile(filename, []byte(`mut n = 0; n += 1; n`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for range 2 {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+		if body := rec.Body.String(); body != "1" {
+			t.Errorf("state leaked between requests. got=%s", body)
+		}
+	}
+}
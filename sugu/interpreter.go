@@ -0,0 +1,177 @@
+// Package sugu は Go のアプリケーションに Sugu を組み込むための API を提供する
+//
+//	interp, _ := sugu.New(sugu.Options{Stdout: &buf})
+//	interp.SetGlobal("name", "Sugu")
+//	result, err := interp.Eval(ctx, `"Hello, " + name`)
+//
+// Interpreter はそれぞれ独立したグローバル変数・組み込み関数・入出力を持つ
+package sugu
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sugu/convert"
+	"sugu/evaluator"
+	"sugu/lexer"
+	"sugu/object"
+	"sugu/parser"
+	"sugu/vm"
+	"time"
+)
+
+// Engine はプログラムの実行に使うバックエンド
+type Engine string
+
+const (
+	EngineEval Engine = "eval" // 木構造評価器（既定）
+	EngineVM   Engine = "vm"   // バイトコードコンパイラとスタック VM
+)
+
+// Options は Interpreter の設定
+type Options struct {
+	Filename    string           // import の相対パスとエラー位置の基準になるファイル名（空ならカレントディレクトリ基準）
+	Engine      Engine           // 実行エンジン（空文字列なら EngineEval）
+	Limits      evaluator.Limits // Eval と Call のたびに適用される実行の上限
+	StrictArity bool             // 関数の引数の数が合わない呼び出しをエラーにする
+	Stdin       io.Reader        // in() の入力（nil なら os.Stdin）
+	Stdout      io.Writer        // out() と outln() の出力先（nil なら os.Stdout）
+	Stderr      io.Writer        // errout() と erroutln() の出力先（nil なら os.Stderr）
+	Clock       func() time.Time // now() が返す現在時刻（nil なら time.Now）
+
+	HTTPTransport  http.RoundTripper // httpRequest などの通信に使う（nil なら http.DefaultTransport）
+	DisableNetwork bool              // httpRequest などの通信を禁止する（HTTPTransport より優先）
+}
+
+// Interpreter は Sugu のコードを実行するインタプリタ
+// グローバル変数は Eval をまたいで保持される。並行して使うことはできない
+type Interpreter struct {
+	ev  *evaluator.Evaluator
+	vm  *vm.VM // EngineVM の場合だけ使う
+	env *object.Environment
+
+	evals int // Eval を呼び出した回数（2 回目以降のソースコードの名前に使う）
+}
+
+// New は opts の設定で新しい Interpreter を作成する
+func New(opts Options) (*Interpreter, error) {
+	ev := evaluator.New(opts.Filename)
+	ev.SetLimits(opts.Limits)
+	ev.SetStrictArity(opts.StrictArity)
+	if opts.Stdin != nil {
+		ev.SetStdin(opts.Stdin)
+	}
+	if opts.Stdout != nil {
+		ev.SetStdout(opts.Stdout)
+	}
+	if opts.Stderr != nil {
+		ev.SetStderr(opts.Stderr)
+	}
+	if opts.Clock != nil {
+		ev.SetClock(opts.Clock)
+	}
+	switch {
+	case opts.DisableNetwork:
+		ev.SetHTTPTransport(nil)
+	case opts.HTTPTransport != nil:
+		ev.SetHTTPTransport(opts.HTTPTransport)
+	}
+
+	interp := &Interpreter{ev: ev, env: object.NewEnvironment()}
+	switch opts.Engine {
+	case EngineEval, "":
+	case EngineVM:
+		interp.vm = vm.New(ev)
+	default:
+		return nil, fmt.Errorf("unknown engine: %s", opts.Engine)
+	}
+	return interp, nil
+}
+
+// Eval は source を実行し、最後に評価した文の値を返す
+// 構文エラーと実行時エラーは *object.Error として返す（構文エラーの Kind は object.SYNTAX_ERROR）
+// ctx がキャンセルされると実行を中断し、"execution limit exceeded" のエラーを返す
+// 最初の Eval のエラー位置は Options.Filename、2 回目以降は "<eval:2>" のように何回目の Eval かで表示する
+func (i *Interpreter) Eval(ctx context.Context, source string) (object.Object, error) {
+	i.evals++
+	p := parser.New(lexer.New(source))
+	program := p.ParseProgram()
+	if errs := p.Errors(); len(errs) != 0 {
+		return nil, &object.Error{Message: strings.Join(errs, "\n"), Kind: object.SYNTAX_ERROR}
+	}
+
+	if i.evals == 1 {
+		i.ev.SetSource(source)
+	} else {
+		i.ev.SetNamedSource(fmt.Sprintf("<eval:%d>", i.evals), source)
+	}
+	var result object.Object
+	if i.vm != nil {
+		result = i.vm.EvalContext(ctx, program, i.env)
+	} else {
+		result = i.ev.EvalContext(ctx, program, i.env)
+	}
+	return unwrapError(result)
+}
+
+// Call は Sugu の関数（または組み込み関数）fn を args を引数にして呼び出し、戻り値を返す
+// 引数の Go の値は convert.ToObject で Sugu の値に変換する
+// catch されなかった throw と実行時エラーは *object.Error として返す
+func (i *Interpreter) Call(ctx context.Context, fn object.Object, args ...any) (object.Object, error) {
+	objs := make([]object.Object, len(args))
+	for n, arg := range args {
+		obj, err := convert.ToObject(arg)
+		if err != nil {
+			return nil, fmt.Errorf("argument %d: %w", n+1, err)
+		}
+		objs[n] = obj
+	}
+
+	end := i.ev.Begin(ctx)
+	defer end()
+	return unwrapError(i.ev.Call(fn, objs))
+}
+
+// SetGlobal はグローバル変数を設定する（同じ名前の変数があれば上書きする）
+// Go の値は convert.ToObject で Sugu の値に変換する。変換できない値はエラーになる
+func (i *Interpreter) SetGlobal(name string, value any) error {
+	obj, err := convert.ToObject(value)
+	if err != nil {
+		return fmt.Errorf("cannot set %s: %w", name, err)
+	}
+	i.env.Set(name, obj)
+	return nil
+}
+
+// GetGlobal はグローバル変数の値を返す
+// Go の値が必要な場合は convert.FromObject で変換する
+func (i *Interpreter) GetGlobal(name string) (object.Object, bool) {
+	return i.env.Get(name)
+}
+
+// Register は Go の関数を組み込み関数として登録する
+// fn は object.BuiltinFunction か、convert.ToObject で変換できる任意の関数
+// （引数は Sugu の値から変換され、戻り値の error は Sugu の実行時エラーになる）
+// 同じ名前の組み込み関数がある場合は置き換える。登録はこの Interpreter だけに影響する
+func (i *Interpreter) Register(name string, fn any) error {
+	obj, err := convert.ToObject(fn)
+	if err != nil {
+		return fmt.Errorf("cannot register %s: %w", name, err)
+	}
+	builtin, ok := obj.(*object.Builtin)
+	if !ok {
+		return fmt.Errorf("cannot register %s: %T is not a function", name, fn)
+	}
+	i.ev.DefineBuiltin(name, builtin)
+	return nil
+}
+
+// unwrapError は評価結果のエラーを Go の error として取り出す
+func unwrapError(result object.Object) (object.Object, error) {
+	if errObj, ok := result.(*object.Error); ok {
+		return nil, errObj
+	}
+	return result, nil
+}
@@ -0,0 +1,395 @@
+package sugu
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sugu/evaluator"
+	"sugu/object"
+	"testing"
+	"time"
+)
+
+var engines = []Engine{EngineEval, EngineVM}
+
+func newInterpreter(t *testing.T, opts Options) *Interpreter {
+	t.Helper()
+	interp, err := New(opts)
+	if err != nil {
+		t.Fatalf("New failed: %s", err)
+	}
+	return interp
+}
+
+func TestEval(t *testing.T) {
+	tests := []struct {
+		source   string
+		expected string
+	}{
+		{"1 + 2", "3"},
+		{`"Hello, " + "Sugu"`, "Hello, Sugu"},
+		{"func double(x) => { x * 2 }; double(21)", "42"},
+		{"push([1, 2], 3)", "[1, 2, 3]"},
+	}
+
+	for _, engine := range engines {
+		for _, tt := range tests {
+			interp := newInterpreter(t, Options{Engine: engine})
+			result, err := interp.Eval(context.Background(), tt.source)
+			if err != nil {
+				t.Errorf("%s: %q failed: %s", engine, tt.source, err)
+				continue
+			}
+			if result.Inspect() != tt.expected {
+				t.Errorf("%s: wrong result for %q. expected=%q, got=%q", engine, tt.source, tt.expected, result.Inspect())
+			}
+		}
+	}
+}
+
+func TestEvalErrorSource(t *testing.T) {
+	for _, engine := range engines {
+		interp := newInterpreter(t, Options{Engine: engine})
+		if _, err := interp.Eval(context.Background(), "func half(n) => {\n  return n / 0;\n}"); err != nil {
+			t.Fatalf("%s: Eval failed: %s", engine, err)
+		}
+
+		// 前の Eval で定義した関数のエラーは、その Eval の位置とソースコードになる
+		_, err := interp.Eval(context.Background(), "half(1)")
+		var errObj *object.Error
+		if !errors.As(err, &errObj) {
+			t.Fatalf("%s: expected *object.Error. got=%T(%v)", engine, err, err)
+		}
+		if err.Error() != "line 2, column 12: division by zero" {
+			t.Errorf("%s: wrong message. got=%q", engine, err.Error())
+		}
+		if errObj.Source != "  return n / 0;" {
+			t.Errorf("%s: wrong source. got=%q", engine, errObj.Source)
+		}
+		if trace := errObj.StackTrace(); trace != "  at half (<eval:2>, line 1, column 5)" {
+			t.Errorf("%s: wrong stack trace. got=%q", engine, trace)
+		}
+	}
+}
+
+func TestEvalErrors(t *testing.T) {
+	tests := []struct {
+		source  string
+		kind    string
+		message string
+	}{
+		{"1 +", object.SYNTAX_ERROR, "line 1, column 4: no prefix parse function for EOF found"},
+		{"1 / 0", object.DIVISION_BY_ZERO, "line 1, column 3: division by zero"},
+		{`throw error("boom", "CUSTOM")`, "CUSTOM", "line 1, column 1: boom"},
+	}
+
+	for _, engine := range engines {
+		for _, tt := range tests {
+			interp := newInterpreter(t, Options{Engine: engine})
+			_, err := interp.Eval(context.Background(), tt.source)
+			var errObj *object.Error
+			if !errors.As(err, &errObj) {
+				t.Errorf("%s: expected *object.Error for %q. got=%T(%v)", engine, tt.source, err, err)
+				continue
+			}
+			if errObj.Kind != tt.kind {
+				t.Errorf("%s: wrong kind for %q. expected=%q, got=%q", engine, tt.source, tt.kind, errObj.Kind)
+			}
+			if err.Error() != tt.message {
+				t.Errorf("%s: wrong message for %q. expected=%q, got=%q", engine, tt.source, tt.message, err.Error())
+			}
+		}
+	}
+}
+
+func TestIO(t *testing.T) {
+	for _, engine := range engines {
+		stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+		interp := newInterpreter(t, Options{
+			Engine: engine,
+			Stdin:  strings.NewReader("Alice\r\nBob\n"),
+			Stdout: stdout,
+			Stderr: stderr,
+		})
+
+		source := `outln("Hello, " + in()); out("Hi, " + in()); erroutln("warning")`
+		if _, err := interp.Eval(context.Background(), source); err != nil {
+			t.Fatalf("%s: Eval failed: %s", engine, err)
+		}
+		if stdout.String() != "Hello, Alice\nHi, Bob" {
+			t.Errorf("%s: wrong stdout. got=%q", engine, stdout.String())
+		}
+		if stderr.String() != "warning\n" {
+			t.Errorf("%s: wrong stderr. got=%q", engine, stderr.String())
+		}
+
+		// 入力が終わった後の in() はエラーになる
+		if _, err := interp.Eval(context.Background(), "in()"); err == nil || !strings.Contains(err.Error(), "failed to read input") {
+			t.Errorf("%s: expected read error. got=%v", engine, err)
+		}
+	}
+}
+
+func TestClock(t *testing.T) {
+	fixed := time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)
+	for _, engine := range engines {
+		interp := newInterpreter(t, Options{Engine: engine, Clock: func() time.Time { return fixed }})
+
+		result, err := interp.Eval(context.Background(), `formatTime(addDate(now(), 0, 0, 1), "DateTime")`)
+		if err != nil {
+			t.Fatalf("%s: Eval failed: %s", engine, err)
+		}
+		if result.Inspect() != "2024-05-07 07:08:09" {
+			t.Errorf("%s: wrong result. got=%q", engine, result.Inspect())
+		}
+	}
+}
+
+func TestHTTPOptions(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "pong")
+	}))
+	defer server.Close()
+
+	for _, engine := range engines {
+		interp := newInterpreter(t, Options{Engine: engine, HTTPTransport: server.Client().Transport})
+		result, err := interp.Eval(context.Background(), fmt.Sprintf(`httpGet(%q)["body"]`, server.URL))
+		if err != nil {
+			t.Fatalf("%s: Eval failed: %s", engine, err)
+		}
+		if result.Inspect() != "pong" {
+			t.Errorf("%s: wrong result. got=%q", engine, result.Inspect())
+		}
+
+		interp = newInterpreter(t, Options{Engine: engine, HTTPTransport: server.Client().Transport, DisableNetwork: true})
+		_, err = interp.Eval(context.Background(), fmt.Sprintf(`httpGet(%q)`, server.URL))
+		if err == nil || !strings.Contains(err.Error(), "network access is disabled") {
+			t.Errorf("%s: expected network error. got=%v", engine, err)
+		}
+	}
+}
+
+func TestGlobals(t *testing.T) {
+	for _, engine := range engines {
+		interp := newInterpreter(t, Options{Engine: engine})
+		if err := interp.SetGlobal("name", "Sugu"); err != nil {
+			t.Fatalf("%s: SetGlobal failed: %s", engine, err)
+		}
+		if err := interp.SetGlobal("config", struct {
+			Port  int      `sugu:"port"`
+			Hosts []string `sugu:"hosts"`
+		}{8080, []string{"a", "b"}}); err != nil {
+			t.Fatalf("%s: SetGlobal failed: %s", engine, err)
+		}
+
+		result, err := interp.Eval(context.Background(), `mut greeting = "Hello, " + name; greeting`)
+		if err != nil {
+			t.Fatalf("%s: Eval failed: %s", engine, err)
+		}
+		if result.Inspect() != "Hello, Sugu" {
+			t.Errorf("%s: wrong result. got=%q", engine, result.Inspect())
+		}
+
+		// グローバル変数は Eval をまたいで保持される
+		greeting, ok := interp.GetGlobal("greeting")
+		if !ok || greeting.Inspect() != "Hello, Sugu" {
+			t.Errorf("%s: wrong global greeting. got=%v, %t", engine, greeting, ok)
+		}
+		if _, ok := interp.GetGlobal("missing"); ok {
+			t.Errorf("%s: expected missing global to be undefined", engine)
+		}
+
+		result, err = interp.Eval(context.Background(), `config["port"] + len(config["hosts"])`)
+		if err != nil || result.Inspect() != "8082" {
+			t.Errorf("%s: wrong result for config. got=%v, %v", engine, result, err)
+		}
+
+		err = interp.SetGlobal("ch", make(chan int))
+		if err == nil || err.Error() != "cannot set ch: cannot convert chan int to a Sugu value" {
+			t.Errorf("%s: expected conversion error. got=%v", engine, err)
+		}
+	}
+}
+
+func TestRegister(t *testing.T) {
+	for _, engine := range engines {
+		interp := newInterpreter(t, Options{Engine: engine})
+		register := func(name string, fn any) {
+			if err := interp.Register(name, fn); err != nil {
+				t.Fatalf("%s: Register(%s) failed: %s", engine, name, err)
+			}
+		}
+		register("twice", func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return &object.Error{Message: "twice expects 1 argument", Kind: object.ARGUMENT_ERROR}
+			}
+			n, ok := args[0].(*object.Number)
+			if !ok {
+				return &object.Error{Message: "twice expects NUMBER", Kind: object.TYPE_ERROR}
+			}
+			return &object.Number{Value: n.Value * 2}
+		})
+		// 引数と戻り値は自動で変換される
+		register("repeat", func(s string, n int) (string, error) {
+			if n < 0 {
+				return "", errors.New("negative count")
+			}
+			return strings.Repeat(s, n), nil
+		})
+		// 既存の組み込み関数も置き換えられる
+		register("len", func(args ...object.Object) object.Object {
+			return &object.String{Value: "overridden"}
+		})
+
+		result, err := interp.Eval(context.Background(), `[twice(21), repeat("ab", 2), len([])]`)
+		if err != nil {
+			t.Fatalf("%s: Eval failed: %s", engine, err)
+		}
+		if result.Inspect() != "[42, abab, overridden]" {
+			t.Errorf("%s: wrong result. got=%q", engine, result.Inspect())
+		}
+
+		// 登録した関数のエラーは位置付きの実行時エラーになり、catch できる（2 回目以降の Eval の位置は "<eval:N>"）
+		tests := []struct {
+			source   string
+			expected string
+		}{
+			{`twice("a")`, "<eval:2>, line 1, column 6: twice expects NUMBER"},
+			{`repeat("a", -1)`, "<eval:3>, line 1, column 7: negative count"},
+			{`repeat("a", 1.5)`, "<eval:4>, line 1, column 7: argument 2: cannot convert 1.5 to int: not an integer"},
+			{`repeat("a")`, "<eval:5>, line 1, column 7: wrong number of arguments. got=1, want=2"},
+		}
+		for _, tt := range tests {
+			_, err = interp.Eval(context.Background(), tt.source)
+			if err == nil || err.Error() != tt.expected {
+				t.Errorf("%s: wrong error for %q. expected=%q, got=%v", engine, tt.source, tt.expected, err)
+			}
+		}
+		result, err = interp.Eval(context.Background(), `try { twice() } catch (e) { e["kind"] }`)
+		if err != nil || result.Inspect() != "ArgumentError" {
+			t.Errorf("%s: expected caught error. got=%v, %v", engine, result, err)
+		}
+	}
+}
+
+func TestRegisterErrors(t *testing.T) {
+	interp := newInterpreter(t, Options{})
+	tests := []struct {
+		fn       any
+		expected string
+	}{
+		{42, "cannot register f: int is not a function"},
+		{func() (int, int) { return 0, 0 }, "cannot register f: cannot convert func() (int, int) to a Sugu value: a function must return at most one value and an error"},
+	}
+	for _, tt := range tests {
+		if err := interp.Register("f", tt.fn); err == nil || err.Error() != tt.expected {
+			t.Errorf("wrong error for %T. expected=%q, got=%v", tt.fn, tt.expected, err)
+		}
+	}
+}
+
+func TestInterpretersAreIndependent(t *testing.T) {
+	first := newInterpreter(t, Options{})
+	second := newInterpreter(t, Options{})
+
+	first.Register("secret", func() int { return 42 })
+	first.SetGlobal("x", 1)
+
+	if _, err := second.Eval(context.Background(), "secret()"); err == nil || !strings.Contains(err.Error(), "identifier not found: secret") {
+		t.Errorf("builtin registered in another interpreter should not be visible. got=%v", err)
+	}
+	if _, ok := second.GetGlobal("x"); ok {
+		t.Errorf("global set in another interpreter should not be visible")
+	}
+
+	// 標準の組み込み関数は Register の影響を受けない
+	first.Register("len", func(v any) int { return -1 })
+	result, err := second.Eval(context.Background(), "len([1, 2])")
+	if err != nil || result.Inspect() != "2" {
+		t.Errorf("len should not be overridden. got=%v, %v", result, err)
+	}
+}
+
+func TestCall(t *testing.T) {
+	for _, engine := range engines {
+		interp := newInterpreter(t, Options{Engine: engine})
+		source := `
+mut count = 0;
+func add(a, b) => { count++; a + b }
+func fail(msg) => { throw error(msg, "CUSTOM") }
+const counter = func() => { count };
+`
+		if _, err := interp.Eval(context.Background(), source); err != nil {
+			t.Fatalf("%s: Eval failed: %s", engine, err)
+		}
+
+		add, _ := interp.GetGlobal("add")
+		result, err := interp.Call(context.Background(), add, 1, &object.Number{Value: 2})
+		if err != nil || result.Inspect() != "3" {
+			t.Errorf("%s: wrong result of add. got=%v, %v", engine, result, err)
+		}
+
+		// クロージャは定義された環境を参照する
+		counter, _ := interp.GetGlobal("counter")
+		result, err = interp.Call(context.Background(), counter)
+		if err != nil || result.Inspect() != "1" {
+			t.Errorf("%s: wrong result of counter. got=%v, %v", engine, result, err)
+		}
+
+		// catch されなかった throw はエラーとして返る
+		fail, _ := interp.GetGlobal("fail")
+		_, err = interp.Call(context.Background(), fail, "boom")
+		var errObj *object.Error
+		if !errors.As(err, &errObj) || errObj.Kind != "CUSTOM" || errObj.Text() != "boom" {
+			t.Errorf("%s: wrong error of fail. got=%v", engine, err)
+		}
+
+		// 組み込み関数も呼び出せる
+		builtin, _ := interp.Eval(context.Background(), "len")
+		result, err = interp.Call(context.Background(), builtin, "abc")
+		if err != nil || result.Inspect() != "3" {
+			t.Errorf("%s: wrong result of len. got=%v, %v", engine, result, err)
+		}
+
+		if _, err := interp.Call(context.Background(), &object.Number{Value: 1}); err == nil || err.Error() != "not a function: NUMBER" {
+			t.Errorf("%s: expected not a function error. got=%v", engine, err)
+		}
+		if _, err := interp.Call(context.Background(), add, make(chan int)); err == nil || err.Error() != "argument 1: cannot convert chan int to a Sugu value" {
+			t.Errorf("%s: expected conversion error. got=%v", engine, err)
+		}
+	}
+}
+
+func TestLimits(t *testing.T) {
+	for _, engine := range engines {
+		interp := newInterpreter(t, Options{Engine: engine, Limits: evaluator.Limits{MaxSteps: 100}})
+		_, err := interp.Eval(context.Background(), "func loop() => { while (true) {} }")
+		if err != nil {
+			t.Fatalf("%s: Eval failed: %s", engine, err)
+		}
+
+		_, err = interp.Eval(context.Background(), "loop()")
+		if err == nil || !strings.HasPrefix(err.Error(), evaluator.LimitExceededMessage) {
+			t.Errorf("%s: expected limit error from Eval. got=%v", engine, err)
+		}
+
+		// Call にも上限が適用される
+		loop, _ := interp.GetGlobal("loop")
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, err = interp.Call(ctx, loop)
+		if err == nil || err.Error() != "execution limit exceeded: context canceled" {
+			t.Errorf("%s: expected limit error from Call. got=%v", engine, err)
+		}
+	}
+}
+
+func TestUnknownEngine(t *testing.T) {
+	if _, err := New(Options{Engine: "jit"}); err == nil || err.Error() != "unknown engine: jit" {
+		t.Errorf("expected unknown engine error. got=%v", err)
+	}
+}
@@ -19,6 +19,12 @@ const (
 	NUMBER = "NUMBER" // 数値（整数・小数）
 	STRING = "STRING" // 文字列
 
+	// テンプレート文字列（`a${x}b${y}c` は TEMPLATE_HEAD "a"、x、TEMPLATE_MIDDLE "b"、y、TEMPLATE_TAIL "c" になる）
+	TEMPLATE        = "TEMPLATE"        // ${} を含まないテンプレート文字列全体
+	TEMPLATE_HEAD   = "TEMPLATE_HEAD"   // ` から最初の ${ まで
+	TEMPLATE_MIDDLE = "TEMPLATE_MIDDLE" // } から次の ${ まで
+	TEMPLATE_TAIL   = "TEMPLATE_TAIL"   // 最後の } から ` まで
+
 	// 演算子
 	ASSIGN   = "="
 	PLUS     = "+"
@@ -56,6 +62,7 @@ const (
 	SEMICOLON = ";"
 	COLON     = ":"
 	ARROW     = "=>"
+	ELLIPSIS  = "..."
 
 	LPAREN   = "("
 	RPAREN   = ")"
@@ -83,7 +90,10 @@ const (
 	NULL     = "NULL"
 	TRY      = "TRY"
 	CATCH    = "CATCH"
+	FINALLY  = "FINALLY"
 	THROW    = "THROW"
+	IMPORT   = "IMPORT"
+	EXPORT   = "EXPORT"
 )
 
 var keywords = map[string]TokenType{
@@ -105,7 +115,10 @@ var keywords = map[string]TokenType{
 	"null":     NULL,
 	"try":      TRY,
 	"catch":    CATCH,
+	"finally":  FINALLY,
 	"throw":    THROW,
+	"import":   IMPORT,
+	"export":   EXPORT,
 }
 
 // LookupIdent は識別子がキーワードかどうかを判定する
@@ -0,0 +1,36 @@
+package vm
+
+import "sugu/object"
+
+// iterator は for-in の反復状態（ループの間スタックに置かれる）
+type iterator struct {
+	elements []object.Object   // 配列の要素
+	pairs    []object.HashPair // マップの要素（反復開始時点の組）
+	isMap    bool
+	index    int
+
+	env      *object.Environment // 反復用のスコープ（使い回す）
+	closures int                 // env を作った時点の VM のクロージャ数
+}
+
+func (it *iterator) Type() object.ObjectType { return "ITERATOR" }
+func (it *iterator) Inspect() string         { return "iterator" }
+
+// next は次の要素を返す
+// 配列ではインデックスと要素、マップではキーと値を返す
+func (it *iterator) next() (object.Object, object.Object, bool) {
+	i := it.index
+	if it.isMap {
+		if i >= len(it.pairs) {
+			return nil, nil, false
+		}
+		it.index++
+		return it.pairs[i].Key, it.pairs[i].Value, true
+	}
+
+	if i >= len(it.elements) {
+		return nil, nil, false
+	}
+	it.index++
+	return &object.Number{Value: float64(i)}, it.elements[i], true
+}
@@ -0,0 +1,851 @@
+package vm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"sugu/ast"
+	"sugu/compiler"
+	"sugu/evaluator"
+	"sugu/object"
+)
+
+const initialStackSize = 2048
+
+// frame は関数呼び出し（またはトップレベルのコード）の実行状態
+type frame struct {
+	fn   *compiler.CompiledFunction
+	ip   int
+	env  *object.Environment // 現在のスコープ
+	base int                 // このフレームが使うスタックの開始位置
+	last object.Object       // 最後に評価した文の結果
+	file string              // エラー位置の表示に使うファイル名
+
+	call  *object.Function // 呼び出された関数（トップレベルのコードでは nil）
+	site  int              // 呼び出し元のフレームでの OpCall の位置
+	depth int              // 関数呼び出しの深さ
+}
+
+// handler は OpSetupTry で登録された catch、または OpSetupFinally で登録された finally の飛び先
+type handler struct {
+	frame   int  // try を実行したフレームの番号
+	catch   int  // catch ブロック（または finally ブロック）の位置
+	finally bool // finally ブロックの場合は例外を保留して実行し、終わりで投げ直す
+	sp      int
+	env     *object.Environment
+}
+
+// exception は throw された値、または実行中に発生したエラー
+type exception struct {
+	value object.Object // throw された値（エラーの場合は nil）
+	err   *object.Error
+	stack []object.StackFrame // throw した時点の呼び出し履歴
+}
+
+// pending は finally ブロックの実行中にスタックに置かれる、ブロックを抜けた後の処理
+// 例外で入った場合は終わりで投げ直し、正常に入った場合はそれまでの文の評価結果に戻す
+type pending struct {
+	exc  *exception
+	last object.Object
+}
+
+func (p *pending) Type() object.ObjectType { return "PENDING" }
+func (p *pending) Inspect() string         { return "pending" }
+
+// VM はコンパイル済みのバイトコードを実行するスタックマシン
+// 組み込み関数やモジュールの読み込みは Evaluator と共有する
+type VM struct {
+	ev        *evaluator.Evaluator
+	functions map[*ast.BlockStatement]*compiler.CompiledFunction // 関数本体とコンパイル結果の対応
+
+	stack    []object.Object
+	sp       int // 次に値を積む位置
+	frames   []*frame
+	handlers []handler
+
+	closures int // 作成したクロージャの数（for-in のスコープを使い回せるかの判定に使用）
+	site     int // 実行中の組み込み関数の OpCall の位置（組み込み関数の外では -1）
+}
+
+// New は ev と状態を共有する VM を作成する
+// ev で import したモジュールもこの VM で評価される
+func New(ev *evaluator.Evaluator) *VM {
+	vm := &VM{
+		ev:        ev,
+		functions: make(map[*ast.BlockStatement]*compiler.CompiledFunction),
+		stack:     make([]object.Object, initialStackSize),
+		site:      -1,
+	}
+	ev.SetEngine(vm.Eval)
+	ev.SetCaller(vm.Call)
+	return vm
+}
+
+// Eval はプログラムをコンパイルして実行する
+// 結果は evaluator の Eval と同じく、最後に評価した文の値またはエラーになる
+func (vm *VM) Eval(program *ast.Program, env *object.Environment) object.Object {
+	comp := compiler.New()
+	if err := comp.Compile(program); err != nil {
+		return vm.compileError(err)
+	}
+	return vm.Run(comp.Bytecode(), env)
+}
+
+// EvalContext は ctx がキャンセルされるか Evaluator に設定された上限に達するまでプログラムを実行する
+func (vm *VM) EvalContext(ctx context.Context, program *ast.Program, env *object.Environment) object.Object {
+	end := vm.ev.Begin(ctx)
+	defer end()
+	return vm.Eval(program, env)
+}
+
+// Run はコンパイル済みのプログラムを env をトップレベルのスコープとして実行する
+func (vm *VM) Run(bc *compiler.Bytecode, env *object.Environment) object.Object {
+	for _, fn := range bc.Functions {
+		vm.functions[fn.Literal.Body] = fn
+	}
+
+	base := len(vm.frames)
+	depth := 0
+	if base > 0 {
+		depth = vm.frames[base-1].depth
+	}
+	vm.frames = append(vm.frames, &frame{fn: bc.Main, env: env, base: vm.sp, file: vm.ev.File(), depth: depth})
+
+	return vm.result(vm.run(base))
+}
+
+// Call は関数 fn を args を引数にして呼び出し、戻り値を返す（evaluator.Caller の実装）
+// 組み込み関数からの呼び出しは、その組み込み関数を呼び出した位置からの呼び出しとして呼び出し履歴に記録する
+// Go からの呼び出し自体は呼び出し履歴に記録せず、呼び出しの深さにも数えない
+// catch されなかった throw は evaluator.Throw の値として返す
+func (vm *VM) Call(fn object.Object, args []object.Object) object.Object {
+	switch fn := fn.(type) {
+	case *object.Function:
+		code, err := vm.compiled(fn)
+		if err != nil {
+			return vm.compileError(err)
+		}
+
+		base := len(vm.frames)
+		f := &frame{fn: code, base: vm.sp, last: evaluator.NULL, file: fn.File}
+		if base > 0 {
+			f.depth = vm.frames[base-1].depth
+		}
+		if vm.site >= 0 {
+			caller := vm.frames[base-1]
+			if maxDepth := vm.ev.MaxCallDepth(); f.depth >= maxDepth {
+				return vm.errorAt(caller, vm.site, object.RANGE_ERROR, "maximum call depth exceeded (%d)", maxDepth)
+			}
+			f.call, f.site, f.depth = fn, vm.site, f.depth+1
+		}
+		env, errObj := vm.ev.ExtendFunctionEnv(fn, args)
+		if errObj != nil {
+			return errObj
+		}
+		f.env = env
+		vm.frames = append(vm.frames, f)
+
+		site := vm.site
+		vm.site = -1
+		result, exc := vm.run(base)
+		vm.site = site
+		if exc != nil && exc.err == nil {
+			return evaluator.Throw(exc.value, exc.stack)
+		}
+		return vm.result(result, exc)
+
+	case *object.Builtin:
+		return fn.Fn(args...)
+
+	default:
+		return newError(object.TYPE_ERROR, "not a function: %s", fn.Type())
+	}
+}
+
+// result は run の結果を Eval と同じ形の値にする
+func (vm *VM) result(result object.Object, exc *exception) object.Object {
+	if exc != nil {
+		if exc.err != nil {
+			return exc.err
+		}
+		// キャッチされなかったthrowはエラーとして扱う
+		return evaluator.UncaughtError(exc.value, exc.stack)
+	}
+	return result
+}
+
+// run は base 番目のフレームが return するまで命令を実行する
+// catch されなかった例外は base 番目のフレームを破棄して返す
+func (vm *VM) run(base int) (object.Object, *exception) {
+	f := vm.frames[len(vm.frames)-1]
+
+	for {
+		ins := f.fn.Instructions
+		start := f.ip
+		op := compiler.Opcode(ins[start])
+		f.ip++
+
+		var errObj *object.Error
+		var thrown object.Object
+		var rethrown *exception // finally ブロックの後などで投げ直す例外
+
+		switch op {
+		case compiler.OpConstant:
+			idx := compiler.ReadUint16(ins[f.ip:])
+			f.ip += 2
+			vm.push(f.fn.Bytecode.Constants[idx])
+
+		case compiler.OpNull:
+			vm.push(evaluator.NULL)
+
+		case compiler.OpTrue:
+			vm.push(evaluator.TRUE)
+
+		case compiler.OpFalse:
+			vm.push(evaluator.FALSE)
+
+		case compiler.OpArray:
+			n := int(compiler.ReadUint16(ins[f.ip:]))
+			f.ip += 2
+			elements := make([]object.Object, n)
+			copy(elements, vm.stack[vm.sp-n:vm.sp])
+			vm.sp -= n
+			vm.push(&object.Array{Elements: elements})
+
+		case compiler.OpTemplate:
+			n := int(compiler.ReadUint16(ins[f.ip:]))
+			f.ip += 2
+			var out strings.Builder
+			for _, v := range vm.stack[vm.sp-n : vm.sp] {
+				out.WriteString(v.Inspect())
+			}
+			vm.sp -= n
+			vm.push(&object.String{Value: out.String()})
+
+		case compiler.OpMap:
+			n := int(compiler.ReadUint16(ins[f.ip:]))
+			f.ip += 2
+			m := &object.Map{}
+			for i := vm.sp - 2*n; i < vm.sp; i += 2 {
+				m.Set(vm.stack[i].(object.Hashable), vm.stack[i+1])
+			}
+			vm.sp -= 2 * n
+			vm.push(m)
+
+		case compiler.OpHashKey:
+			_, errObj = evaluator.HashKey(vm.stack[vm.sp-1])
+
+		case compiler.OpClosure:
+			idx := compiler.ReadUint16(ins[f.ip:])
+			f.ip += 2
+			vm.push(vm.newClosure(f, f.fn.Bytecode.Functions[idx].Literal))
+
+		case compiler.OpPop:
+			vm.sp--
+
+		case compiler.OpPopLast:
+			f.last = vm.pop()
+
+		case compiler.OpDup:
+			vm.push(vm.stack[vm.sp-1])
+
+		case compiler.OpAdd, compiler.OpSub, compiler.OpMul, compiler.OpDiv, compiler.OpMod,
+			compiler.OpEqual, compiler.OpNotEqual, compiler.OpLess, compiler.OpGreater,
+			compiler.OpLessEqual, compiler.OpGreaterEqual:
+			right := vm.pop()
+			left := vm.pop()
+			result := infixOperation(op, left, right)
+			if isError(result) {
+				errObj = result.(*object.Error)
+				break
+			}
+			vm.push(result)
+
+		case compiler.OpMinus, compiler.OpBang:
+			operator := "-"
+			if op == compiler.OpBang {
+				operator = "!"
+			}
+			result := evaluator.PrefixOperation(operator, vm.pop())
+			if isError(result) {
+				errObj = result.(*object.Error)
+				break
+			}
+			vm.push(result)
+
+		case compiler.OpSwitchEqual:
+			caseValue := vm.pop()
+			value := vm.pop()
+			vm.push(evaluator.NativeBool(evaluator.IsEqual(value, caseValue)))
+
+		case compiler.OpJump:
+			f.ip = int(compiler.ReadUint32(ins[f.ip:]))
+
+		case compiler.OpJumpNotTruthy:
+			if !evaluator.IsTruthy(vm.pop()) {
+				f.ip = int(compiler.ReadUint32(ins[f.ip:]))
+			} else {
+				f.ip += 4
+			}
+
+		case compiler.OpJumpFalsyKeep:
+			if !evaluator.IsTruthy(vm.stack[vm.sp-1]) {
+				f.ip = int(compiler.ReadUint32(ins[f.ip:]))
+			} else {
+				vm.sp--
+				f.ip += 4
+			}
+
+		case compiler.OpJumpTruthyKeep:
+			if evaluator.IsTruthy(vm.stack[vm.sp-1]) {
+				f.ip = int(compiler.ReadUint32(ins[f.ip:]))
+			} else {
+				vm.sp--
+				f.ip += 4
+			}
+
+		case compiler.OpJumpIfBound:
+			if f.env.Exists(vm.readNameAt(f, f.ip+4)) {
+				f.ip = int(compiler.ReadUint32(ins[f.ip:]))
+			} else {
+				f.ip += 6
+			}
+
+		case compiler.OpGetName:
+			name := vm.readName(f)
+			if val, ok := f.env.Get(name); ok {
+				vm.push(val)
+			} else if builtin, ok := vm.ev.LookupBuiltin(name); ok {
+				vm.push(builtin)
+			} else {
+				errObj = vm.errorAt(f, start, object.REFERENCE_ERROR, "identifier not found: %s", name)
+			}
+
+		case compiler.OpGetVar:
+			name := vm.readName(f)
+			if val, ok := f.env.Get(name); ok {
+				vm.push(val)
+			} else {
+				errObj = vm.errorAt(f, start, object.REFERENCE_ERROR, "identifier not found: %s", name)
+			}
+
+		case compiler.OpDefine:
+			f.env.Set(vm.readName(f), vm.stack[vm.sp-1])
+
+		case compiler.OpDefineConst:
+			f.env.SetConst(vm.readName(f), vm.stack[vm.sp-1])
+
+		case compiler.OpDestructure:
+			idx := compiler.ReadUint16(ins[f.ip:])
+			constant := ins[f.ip+2] == 1
+			f.ip += 3
+			errObj = evaluator.BindPattern(f.env, f.fn.Bytecode.Patterns[idx], vm.stack[vm.sp-1], constant)
+
+		case compiler.OpCheckDefined:
+			name := vm.readName(f)
+			if _, ok := f.env.Get(name); !ok {
+				errObj = vm.errorAt(f, start, object.REFERENCE_ERROR, "identifier not found: %s", name)
+			}
+
+		case compiler.OpCheckMutable:
+			name := vm.readName(f)
+			kind := ins[f.ip]
+			f.ip++
+			if f.env.IsConst(name) {
+				if kind == compiler.MutateModify {
+					errObj = newError(object.TYPE_ERROR, "cannot modify const variable: %s", name)
+				} else {
+					errObj = newError(object.TYPE_ERROR, "cannot reassign to const variable: %s", name)
+				}
+			}
+
+		case compiler.OpAssign:
+			name := vm.readName(f)
+			if f.env.IsConst(name) {
+				errObj = vm.errorAt(f, start, object.TYPE_ERROR, "cannot reassign to const variable: %s", name)
+			} else if _, ok := f.env.Update(name, vm.stack[vm.sp-1]); !ok {
+				errObj = vm.errorAt(f, start, object.REFERENCE_ERROR, "failed to update variable: %s", name)
+			}
+
+		case compiler.OpUpdate:
+			f.env.Update(vm.readName(f), vm.stack[vm.sp-1])
+
+		case compiler.OpPostfix:
+			name := vm.readName(f)
+			operator := "++"
+			delta := 1.0
+			if ins[f.ip] == 1 {
+				operator, delta = "--", -1
+			}
+			f.ip++
+			current := vm.stack[vm.sp-1]
+			if f.env.IsConst(name) {
+				errObj = newError(object.TYPE_ERROR, "cannot reassign to const variable: %s", name)
+			} else if num, ok := current.(*object.Number); !ok {
+				errObj = newError(object.TYPE_ERROR, "postfix operator %s not supported: %s", operator, current.Type())
+			} else {
+				f.env.Update(name, &object.Number{Value: num.Value + delta})
+			}
+
+		case compiler.OpIndex:
+			index := vm.pop()
+			left := vm.pop()
+			result := evaluator.IndexOperation(left, index)
+			if isError(result) {
+				errObj = result.(*object.Error)
+				break
+			}
+			vm.push(result)
+
+		case compiler.OpSetIndex:
+			val := vm.pop()
+			index := vm.pop()
+			left := vm.pop()
+			result := evaluator.IndexAssign(left, index, val)
+			if isError(result) {
+				errObj = result.(*object.Error)
+				break
+			}
+			vm.push(result)
+
+		case compiler.OpIndexCompound:
+			operator := compiler.InfixOperator(compiler.Opcode(ins[f.ip]))
+			f.ip++
+			val := vm.pop()
+			index := vm.pop()
+			left := vm.pop()
+			result := evaluator.IndexOperation(left, index)
+			if !isError(result) {
+				result = evaluator.InfixOperation(operator, result, val)
+			}
+			if !isError(result) {
+				result = evaluator.IndexAssign(left, index, result)
+			}
+			if isError(result) {
+				errObj = result.(*object.Error)
+				break
+			}
+			vm.push(result)
+
+		case compiler.OpSliceIndex:
+			_, errObj = evaluator.SliceIndex(vm.stack[vm.sp-1])
+
+		case compiler.OpSlice:
+			flags := ins[f.ip]
+			f.ip++
+			var low, high int64
+			hasLow, hasHigh := flags&compiler.SliceLow != 0, flags&compiler.SliceHigh != 0
+			if hasHigh {
+				high, _ = evaluator.SliceIndex(vm.pop())
+			}
+			if hasLow {
+				low, _ = evaluator.SliceIndex(vm.pop())
+			}
+			result := evaluator.SliceOperation(vm.pop(), low, high, hasLow, hasHigh)
+			if isError(result) {
+				errObj = result.(*object.Error)
+				break
+			}
+			vm.push(result)
+
+		case compiler.OpPushScope:
+			f.env = object.NewEnclosedEnvironment(f.env)
+
+		case compiler.OpPopScope:
+			f.env = f.env.Outer()
+
+		case compiler.OpCall:
+			argc := int(ins[f.ip])
+			f.ip++
+			callee := vm.stack[vm.sp-1-argc]
+			switch fn := callee.(type) {
+			case *object.Function:
+				if maxDepth := vm.ev.MaxCallDepth(); f.depth >= maxDepth {
+					errObj = vm.errorAt(f, start, object.RANGE_ERROR, "maximum call depth exceeded (%d)", maxDepth)
+					break
+				}
+				code, err := vm.compiled(fn)
+				if err != nil {
+					errObj = vm.compileError(err)
+					break
+				}
+				env, bindErr := vm.ev.ExtendFunctionEnv(fn, vm.stack[vm.sp-argc:vm.sp])
+				if bindErr != nil {
+					errObj = bindErr
+					break
+				}
+				vm.sp -= argc + 1
+				f = &frame{fn: code, env: env, base: vm.sp, last: evaluator.NULL, file: fn.File, call: fn, site: start, depth: f.depth + 1}
+				vm.frames = append(vm.frames, f)
+
+			case *object.Builtin:
+				args := make([]object.Object, argc)
+				copy(args, vm.stack[vm.sp-argc:vm.sp])
+				vm.sp -= argc + 1
+				site := vm.site
+				vm.site = start
+				result := fn.Fn(args...)
+				vm.site = site
+				if isError(result) {
+					errObj = result.(*object.Error)
+					break
+				}
+				// 組み込み関数から呼び出した関数の throw は、throw した時点の呼び出し履歴のまま投げ直す
+				if value, stack, ok := evaluator.Thrown(result); ok {
+					rethrown = &exception{value: value, stack: stack}
+					break
+				}
+				vm.push(result)
+
+			default:
+				errObj = newError(object.TYPE_ERROR, "not a function: %s", callee.Type())
+			}
+
+		case compiler.OpReturnValue, compiler.OpReturnLast:
+			result := f.last
+			if op == compiler.OpReturnValue {
+				result = vm.pop()
+			}
+			idx := len(vm.frames) - 1
+			vm.frames = vm.frames[:idx]
+			vm.sp = f.base
+			vm.dropHandlers(idx)
+			if idx == base {
+				return result, nil
+			}
+			f = vm.frames[idx-1]
+			vm.push(result)
+
+		case compiler.OpIterInit:
+			iterable := vm.pop()
+			switch obj := iterable.(type) {
+			case *object.Array:
+				vm.push(&iterator{elements: obj.Elements})
+			case *object.Map:
+				vm.push(&iterator{pairs: obj.Pairs(), isMap: true})
+			default:
+				errObj = newError(object.TYPE_ERROR, "for-in requires ARRAY or MAP, got %s", iterable.Type())
+			}
+
+		case compiler.OpIterNext:
+			target := int(compiler.ReadUint32(ins[f.ip:]))
+			key := vm.readNameAt(f, f.ip+4)
+			valueIdx := compiler.ReadUint16(ins[f.ip+6:])
+			f.ip += 8
+			it := vm.stack[vm.sp-1].(*iterator)
+			k, v, ok := it.next()
+			if !ok {
+				f.ip = target
+				break
+			}
+			vars := 1
+			if valueIdx != compiler.NoName {
+				vars = 2
+			}
+			env := vm.iterationEnv(it, f.env, vars)
+			if valueIdx != compiler.NoName {
+				env.SetConst(key, k)
+				env.SetConst(f.fn.Bytecode.Names[valueIdx], v)
+			} else if it.isMap {
+				env.SetConst(key, k)
+			} else {
+				env.SetConst(key, v)
+			}
+			f.env = env
+
+		case compiler.OpStep:
+			errObj = vm.ev.Step()
+
+		case compiler.OpSetupTry:
+			catch := int(compiler.ReadUint32(ins[f.ip:]))
+			f.ip += 4
+			vm.handlers = append(vm.handlers, handler{frame: len(vm.frames) - 1, catch: catch, sp: vm.sp, env: f.env})
+
+		case compiler.OpPopTry:
+			vm.handlers = vm.handlers[:len(vm.handlers)-1]
+
+		case compiler.OpSetupFinally:
+			target := int(compiler.ReadUint32(ins[f.ip:]))
+			f.ip += 4
+			vm.handlers = append(vm.handlers, handler{frame: len(vm.frames) - 1, catch: target, finally: true, sp: vm.sp, env: f.env})
+
+		case compiler.OpEnterFinally:
+			vm.push(&pending{last: f.last})
+
+		case compiler.OpEndFinally:
+			p := vm.pop().(*pending)
+			if p.exc == nil {
+				f.last = p.last
+				break
+			}
+			rethrown = p.exc
+
+		case compiler.OpThrow:
+			thrown = vm.pop()
+			if exc, ok := thrown.(*object.Exception); ok {
+				pos, _ := f.fn.PositionAt(start)
+				vm.ev.ThrowException(exc, f.file, pos.Line, pos.Column, vm.stackTrace())
+			}
+
+		case compiler.OpError:
+			msg := f.fn.Bytecode.Constants[compiler.ReadUint16(ins[f.ip:])].(*object.String).Value
+			kind := f.fn.Bytecode.Constants[compiler.ReadUint16(ins[f.ip+2:])].(*object.String).Value
+			f.ip += 4
+			errObj = vm.errorAt(f, start, kind, "%s", msg)
+
+		case compiler.OpImport:
+			idx := compiler.ReadUint16(ins[f.ip:])
+			f.ip += 2
+			result := vm.ev.Import(f.fn.Bytecode.Imports[idx], f.env)
+			if isError(result) {
+				errObj = result.(*object.Error)
+				break
+			}
+			vm.push(result)
+
+		case compiler.OpExport:
+			vm.ev.Export(vm.readName(f))
+
+		default:
+			errObj = newError(object.GENERIC_ERROR, "unknown opcode: %d", op)
+		}
+
+		if errObj == nil && thrown == nil && rethrown == nil {
+			continue
+		}
+
+		// 位置を持たないエラー（演算子や組み込み関数のエラー）には命令の位置を付ける
+		if errObj != nil {
+			if pos, ok := f.fn.PositionAt(start); ok {
+				vm.ev.Locate(errObj, f.file, pos.Line, pos.Column)
+			}
+		}
+
+		exc := rethrown
+		if exc == nil {
+			exc = &exception{value: thrown, err: errObj}
+		}
+		if !vm.catch(base, exc) {
+			// キャッチされなかった例外には、フレームを破棄する前の呼び出し履歴を付ける
+			if exc.stack == nil {
+				exc.stack = vm.stackTrace()
+			}
+			if exc.err != nil && exc.err.Stack == nil {
+				exc.err.Stack = exc.stack
+			}
+			vm.sp = vm.frames[base].base
+			vm.frames = vm.frames[:base]
+			vm.dropHandlers(base)
+			return nil, exc
+		}
+		f = vm.frames[len(vm.frames)-1]
+	}
+}
+
+// catch は base 番目以降のフレームで登録された最も内側の catch または finally に制御を移す
+// catch の変数には throw された値、またはエラーの情報を持つ Exception が入る
+// finally の場合は例外を保留し、finally ブロックの終わりで投げ直す
+// 実行の上限に達した場合はキャッチせず、finally ブロックも実行しない
+func (vm *VM) catch(base int, exc *exception) bool {
+	if len(vm.handlers) == 0 || vm.ev.LimitExceeded() {
+		return false
+	}
+	h := vm.handlers[len(vm.handlers)-1]
+	if h.frame < base {
+		return false
+	}
+	vm.handlers = vm.handlers[:len(vm.handlers)-1]
+
+	// フレームを破棄する前に、発生した時点の呼び出し履歴を記録する
+	// finally ブロックの後で投げ直す場合もこの呼び出し履歴を使う
+	if exc.stack == nil {
+		exc.stack = vm.stackTrace()
+	}
+	if exc.err != nil && exc.err.Stack == nil {
+		exc.err.Stack = exc.stack
+	}
+
+	vm.frames = vm.frames[:h.frame+1]
+	f := vm.frames[h.frame]
+	f.ip = h.catch
+	f.env = h.env
+	vm.sp = h.sp
+
+	if h.finally {
+		vm.push(&pending{exc: exc})
+	} else if exc.err != nil {
+		vm.push(object.NewException(exc.err))
+	} else {
+		vm.push(exc.value)
+	}
+	return true
+}
+
+// stackTrace は現在の呼び出し履歴を内側の呼び出しから順に返す（関数の中でなければ nil）
+func (vm *VM) stackTrace() []object.StackFrame {
+	var stack []object.StackFrame
+	for i := len(vm.frames) - 1; i > 0; i-- {
+		f := vm.frames[i]
+		if f.call == nil {
+			continue
+		}
+		caller := vm.frames[i-1]
+		pos, _ := caller.fn.PositionAt(f.site)
+		stack = append(stack, object.StackFrame{Function: f.call.Name, File: caller.file, Line: pos.Line, Column: pos.Column})
+	}
+	return stack
+}
+
+// dropHandlers は frame 番目以降のフレームで登録された catch を取り除く
+func (vm *VM) dropHandlers(frame int) {
+	for len(vm.handlers) > 0 && vm.handlers[len(vm.handlers)-1].frame >= frame {
+		vm.handlers = vm.handlers[:len(vm.handlers)-1]
+	}
+}
+
+// newClosure は関数リテラルから現在のスコープを捕捉した関数を作る
+func (vm *VM) newClosure(f *frame, lit *ast.FunctionLiteral) *object.Function {
+	name := ""
+	if lit.Name != nil {
+		name = lit.Name.Value
+	}
+	fn := &object.Function{
+		Parameters: lit.Parameters,
+		Defaults:   lit.Defaults,
+		Patterns:   lit.Patterns,
+		Rest:       lit.Rest,
+		Body:       lit.Body,
+		Env:        f.env,
+		Name:       name,
+		File:       f.file,
+	}
+	if name != "" {
+		f.env.Set(name, fn)
+	}
+	vm.closures++
+	return fn
+}
+
+// compiled は関数本体のコンパイル結果を返す
+// VM の外で作られた関数は初めて呼び出されたときにコンパイルする
+func (vm *VM) compiled(fn *object.Function) (*compiler.CompiledFunction, error) {
+	if code, ok := vm.functions[fn.Body]; ok {
+		return code, nil
+	}
+	code, err := compiler.CompileFunction(&ast.FunctionLiteral{Parameters: fn.Parameters, Defaults: fn.Defaults, Patterns: fn.Patterns, Rest: fn.Rest, Body: fn.Body})
+	if err != nil {
+		return nil, err
+	}
+	vm.functions[fn.Body] = code
+	return code, nil
+}
+
+// iterationEnv は for-in の反復用のスコープを返す
+// 前の反復からクロージャが作られていなければ、スコープを作り直さずに使い回す
+// 本体で変数が宣言されていなければ、ループ変数は上書きされるので削除も省略する
+func (vm *VM) iterationEnv(it *iterator, outer *object.Environment, vars int) *object.Environment {
+	if it.env != nil && it.closures == vm.closures && it.env.Outer() == outer {
+		if it.env.Len() != vars {
+			it.env.Clear()
+		}
+	} else {
+		it.env = object.NewEnclosedEnvironment(outer)
+	}
+	it.closures = vm.closures
+	return it.env
+}
+
+// infixOperation は中置演算子を適用する
+// 数値同士の演算は evaluator を経由せずに計算する
+func infixOperation(op compiler.Opcode, left, right object.Object) object.Object {
+	if l, ok := left.(*object.Number); ok {
+		if r, ok := right.(*object.Number); ok {
+			switch op {
+			case compiler.OpAdd:
+				return &object.Number{Value: l.Value + r.Value}
+			case compiler.OpSub:
+				return &object.Number{Value: l.Value - r.Value}
+			case compiler.OpMul:
+				return &object.Number{Value: l.Value * r.Value}
+			case compiler.OpDiv:
+				if r.Value != 0 {
+					return &object.Number{Value: l.Value / r.Value}
+				}
+			case compiler.OpMod:
+				if r.Value != 0 {
+					return &object.Number{Value: math.Mod(l.Value, r.Value)}
+				}
+			case compiler.OpLess:
+				return evaluator.NativeBool(l.Value < r.Value)
+			case compiler.OpGreater:
+				return evaluator.NativeBool(l.Value > r.Value)
+			case compiler.OpLessEqual:
+				return evaluator.NativeBool(l.Value <= r.Value)
+			case compiler.OpGreaterEqual:
+				return evaluator.NativeBool(l.Value >= r.Value)
+			case compiler.OpEqual:
+				return evaluator.NativeBool(l.Value == r.Value)
+			case compiler.OpNotEqual:
+				return evaluator.NativeBool(l.Value != r.Value)
+			}
+		}
+	}
+	return evaluator.InfixOperation(compiler.InfixOperator(op), left, right)
+}
+
+// ヘルパー関数
+
+func (vm *VM) push(obj object.Object) {
+	if vm.sp >= len(vm.stack) {
+		vm.stack = append(vm.stack, make([]object.Object, len(vm.stack))...)
+	}
+	vm.stack[vm.sp] = obj
+	vm.sp++
+}
+
+func (vm *VM) pop() object.Object {
+	vm.sp--
+	return vm.stack[vm.sp]
+}
+
+// readName は 2 バイトのオペランドを名前として読む
+func (vm *VM) readName(f *frame) string {
+	name := vm.readNameAt(f, f.ip)
+	f.ip += 2
+	return name
+}
+
+func (vm *VM) readNameAt(f *frame, offset int) string {
+	return f.fn.Bytecode.Names[compiler.ReadUint16(f.fn.Instructions[offset:])]
+}
+
+// errorAt は offset の命令の位置情報付きのエラーを作成する
+// 位置が記録されていない命令では位置なしのエラーになる（木構造評価器と同じメッセージにするため）
+func (vm *VM) errorAt(f *frame, offset int, kind, format string, a ...interface{}) *object.Error {
+	if pos, ok := f.fn.PositionAt(offset); ok {
+		return vm.ev.ErrorAt(f.file, pos.Line, pos.Column, kind, format, a...)
+	}
+	return newError(kind, format, a...)
+}
+
+// compileError はコンパイルエラーを実行時のエラーと同じ形式に変換する
+func (vm *VM) compileError(err error) *object.Error {
+	var compileErr *compiler.Error
+	if errors.As(err, &compileErr) {
+		return vm.ev.ErrorAt(vm.ev.File(), compileErr.Line, compileErr.Column, object.SYNTAX_ERROR, "%s", compileErr.Message)
+	}
+	return newError(object.GENERIC_ERROR, "%s", err.Error())
+}
+
+func newError(kind, format string, a ...interface{}) *object.Error {
+	return &object.Error{Message: fmt.Sprintf(format, a...), Kind: kind}
+}
+
+func isError(obj object.Object) bool {
+	if obj != nil {
+		return obj.Type() == object.ERROR_OBJ
+	}
+	return false
+}
@@ -0,0 +1,138 @@
+package vm
+
+import (
+	"sugu/ast"
+	"sugu/evaluator"
+	"sugu/lexer"
+	"sugu/object"
+	"sugu/parser"
+	"testing"
+)
+
+// 言語機能全体のテストは evaluator パッケージのテストを VM でも実行して確認している（engine_test.go）
+// ここでは VM 固有の動作を確認する
+
+func TestForInClosuresCaptureEachIteration(t *testing.T) {
+	input := `
+mut fs = [];
+for (x in [1, 2, 3]) {
+	fs = push(fs, func() => { x });
+}
+fs[0]() + fs[1]() * 10 + fs[2]() * 100;
+`
+	testNumber(t, testRun(input), 321)
+}
+
+func TestForInScopeIsFreshEachIteration(t *testing.T) {
+	// 反復のスコープを使い回しても、前の反復で宣言した変数は見えない
+	input := `
+for (i, x in [1, 2]) {
+	if (i == 1) {
+		y;
+	}
+	mut y = x;
+}
+`
+	testError(t, testRun(input), "line 4, column 3: identifier not found: y")
+}
+
+func TestNestedTryAcrossCalls(t *testing.T) {
+	input := `
+func fail(n) => {
+	if (n == 0) { throw "bottom"; }
+	return fail(n - 1);
+}
+mut caught = "";
+for (i in [1, 2]) {
+	try {
+		fail(3);
+	} catch (e) {
+		caught = caught + e;
+		continue;
+	}
+}
+caught;
+`
+	result := testRun(input)
+	str, ok := result.(*object.String)
+	if !ok {
+		t.Fatalf("object is not String. got=%T (%+v)", result, result)
+	}
+	if str.Value != "bottombottom" {
+		t.Errorf("wrong value. want=%q, got=%q", "bottombottom", str.Value)
+	}
+}
+
+func TestCallFunctionCreatedByEvaluator(t *testing.T) {
+	env := object.NewEnvironment()
+	evaluator.Eval(parse("func double(x) => { x * 2 }"), env)
+
+	machine := New(evaluator.New(""))
+	testNumber(t, machine.Eval(parse("double(21)"), env), 42)
+}
+
+func TestCompileErrorIsReturnedAsError(t *testing.T) {
+	testError(t, testRun("1;\nbreak;"), "line 2, column 1: break outside loop")
+}
+
+func BenchmarkForIn(b *testing.B) {
+	input := `
+mut data = [];
+for (mut i = 0; i < 1000; i++) {
+	data = push(data, i);
+}
+mut sum = 0;
+for (mut n = 0; n < 100; n++) {
+	for (x in data) {
+		if (x % 3 == 0) { sum += x * 2; }
+	}
+}
+sum;
+`
+	program := parse(input)
+
+	b.Run("evaluator", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			evaluator.New("").Eval(program, object.NewEnvironment())
+		}
+	})
+	b.Run("vm", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			New(evaluator.New("")).Eval(program, object.NewEnvironment())
+		}
+	})
+}
+
+// ヘルパー関数
+
+func parse(input string) *ast.Program {
+	l := lexer.New(input)
+	p := parser.New(l)
+	return p.ParseProgram()
+}
+
+func testRun(input string) object.Object {
+	return New(evaluator.New("")).Eval(parse(input), object.NewEnvironment())
+}
+
+func testNumber(t *testing.T, obj object.Object, expected float64) {
+	t.Helper()
+	result, ok := obj.(*object.Number)
+	if !ok {
+		t.Fatalf("object is not Number. got=%T (%+v)", obj, obj)
+	}
+	if result.Value != expected {
+		t.Errorf("object has wrong value. got=%v, want=%v", result.Value, expected)
+	}
+}
+
+func testError(t *testing.T, obj object.Object, expected string) {
+	t.Helper()
+	errObj, ok := obj.(*object.Error)
+	if !ok {
+		t.Fatalf("no error object returned. got=%T (%+v)", obj, obj)
+	}
+	if errObj.Message != expected {
+		t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+	}
+}